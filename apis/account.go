@@ -42,6 +42,11 @@ func Register(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = CheckImageReference(DB, body.Avatar)
+	if err != nil {
+		return err
+	}
+
 	var user User
 	err = copier.CopyWithOption(&user, &body, copier.Option{IgnoreEmpty: true})
 	if err != nil {
@@ -166,6 +171,11 @@ func ModifyUserMe(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = CheckImageReference(DB, body.Avatar)
+	if err != nil {
+		return err
+	}
+
 	err = DB.Transaction(func(tx *gorm.DB) error {
 		err = tx.Clauses(LockClause).Take(&user, user.ID).Error
 		if err != nil {
@@ -328,6 +338,11 @@ func ModifyAUser(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = CheckImageReference(DB, body.Avatar)
+	if err != nil {
+		return err
+	}
+
 	var user User
 	err = DB.Transaction(func(tx *gorm.DB) error {
 		err = tx.Clauses(LockClause).Take(&user, userID).Error
@@ -10,8 +10,9 @@ import (
 
 // ListBalances godoc
 // @Summary List balances
+// @Description Set export to csv, xlsx or ndjson to download all the filtered records as a file
 // @Tags Balance
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
 // @Param json query BalanceListRequest true "query"
 // @Success 200 {object} BalanceListResponse
 // @Router /balances [get]
@@ -46,6 +47,13 @@ func ListBalances(c *fiber.Ctx) error {
 
 	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
 
+	if query.Export != "" {
+		return Export(c, "balances", query.Export, querySet, func(balances []Balance) (response []BalanceResponse, err error) {
+			err = copier.Copy(&response, &balances)
+			return
+		})
+	}
+
 	var balances []Balance
 	if err := querySet.Find(&balances).Error; err != nil {
 		return err
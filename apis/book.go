@@ -6,13 +6,16 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 // ListBooks godoc
 // @Summary List books
+// @Description Set export to csv, xlsx or ndjson to download all the filtered records as a file
 // @Tags Book
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
 // @Param json query BookListRequest true "query"
 // @Success 200 {object} BookListResponse
 // @Router /books [get]
@@ -26,13 +29,42 @@ func ListBooks(c *fiber.Ctx) error {
 	if err := ValidateQuery(c, &query); err != nil {
 		return err
 	}
+	if query.IncludeDeleted && !user.IsAdmin {
+		return Forbidden("Only admin can list archived books")
+	}
 
-	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	querySet := query.QuerySet(DB)
+	if query.IncludeDeleted {
+		querySet = querySet.Unscoped()
+	}
+	if query.CategoryID != nil {
+		querySet = querySet.Where("id IN (?)", BookIDsInCategory(DB, *query.CategoryID))
+	}
+	if query.Tag != nil {
+		querySet = querySet.Where("id IN (?)", BookIDsWithTag(DB, *query.Tag))
+	}
+	if query.ContributorID != nil {
+		var role ContributorRole
+		if query.ContributorRole != nil {
+			role = *query.ContributorRole
+		}
+		querySet = querySet.Where("id IN (?)", BookIDsOfContributor(DB, *query.ContributorID, role))
+	}
+	if query.LocationID != nil {
+		querySet = querySet.Where("id IN (?)", BookIDsAtLocation(DB, *query.LocationID))
+	}
 	if query.ID != nil {
 		querySet = querySet.Where("id = ?", *query.ID)
 	} else if query.ISBN != nil {
-		querySet = querySet.Where("isbn = ?", *query.ISBN)
+		isbn, ok := NormalizeISBN(*query.ISBN)
+		if !ok {
+			isbn = *query.ISBN
+		}
+		querySet = querySet.Where("isbn = ?", isbn)
 	} else {
+		if query.Q != nil {
+			querySet = SearchBooks(querySet, *query.Q)
+		}
 		if query.Title != nil {
 			querySet = querySet.Where("title LIKE ?", "%"+*query.Title+"%")
 		}
@@ -46,13 +78,24 @@ func ListBooks(c *fiber.Ctx) error {
 			querySet = querySet.Where("on_sale = ?", *query.OnSale)
 		}
 	}
+	querySet = querySet.Order(ToOrderString(query.OrderBy, query.Sort))
 
 	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
 
+	if query.Export != "" {
+		return Export(c, "books", query.Export, querySet.Scopes(PreloadBookTaxonomy, PreloadBookContributors, PreloadBookStocks), func(books []Book) (response []BookResponse, err error) {
+			err = copier.Copy(&response, &books)
+			return
+		})
+	}
+
 	var books []Book
-	if err := querySet.Find(&books).Error; err != nil {
+	if err := querySet.Scopes(PreloadBookTaxonomy, PreloadBookContributors, PreloadBookStocks).Find(&books).Error; err != nil {
 		return err
 	}
+	if query.Q != nil {
+		HighlightBookSnippets(books, *query.Q)
+	}
 
 	var pageTotal int64
 	if err := querySet.Model(&Book{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
@@ -68,6 +111,92 @@ func ListBooks(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// GetABook godoc
+// @Summary Get a book by id
+// @Description Get a book with its sale and purchase summary
+// @Tags Book
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} BookSummaryResponse
+// @Router /books/{id} [get]
+func GetABook(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var book Book
+	if err := DB.Scopes(PreloadBookTaxonomy, PreloadBookContributors, PreloadBookStocks).First(&book, bookID).Error; err != nil {
+		return err
+	}
+
+	var response BookSummaryResponse
+	if err := copier.Copy(&response.BookResponse, &book); err != nil {
+		return err
+	}
+
+	// sales
+	err = DB.Model(&Sale{}).
+		Select("COALESCE(SUM(quantity - refunded_quantity), 0)").
+		Where("book_id = ?", book.ID).
+		Scan(&response.TotalSold).Error
+	if err != nil {
+		return err
+	}
+
+	var lastSale Sale
+	err = DB.Where("book_id = ?", book.ID).Order("id desc").Limit(1).Find(&lastSale).Error
+	if err != nil {
+		return err
+	}
+	if lastSale.ID != 0 {
+		response.LastSaleTime = &lastSale.CreatedAt
+	}
+
+	// purchases, missing units of closed purchases are not counted
+	var purchased struct {
+		Quantity int
+		Cost     int // 以分为单位
+	}
+	err = DB.Model(&PurchaseItem{}).
+		Select(`
+			COALESCE(SUM(CASE WHEN purchase.status = ? THEN purchase_item.received_quantity ELSE purchase_item.quantity END), 0) AS quantity,
+			COALESCE(SUM(CASE WHEN purchase.status = ? THEN purchase_item.received_quantity ELSE purchase_item.quantity END * purchase_item.price), 0) AS cost
+		`, PurchaseStatusClosed, PurchaseStatusClosed).
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase_item.book_id = ? AND purchase.status IN ?", book.ID,
+			[]PurchaseStatus{PurchaseStatusPaid, PurchaseStatusArrived, PurchaseStatusClosed}).
+		Scan(&purchased).Error
+	if err != nil {
+		return err
+	}
+	response.TotalPurchased = purchased.Quantity
+	if purchased.Quantity > 0 {
+		averageCost := float64(purchased.Cost) / float64(purchased.Quantity) / 100
+		response.AveragePurchaseCost = &averageCost
+		if book.Price != nil && *book.Price > 0 {
+			margin := (book.PriceFloat() - averageCost) / book.PriceFloat()
+			response.Margin = &margin
+		}
+	}
+
+	err = DB.Model(&PurchaseItem{}).
+		Select("COALESCE(SUM(purchase_item.quantity - purchase_item.received_quantity), 0)").
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase_item.book_id = ? AND purchase.status = ?", book.ID, PurchaseStatusPaid).
+		Scan(&response.PendingArrivals).Error
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
 // CreateABook godoc
 // @Summary Create a book
 // @Tags Book
@@ -86,13 +215,52 @@ func CreateABook(c *fiber.Ctx) error {
 	if err := ValidateBody(c, &body); err != nil {
 		return err
 	}
+	if err := CheckImageReference(DB, body.Cover); err != nil {
+		return err
+	}
 
 	var book Book
 	if err := copier.CopyWithOption(&book, &body, copier.Option{IgnoreEmpty: true}); err != nil {
 		return err
 	}
+	book.ISBN, _ = NormalizeISBN(body.ISBN)
 	book.UserID = user.ID
-	if err := DB.Create(&book).Error; err != nil {
+	book.Contributors = nil // set by SetContributors
+
+	// archived books also occupy the isbn, restore them instead.
+	// This is a fast path, the unique index catches concurrent creates
+	var count int64
+	if err := DB.Unscoped().Model(&Book{}).Where("isbn = ?", book.ISBN).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBookISBNExists
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		contributors, err := resolveBookContributors(tx, body.Contributors, body.Author)
+		if err != nil {
+			return err
+		}
+		if body.Author == "" {
+			book.Author = ContributorByline(contributors)
+		}
+		if err = tx.Create(&book).Error; err != nil {
+			if IsDuplicatedKey(err) {
+				return ErrBookISBNExists
+			}
+			return err
+		}
+		if book.Price != nil {
+			if _, err = book.ChangePrice(tx, user.ID, *book.Price, book.CreatedAt); err != nil {
+				return err
+			}
+		}
+		if err = book.SetContributors(tx, contributors); err != nil {
+			return err
+		}
+		return setBookTaxonomy(tx, &book, body.CategoryIDs, body.TagNames)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -125,7 +293,7 @@ func ModifyABook(c *fiber.Ctx) error {
 	}
 
 	var book Book
-	if err := DB.Where("id = ?", bookID).First(&book).Error; err != nil {
+	if err := DB.Scopes(PreloadBookTaxonomy, PreloadBookContributors, PreloadBookStocks).Where("id = ?", bookID).First(&book).Error; err != nil {
 		return err
 	}
 
@@ -133,11 +301,44 @@ func ModifyABook(c *fiber.Ctx) error {
 	if err := ValidateBody(c, &body); err != nil {
 		return err
 	}
+	if err := CheckImageReference(DB, body.Cover); err != nil {
+		return err
+	}
 
+	price, contributors := book.Price, book.Contributors
+	book.Price = nil // otherwise copier writes through the pointer, the old price is needed to record changes
 	if err := copier.CopyWithOption(&book, &body, copier.Option{IgnoreEmpty: true}); err != nil {
 		return err
 	}
-	if err := DB.Save(&book).Error; err != nil {
+	if body.PriceFloat == nil {
+		book.Price = price // IgnoreEmpty does not apply to the Price method
+	}
+	book.Contributors = contributors // set by SetContributors
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if body.Contributors != nil || body.Author != nil {
+			contributors, err := resolveBookContributors(tx, body.Contributors, book.Author)
+			if err != nil {
+				return err
+			}
+			if body.Author == nil {
+				book.Author = ContributorByline(contributors)
+			}
+			if err = book.SetContributors(tx, contributors); err != nil {
+				return err
+			}
+		}
+		// stock is maintained by ChangeStock, the value read above may be stale
+		if err := tx.Omit(clause.Associations, "stock").Save(&book).Error; err != nil {
+			return err
+		}
+		if book.Price != nil && (price == nil || *book.Price != *price) {
+			if _, err := book.ChangePrice(tx, user.ID, *book.Price, time.Now()); err != nil {
+				return err
+			}
+		}
+		return setBookTaxonomy(tx, &book, body.CategoryIDs, body.TagNames)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -148,3 +349,115 @@ func ModifyABook(c *fiber.Ctx) error {
 
 	return c.JSON(&bookResponse)
 }
+
+// DeleteABook godoc
+// @Summary Archive a book, admin only
+// @Description Soft delete a book, blocked while the book has stock or open purchases
+// @Tags Book
+// @Param id path int true "id"
+// @Success 204
+// @Router /books/{id} [delete]
+func DeleteABook(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var book Book
+		if err = tx.Clauses(LockClause).First(&book, bookID).Error; err != nil {
+			return err
+		}
+
+		return book.Archive(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreABook godoc
+// @Summary Restore an archived book, admin only
+// @Tags Book
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} BookResponse
+// @Router /books/{id}/_restore [post]
+func RestoreABook(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var book Book
+	if err = DB.Unscoped().First(&book, bookID).Error; err != nil {
+		return err
+	}
+	if !book.DeletedAt.Valid {
+		return BadRequest("Book is not archived")
+	}
+
+	book.DeletedAt = gorm.DeletedAt{}
+	if err = DB.Unscoped().Model(&book).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+
+	var bookResponse BookResponse
+	if err = copier.Copy(&bookResponse, &book); err != nil {
+		return err
+	}
+
+	return c.JSON(&bookResponse)
+}
+
+// setBookTaxonomy replaces the categories and tags of the book if set
+func setBookTaxonomy(tx *gorm.DB, book *Book, categoryIDs []int, tagNames []string) error {
+	if categoryIDs != nil {
+		if err := book.SetCategories(tx, categoryIDs); err != nil {
+			return err
+		}
+	}
+	if tagNames != nil {
+		if err := book.SetTags(tx, tagNames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveBookContributors returns the contributors of the request, or the contributors split from author if not set
+func resolveBookContributors(tx *gorm.DB, requests []BookContributorRequest, author string) ([]BookContributor, error) {
+	var contributors []BookContributor
+	if requests == nil {
+		contributors = ParseContributors(author)
+	} else {
+		contributors = make([]BookContributor, len(requests))
+		for i, request := range requests {
+			contributors[i].Role = request.Role
+			if request.ContributorID != nil {
+				contributors[i].ContributorID = *request.ContributorID
+			} else {
+				contributors[i].Contributor = &Contributor{Name: *request.Name}
+			}
+		}
+	}
+	return contributors, ResolveContributors(tx, contributors)
+}
@@ -0,0 +1,518 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportBooks godoc
+// @Summary Import books from csv or ONIX 3.0, admin only
+// @Description Upsert books by isbn, each row is validated as BookCreateRequest.
+// @Description For existing books, only the columns present in the row are updated.
+// @Description The file is uploaded as multipart form field "file" or as the raw request body.
+// @Tags Book
+// @Accept multipart/form-data,text/csv,application/xml
+// @Produce json
+// @Param json query BookImportRequest true "query"
+// @Param file formData file false "csv or ONIX file"
+// @Success 200 {object} BookImportResponse
+// @Router /books/_import [post]
+func ImportBooks(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var query BookImportRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return BadRequest("file is required")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		reader = file
+	} else {
+		if len(c.Body()) == 0 {
+			return BadRequest("body is empty")
+		}
+		reader = bytes.NewReader(c.Body())
+	}
+
+	var records []bookImportRecord
+	var err error
+	switch query.Format {
+	case "csv":
+		records, err = parseBookCSV(reader)
+	case "onix":
+		records, err = parseBookONIX(reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	response := BookImportResponse{DryRun: query.DryRun, Rows: make([]BookImportRowResponse, 0, len(records))}
+	errDryRun := errors.New("dry run")
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			row := importABook(tx, record, user.ID)
+			switch row.Status {
+			case BookImportStatusCreated:
+				response.Created++
+				if query.DryRun {
+					row.BookID = 0 // rolled back
+				}
+			case BookImportStatusUpdated:
+				response.Updated++
+			case BookImportStatusFailed:
+				response.Failed++
+			}
+			response.Rows = append(response.Rows, row)
+		}
+		if query.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// bookImportFields 可导入的字段, 与 BookCreateRequest 的 json 字段名一致
+var bookImportFields = []string{"isbn", "title", "description", "author", "press", "published_date", "price", "cover", "on_sale"}
+
+// bookImportRecord 一行 csv 或一条 ONIX Product 记录, values 为空的字段不会被导入
+type bookImportRecord struct {
+	Row          int
+	Values       map[string]string
+	Contributors []BookContributor // contributors with roles, split from the author column if not set
+	Err          error             // the row cannot be parsed
+}
+
+// importABook creates or updates a book by isbn in a savepoint, so that a failed row does not affect the others
+func importABook(tx *gorm.DB, record bookImportRecord, userID int) (row BookImportRowResponse) {
+	row = BookImportRowResponse{Row: record.Row, ISBN: record.Values["isbn"], Status: BookImportStatusFailed}
+	if record.Err != nil {
+		row.Message = record.Err.Error()
+		return row
+	}
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var book Book
+		isbn, ok := NormalizeISBN(record.Values["isbn"])
+		if ok {
+			row.ISBN = isbn
+			if err := tx.Unscoped().Where("isbn = ?", isbn).Limit(1).Find(&book).Error; err != nil {
+				return err
+			}
+			if book.DeletedAt.Valid {
+				return BadRequest("Book is archived, restore it first")
+			}
+		}
+
+		// existing values are kept for the columns not present in the row
+		var body BookCreateRequest
+		if book.ID != 0 {
+			body = bookCreateRequestFromBook(&book)
+		}
+		if err := body.setImportValues(record.Values); err != nil {
+			return err
+		}
+		if err := Validate(&body); err != nil {
+			return err
+		}
+		if _, ok := record.Values["cover"]; ok {
+			if err := CheckImageReference(tx, body.Cover); err != nil {
+				return err
+			}
+		}
+
+		price, author := book.Price, book.Author
+		book.Price = nil // otherwise copier writes through the pointer, the old price is needed to record changes
+		if err := copier.CopyWithOption(&book, &body, copier.Option{IgnoreEmpty: true}); err != nil {
+			return err
+		}
+		if body.PriceFloat == nil {
+			book.Price = price // IgnoreEmpty does not apply to the Price method
+		}
+		book.ISBN = isbn
+		if book.ID == 0 {
+			book.UserID = userID
+			row.Status = BookImportStatusCreated
+		} else {
+			row.Status = BookImportStatusUpdated
+		}
+		if err := tx.Omit("stock").Save(&book).Error; err != nil { // stock is maintained by ChangeStock
+			if IsDuplicatedKey(err) {
+				return ErrBookISBNExists // created concurrently
+			}
+			return err
+		}
+		row.BookID = book.ID
+		if book.Price != nil && (price == nil || *book.Price != *price) {
+			if _, err := book.ChangePrice(tx, userID, *book.Price, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		contributors := record.Contributors
+		if contributors == nil && (row.Status == BookImportStatusCreated || book.Author != author) {
+			contributors = ParseContributors(book.Author)
+		}
+		if contributors == nil {
+			return nil
+		}
+		if err := ResolveContributors(tx, contributors); err != nil {
+			return err
+		}
+		return book.SetContributors(tx, contributors)
+	})
+	if err != nil {
+		row.Status = BookImportStatusFailed
+		var detail *ErrorDetail
+		if errors.As(err, &detail) {
+			row.Detail = detail
+		}
+		row.BookID = 0
+		row.Message = err.Error()
+	}
+	return row
+}
+
+func bookCreateRequestFromBook(book *Book) BookCreateRequest {
+	body := BookCreateRequest{
+		ISBN:          book.ISBN,
+		Title:         book.Title,
+		Description:   book.Description,
+		Author:        book.Author,
+		Press:         book.Press,
+		PublishedDate: book.PublishedDate,
+		Cover:         book.Cover,
+		OnSale:        book.OnSale,
+	}
+	if book.Price != nil {
+		price := book.PriceFloat()
+		body.PriceFloat = &price
+	}
+	return body
+}
+
+// setImportValues parses the values of an import record, format errors are returned as ErrorDetail
+func (b *BookCreateRequest) setImportValues(values map[string]string) error {
+	var detail ErrorDetail
+	invalid := func(field, format string) {
+		detail = append(detail, &ErrorDetailElement{Field: field, Tag: "format", Value: format})
+	}
+
+	for _, field := range bookImportFields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		switch field {
+		case "isbn":
+			b.ISBN = value
+		case "title":
+			b.Title = value
+		case "description":
+			b.Description = &value
+		case "author":
+			b.Author = value
+		case "press":
+			b.Press = value
+		case "cover":
+			b.Cover = &value
+		case "published_date":
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				date, err = time.Parse(time.RFC3339, value)
+			}
+			if err != nil {
+				invalid(field, "2006-01-02")
+				continue
+			}
+			b.PublishedDate = &date
+		case "price":
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				invalid(field, "number")
+				continue
+			}
+			b.PriceFloat = &price
+		case "on_sale":
+			onSale, err := strconv.ParseBool(value)
+			if err != nil {
+				invalid(field, "bool")
+				continue
+			}
+			b.OnSale = onSale
+		}
+	}
+	if len(detail) > 0 {
+		return &detail
+	}
+	return nil
+}
+
+// parseBookCSV reads a csv file with a header row, the columns are named as bookImportFields
+func parseBookCSV(r io.Reader) ([]bookImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, BadRequest("Invalid csv header: " + err.Error())
+	}
+	hasISBN := false
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))) // excel writes BOM
+		if !isBookImportField(header[i]) {
+			return nil, BadRequest("Unknown csv column " + header[i])
+		}
+		hasISBN = hasISBN || header[i] == "isbn"
+	}
+	if !hasISBN {
+		return nil, BadRequest("csv column isbn is required")
+	}
+
+	var records []bookImportRecord
+	for row := 1; ; row++ {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		record := bookImportRecord{Row: row, Values: make(map[string]string, len(header))}
+		if err != nil {
+			record.Err = BadRequest(err.Error())
+			records = append(records, record)
+			continue
+		}
+		for i, value := range line {
+			if value = strings.TrimSpace(value); value != "" {
+				record.Values[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func isBookImportField(name string) bool {
+	for _, field := range bookImportFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// onixProduct ONIX 3.0 Product 记录中用到的部分, 仅支持 reference tag
+type onixProduct struct {
+	Identifiers []struct {
+		Type  string `xml:"ProductIDType"`
+		Value string `xml:"IDValue"`
+	} `xml:"ProductIdentifier"`
+	Titles []struct {
+		Type     string `xml:"TitleType"`
+		Elements []struct {
+			Level         string `xml:"TitleElementLevel"`
+			Text          string `xml:"TitleText"`
+			Prefix        string `xml:"TitlePrefix"`
+			WithoutPrefix string `xml:"TitleWithoutPrefix"`
+		} `xml:"TitleElement"`
+	} `xml:"DescriptiveDetail>TitleDetail"`
+	Contributors []struct {
+		Roles          []string `xml:"ContributorRole"`
+		PersonName     string   `xml:"PersonName"`
+		NamesBeforeKey string   `xml:"NamesBeforeKey"`
+		KeyNames       string   `xml:"KeyNames"`
+		CorporateName  string   `xml:"CorporateName"`
+	} `xml:"DescriptiveDetail>Contributor"`
+	Texts []struct {
+		Type string `xml:"TextType"`
+		Text string `xml:"Text"`
+	} `xml:"CollateralDetail>TextContent"`
+	Resources []struct {
+		ContentType string   `xml:"ResourceContentType"`
+		Links       []string `xml:"ResourceVersion>ResourceLink"`
+	} `xml:"CollateralDetail>SupportingResource"`
+	Publishers []struct {
+		Role string `xml:"PublishingRole"`
+		Name string `xml:"PublisherName"`
+	} `xml:"PublishingDetail>Publisher"`
+	Dates []struct {
+		Role string `xml:"PublishingDateRole"`
+		Date string `xml:"Date"`
+	} `xml:"PublishingDetail>PublishingDate"`
+	Prices []struct {
+		Type     string `xml:"PriceType"`
+		Amount   string `xml:"PriceAmount"`
+		Currency string `xml:"CurrencyCode"`
+	} `xml:"ProductSupply>SupplyDetail>Price"`
+}
+
+// parseBookONIX reads the Product records of an ONIX 3.0 message one by one
+func parseBookONIX(r io.Reader) ([]bookImportRecord, error) {
+	decoder := xml.NewDecoder(r)
+	var records []bookImportRecord
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, BadRequest("Invalid ONIX: " + err.Error())
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Product" {
+			continue
+		}
+
+		var product onixProduct
+		if err = decoder.DecodeElement(&product, &start); err != nil {
+			return nil, BadRequest("Invalid ONIX: " + err.Error())
+		}
+		values, contributors := product.values()
+		records = append(records, bookImportRecord{Row: len(records) + 1, Values: values, Contributors: contributors})
+	}
+	if len(records) == 0 {
+		return nil, BadRequest("No Product record found, only ONIX 3.0 reference tags are supported")
+	}
+	return records, nil
+}
+
+// onixContributorRoles ONIX contributor role codes (list 17) of the supported roles
+var onixContributorRoles = map[string]ContributorRole{
+	"A01": ContributorRoleAuthor,
+	"B06": ContributorRoleTranslator,
+	"B01": ContributorRoleEditor,
+	"A12": ContributorRoleIllustrator,
+}
+
+// values maps the product onto bookImportFields and contributors, see ONIX code lists for the codes
+func (p *onixProduct) values() (map[string]string, []BookContributor) {
+	values := make(map[string]string)
+	set := func(field, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			values[field] = value
+		}
+	}
+
+	// ISBN-13 (15) is preferred over GTIN-13 (03) and ISBN-10 (02)
+	for _, idType := range []string{"15", "03", "02"} {
+		for _, identifier := range p.Identifiers {
+			if identifier.Type == idType && values["isbn"] == "" {
+				set("isbn", identifier.Value)
+			}
+		}
+	}
+
+	// distinctive title (01) at product level (01)
+	for _, title := range p.Titles {
+		if title.Type != "01" {
+			continue
+		}
+		for _, element := range title.Elements {
+			if element.Level != "01" {
+				continue
+			}
+			if element.Text != "" {
+				set("title", element.Text)
+			} else {
+				set("title", strings.TrimSpace(element.Prefix+" "+element.WithoutPrefix))
+			}
+		}
+	}
+
+	// contributors in order, the author field is their byline
+	var contributors []BookContributor
+	for _, contributor := range p.Contributors {
+		name := contributor.PersonName
+		if name == "" {
+			name = contributor.NamesBeforeKey + " " + contributor.KeyNames
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			name = strings.TrimSpace(contributor.CorporateName)
+		}
+		if name == "" {
+			continue
+		}
+		for _, code := range contributor.Roles {
+			if role, ok := onixContributorRoles[code]; ok {
+				contributors = append(contributors, BookContributor{Contributor: &Contributor{Name: name}, Role: role})
+			}
+		}
+	}
+	set("author", ContributorByline(contributors))
+
+	// description (03), otherwise short description (02)
+	for _, textType := range []string{"03", "02"} {
+		for _, text := range p.Texts {
+			if text.Type == textType && values["description"] == "" {
+				set("description", text.Text)
+			}
+		}
+	}
+
+	// front cover (01)
+	for _, resource := range p.Resources {
+		if resource.ContentType == "01" && len(resource.Links) > 0 && values["cover"] == "" {
+			set("cover", resource.Links[0])
+		}
+	}
+
+	// publisher (01)
+	for _, publisher := range p.Publishers {
+		if (publisher.Role == "01" || publisher.Role == "") && values["press"] == "" {
+			set("press", publisher.Name)
+		}
+	}
+
+	// publication date (01), format YYYYMMDD by default
+	for _, date := range p.Dates {
+		if date.Role != "01" {
+			continue
+		}
+		if published, err := time.Parse("20060102", strings.TrimSpace(date.Date)); err == nil {
+			set("published_date", published.Format("2006-01-02"))
+		} else {
+			set("published_date", date.Date) // reported as a format error
+		}
+	}
+
+	// RRP including tax (02), otherwise excluding tax (01), only CNY prices are imported
+	for _, priceType := range []string{"02", "01"} {
+		for _, price := range p.Prices {
+			if price.Type == priceType && (price.Currency == "CNY" || price.Currency == "") && values["price"] == "" {
+				set("price", price.Amount)
+			}
+		}
+	}
+
+	return values, contributors
+}
@@ -0,0 +1,173 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ListBookPrices godoc
+// @Summary List the price history of a book
+// @Description Including the scheduled changes. Set at to list the changes effective at the time, the first one in desc order is the price at the time
+// @Tags Book
+// @Produce json
+// @Param id path int true "book id"
+// @Param json query BookPriceListRequest true "query"
+// @Success 200 {object} BookPriceListResponse
+// @Router /books/{id}/prices [get]
+func ListBookPrices(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var query BookPriceListRequest
+	if err = ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	var book Book
+	if err = DB.Unscoped().Select("id").First(&book, bookID).Error; err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Where("book_id = ?", book.ID).
+		Order(ToOrderString("effective_at", query.Sort)).Order(ToOrderString("id", query.Sort))
+	if query.At != nil {
+		querySet = querySet.Where("effective_at <= ?", *query.At)
+	}
+	if query.Pending != nil {
+		if *query.Pending {
+			querySet = querySet.Where("applied_at IS NULL")
+		} else {
+			querySet = querySet.Where("applied_at IS NOT NULL")
+		}
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var prices []BookPrice
+	if err = querySet.Find(&prices).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err = querySet.Model(&BookPrice{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response BookPriceListResponse
+	if err = copier.Copy(&response.Prices, &prices); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// CreateABookPrice godoc
+// @Summary Change the price of a book
+// @Description Takes effect immediately if effective_at is not set, otherwise the book price is updated automatically at the time
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path int true "book id"
+// @Param json body BookPriceCreateRequest true "body"
+// @Success 201 {object} BookPriceResponse
+// @Router /books/{id}/prices [post]
+func CreateABookPrice(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body BookPriceCreateRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	effectiveAt := time.Now()
+	if body.EffectiveAt != nil {
+		if body.EffectiveAt.Before(effectiveAt) {
+			return BadRequest("effective_at must not be in the past")
+		}
+		effectiveAt = *body.EffectiveAt
+	}
+
+	var price *BookPrice
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var book Book
+		if err = tx.Clauses(LockClause).First(&book, bookID).Error; err != nil {
+			return err
+		}
+		price, err = book.ChangePrice(tx, user.ID, body.Price(), effectiveAt)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var response BookPriceResponse
+	if err = copier.Copy(&response, price); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// DeleteABookPrice godoc
+// @Summary Cancel a scheduled price change
+// @Description Only changes not yet effective can be cancelled, by the user who scheduled it or an admin
+// @Tags Book
+// @Param id path int true "book id"
+// @Param price_id path int true "price change id"
+// @Success 204
+// @Router /books/{id}/prices/{price_id} [delete]
+func DeleteABookPrice(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	bookID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+	priceID, err := c.ParamsInt("price_id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var price BookPrice
+		if err = tx.Clauses(LockClause).Where("book_id = ?", bookID).First(&price, priceID).Error; err != nil {
+			return err
+		}
+		if !user.IsAdmin && price.UserID != user.ID {
+			return Forbidden()
+		}
+		// effective changes are used by sales before the scheduler applies them
+		if price.AppliedAt != nil || !price.EffectiveAt.After(time.Now()) {
+			return ErrBookPriceApplied
+		}
+		return tx.Delete(&price).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
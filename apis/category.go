@@ -0,0 +1,258 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListCategories godoc
+// @Summary List categories
+// @Tags Category
+// @Produce json
+// @Param json query CategoryListRequest true "query"
+// @Success 200 {object} CategoryListResponse
+// @Router /categories [get]
+func ListCategories(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query CategoryListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.ParentID != nil {
+		querySet = querySet.Where("parent_id = ?", *query.ParentID)
+	} else if query.Root {
+		querySet = querySet.Where("parent_id IS NULL")
+	}
+	if query.Code != nil {
+		querySet = querySet.Where("code LIKE ?", *query.Code+"%")
+	}
+	if query.Name != nil {
+		querySet = querySet.Where("name LIKE ?", "%"+*query.Name+"%")
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var categories []Category
+	if err := querySet.Find(&categories).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Category{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response CategoryListResponse
+	if err := copier.Copy(&response.Categories, &categories); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetACategory godoc
+// @Summary Get a category by id
+// @Description Get a category with its direct children
+// @Tags Category
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} CategoryResponse
+// @Router /categories/{id} [get]
+func GetACategory(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	categoryID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var category Category
+	if err := DB.First(&category, categoryID).Error; err != nil {
+		return err
+	}
+
+	var children []Category
+	if err := DB.Where("parent_id = ?", category.ID).Order("id").Find(&children).Error; err != nil {
+		return err
+	}
+
+	var response CategoryResponse
+	if err := copier.Copy(&response, &category); err != nil {
+		return err
+	}
+	if err := copier.Copy(&response.Children, &children); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateACategory godoc
+// @Summary Create a category
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Param json body CategoryCreateRequest true "body"
+// @Success 201 {object} CategoryResponse
+// @Router /categories [post]
+func CreateACategory(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body CategoryCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var category Category
+	if err := copier.Copy(&category, &body); err != nil {
+		return err
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryCode(tx, body.Code, 0); err != nil {
+			return err
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CategoryResponse
+	if err = copier.Copy(&response, &category); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyACategory godoc
+// @Summary Modify a category
+// @Description Set parent_id to move the category with all its descendants, 0 moves it to the top level
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body CategoryModifyRequest true "body"
+// @Success 200 {object} CategoryResponse
+// @Router /categories/{id} [patch]
+func ModifyACategory(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	categoryID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body CategoryModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var category Category
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&category, categoryID).Error; err != nil {
+			return err
+		}
+
+		if body.Code != nil {
+			if err = checkCategoryCode(tx, body.Code, category.ID); err != nil {
+				return err
+			}
+			category.Code = body.Code
+		}
+		if body.Name != nil {
+			category.Name = *body.Name
+		}
+		if err = tx.Model(&category).Select("code", "name").Updates(&category).Error; err != nil {
+			return err
+		}
+
+		if body.ParentID != nil {
+			var parentID *int
+			if *body.ParentID != 0 {
+				parentID = body.ParentID
+			}
+			return category.MoveTo(tx, parentID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CategoryResponse
+	if err = copier.Copy(&response, &category); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteACategory godoc
+// @Summary Delete a category, admin only
+// @Description Only categories without children can be deleted, the books are unlinked
+// @Tags Category
+// @Param id path int true "id"
+// @Success 204
+// @Router /categories/{id} [delete]
+func DeleteACategory(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	categoryID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var category Category
+		if err = tx.Clauses(LockClause).First(&category, categoryID).Error; err != nil {
+			return err
+		}
+		return category.Delete(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkCategoryCode returns a conflict error if the code is used by another category
+func checkCategoryCode(tx *gorm.DB, code *string, categoryID int) error {
+	if code == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&Category{}).Where("code = ? AND id <> ?", *code, categoryID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return Conflict("分类编码已存在")
+	}
+	return nil
+}
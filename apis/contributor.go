@@ -0,0 +1,198 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListContributors godoc
+// @Summary List contributors
+// @Description Authors, translators, editors and illustrators, list their books with contributor_id of ListBooks
+// @Tags Contributor
+// @Produce json
+// @Param json query ContributorListRequest true "query"
+// @Success 200 {object} ContributorListResponse
+// @Router /contributors [get]
+func ListContributors(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query ContributorListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.Name != nil {
+		querySet = querySet.Where("name LIKE ?", "%"+*query.Name+"%")
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var contributors []Contributor
+	if err := querySet.Find(&contributors).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Contributor{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response ContributorListResponse
+	if err := copier.Copy(&response.Contributors, &contributors); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetAContributor godoc
+// @Summary Get a contributor by id
+// @Tags Contributor
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} ContributorResponse
+// @Router /contributors/{id} [get]
+func GetAContributor(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	contributorID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var contributor Contributor
+	if err := DB.First(&contributor, contributorID).Error; err != nil {
+		return err
+	}
+
+	var response ContributorResponse
+	if err := copier.Copy(&response, &contributor); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateAContributor godoc
+// @Summary Create a contributor
+// @Description Contributors given by name are also created when a book is created or modified
+// @Tags Contributor
+// @Accept json
+// @Produce json
+// @Param json body ContributorCreateRequest true "body"
+// @Success 201 {object} ContributorResponse
+// @Router /contributors [post]
+func CreateAContributor(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body ContributorCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	contributor := Contributor{Name: body.Name}
+	if err := DB.Create(&contributor).Error; err != nil {
+		return err
+	}
+
+	var response ContributorResponse
+	if err := copier.Copy(&response, &contributor); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyAContributor godoc
+// @Summary Rename a contributor
+// @Description The author field of its books is updated
+// @Tags Contributor
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body ContributorModifyRequest true "body"
+// @Success 200 {object} ContributorResponse
+// @Router /contributors/{id} [patch]
+func ModifyAContributor(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	contributorID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body ContributorModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var contributor Contributor
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&contributor, contributorID).Error; err != nil {
+			return err
+		}
+		return contributor.Rename(tx, body.Name)
+	})
+	if err != nil {
+		return err
+	}
+
+	var response ContributorResponse
+	if err = copier.Copy(&response, &contributor); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteAContributor godoc
+// @Summary Delete a contributor, admin only
+// @Description Only contributors without books can be deleted
+// @Tags Contributor
+// @Param id path int true "id"
+// @Success 204
+// @Router /contributors/{id} [delete]
+func DeleteAContributor(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	contributorID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var contributor Contributor
+		if err = tx.Clauses(LockClause).First(&contributor, contributorID).Error; err != nil {
+			return err
+		}
+		return contributor.Delete(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
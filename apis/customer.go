@@ -0,0 +1,291 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListCustomers godoc
+// @Summary List customers
+// @Tags Customer
+// @Produce json
+// @Param json query CustomerListRequest true "query"
+// @Success 200 {object} CustomerListResponse
+// @Router /customers [get]
+func ListCustomers(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query CustomerListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.Q != nil {
+		q := "%" + *query.Q + "%"
+		querySet = querySet.Where("name LIKE ? OR phone LIKE ? OR email LIKE ? OR member_number LIKE ?", q, q, q, q)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var customers []Customer
+	if err := querySet.Find(&customers).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Customer{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response CustomerListResponse
+	if err := copier.Copy(&response.Customers, &customers); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// LookupACustomer godoc
+// @Summary Look up a customer
+// @Description Find a customer by phone, email or member number exactly, e.g. at checkout
+// @Tags Customer
+// @Produce json
+// @Param json query CustomerLookupRequest true "query"
+// @Success 200 {object} CustomerResponse
+// @Router /customers/_lookup [get]
+func LookupACustomer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query CustomerLookupRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := DB
+	if query.Phone != nil {
+		querySet = querySet.Where("phone = ?", *query.Phone)
+	}
+	if query.Email != nil {
+		querySet = querySet.Where("email = ?", *query.Email)
+	}
+	if query.MemberNumber != nil {
+		querySet = querySet.Where("member_number = ?", *query.MemberNumber)
+	}
+
+	var customer Customer
+	if err := querySet.First(&customer).Error; err != nil {
+		return err
+	}
+
+	var response CustomerResponse
+	if err := copier.Copy(&response, &customer); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// GetACustomer godoc
+// @Summary Get a customer by id
+// @Tags Customer
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} CustomerResponse
+// @Router /customers/{id} [get]
+func GetACustomer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var customer Customer
+	if err := DB.First(&customer, customerID).Error; err != nil {
+		return err
+	}
+
+	var response CustomerResponse
+	if err := copier.Copy(&response, &customer); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateACustomer godoc
+// @Summary Create a customer
+// @Description Phone and member number are unique among customers
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param json body CustomerCreateRequest true "body"
+// @Success 201 {object} CustomerResponse
+// @Router /customers [post]
+func CreateACustomer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body CustomerCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var customer Customer
+	if err := copier.Copy(&customer, &body); err != nil {
+		return err
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := customer.CheckUnique(tx); err != nil {
+			return err
+		}
+		return tx.Create(&customer).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CustomerResponse
+	if err = copier.Copy(&response, &customer); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyACustomer godoc
+// @Summary Modify a customer
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body CustomerModifyRequest true "body"
+// @Success 200 {object} CustomerResponse
+// @Router /customers/{id} [patch]
+func ModifyACustomer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body CustomerModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var customer Customer
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&customer, customerID).Error; err != nil {
+			return err
+		}
+
+		if err = copier.CopyWithOption(&customer, &body, copier.Option{IgnoreEmpty: true}); err != nil {
+			return err
+		}
+		if err = customer.CheckUnique(tx); err != nil {
+			return err
+		}
+
+		return tx.Save(&customer).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CustomerResponse
+	if err = copier.Copy(&response, &customer); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteACustomer godoc
+// @Summary Delete a customer, admin only
+// @Description The sales of the customer are kept
+// @Tags Customer
+// @Param id path int true "id"
+// @Success 204
+// @Router /customers/{id} [delete]
+func DeleteACustomer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	if err = DB.Delete(&Customer{ID: customerID}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetACustomerHistory godoc
+// @Summary Get the purchase history of a customer
+// @Description Books bought, total spent and visits of a customer, refunds excluded
+// @Tags Customer
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} CustomerHistoryResponse
+// @Router /customers/{id}/history [get]
+func GetACustomerHistory(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var customer Customer
+	if err := DB.First(&customer, customerID).Error; err != nil {
+		return err
+	}
+
+	history, err := customer.History(DB)
+	if err != nil {
+		return err
+	}
+
+	response := CustomerHistoryResponse{CustomerID: customer.ID}
+	if err = copier.Copy(&response, &history); err != nil {
+		return err
+	}
+	if response.Books == nil {
+		response.Books = []CustomerBookResponse{}
+	}
+
+	return c.JSON(&response)
+}
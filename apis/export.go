@@ -0,0 +1,260 @@
+package apis
+
+import (
+	. "book_management_system_backend/utils"
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const exportBatchSize = 500
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatXLSX   = "xlsx"
+	ExportFormatNDJSON = "ndjson"
+)
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// Export streams all the records matched by querySet as a file instead of a paged json response.
+// Pagination of querySet is ignored, records are loaded in batches and converted to Response rows,
+// so that the whole result set is never held in memory.
+// csv and xlsx contain the scalar fields of Response, ndjson contains the whole Response.
+//
+// The first batch is loaded before the response starts so that query errors are reported normally,
+// errors occurred while streaming can only be logged and the file is truncated.
+func Export[Model any, Response any](
+	c *fiber.Ctx,
+	name string,
+	format string,
+	querySet *gorm.DB,
+	convert func(models []Model) ([]Response, error),
+) error {
+	querySet = querySet.Offset(-1).Limit(-1).Order("id").Session(&gorm.Session{}) // id makes batches stable
+
+	batch := func(i int) ([]Response, error) {
+		var models []Model
+		if err := querySet.Offset(i * exportBatchSize).Limit(exportBatchSize).Find(&models).Error; err != nil {
+			return nil, err
+		}
+		return convert(models)
+	}
+
+	first, err := batch(0)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Set(fiber.HeaderContentType, exportContentTypes[format])
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer, err := newExportWriter[Response](w, format, name)
+		if err == nil {
+			rows := first
+			for i := 1; len(rows) > 0; i++ {
+				for j := range rows {
+					if err = writer.Write(&rows[j]); err != nil {
+						break
+					}
+				}
+				if err != nil || len(rows) < exportBatchSize {
+					break
+				}
+				if err = writer.Flush(); err != nil {
+					break
+				}
+				if rows, err = batch(i); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			Logger.Error("export error", zap.String("name", name), zap.String("format", format), zap.Error(err))
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+type exportWriter[Response any] interface {
+	Write(row *Response) error
+	Flush() error
+	Close() error
+}
+
+func newExportWriter[Response any](w *bufio.Writer, format string, name string) (exportWriter[Response], error) {
+	switch format {
+	case ExportFormatCSV:
+		writer := &csvExportWriter[Response]{csv: csv.NewWriter(w), columns: exportColumns(reflect.TypeOf((*Response)(nil)).Elem(), nil)}
+		return writer, writer.csv.Write(writer.columns.names())
+	case ExportFormatXLSX:
+		xlsx, err := NewXLSXWriter(w, name)
+		if err != nil {
+			return nil, err
+		}
+		writer := &xlsxExportWriter[Response]{xlsx: xlsx, columns: exportColumns(reflect.TypeOf((*Response)(nil)).Elem(), nil)}
+		header := make([]any, len(writer.columns))
+		for i, column := range writer.columns {
+			header[i] = column.name
+		}
+		return writer, xlsx.WriteRow(header)
+	default:
+		return &ndjsonExportWriter[Response]{w: w, encoder: json.NewEncoder(w)}, nil
+	}
+}
+
+type csvExportWriter[Response any] struct {
+	csv     *csv.Writer
+	columns exportColumnList
+}
+
+func (w *csvExportWriter[Response]) Write(row *Response) error {
+	values := w.columns.values(reflect.ValueOf(row).Elem())
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.csv.Write(record)
+}
+
+func (w *csvExportWriter[Response]) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvExportWriter[Response]) Close() error {
+	return w.Flush()
+}
+
+type xlsxExportWriter[Response any] struct {
+	xlsx    *XLSXWriter
+	columns exportColumnList
+}
+
+func (w *xlsxExportWriter[Response]) Write(row *Response) error {
+	return w.xlsx.WriteRow(w.columns.values(reflect.ValueOf(row).Elem()))
+}
+
+func (w *xlsxExportWriter[Response]) Flush() error {
+	return w.xlsx.Flush()
+}
+
+func (w *xlsxExportWriter[Response]) Close() error {
+	return w.xlsx.Close()
+}
+
+type ndjsonExportWriter[Response any] struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter[Response]) Write(row *Response) error {
+	return w.encoder.Encode(row) // Encode appends a newline
+}
+
+func (w *ndjsonExportWriter[Response]) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *ndjsonExportWriter[Response]) Close() error {
+	return w.Flush()
+}
+
+// exportColumn a scalar field of the response, named by its json tag
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+type exportColumnList []exportColumn
+
+// exportColumns collects the scalar fields of a response struct, including the fields of embedded structs.
+// Nested structs and slices, e.g. the book of a sale, are only exported in ndjson.
+func exportColumns(t reflect.Type, index []int) (columns exportColumnList) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, exportColumns(field.Type, fieldIndex)...)
+			continue
+		}
+
+		name := field.Tag.Get("json")
+		for j := range name {
+			if name[j] == ',' {
+				name = name[:j]
+				break
+			}
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			if fieldType != reflect.TypeOf(time.Time{}) {
+				continue
+			}
+		case reflect.Slice, reflect.Map, reflect.Array, reflect.Interface:
+			continue
+		}
+		columns = append(columns, exportColumn{name: name, index: fieldIndex})
+	}
+	return columns
+}
+
+func (columns exportColumnList) names() []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// values returns the column values of a response, nil pointers are returned as nil
+func (columns exportColumnList) values(row reflect.Value) []any {
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		value := row.FieldByIndex(column.index)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		values[i] = value.Interface()
+	}
+	return values
+}
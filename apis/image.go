@@ -0,0 +1,132 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cache"
+	"io"
+	"strings"
+	"time"
+)
+
+// imageCacheMiddleware caches the images loaded from the storage in memory, images are immutable
+var imageCacheMiddleware = cache.New(cache.Config{
+	Expiration: time.Hour,
+	KeyGenerator: func(c *fiber.Ctx) string {
+		return c.Path() + "?thumbnail=" + c.Query("thumbnail")
+	},
+	StoreResponseHeaders: true,
+	MaxBytes:             64 * 1024 * 1024,
+})
+
+// imageCache skips the cache for conditional requests, which are answered with 304 by GetAnImage
+func imageCache(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderIfNoneMatch) != "" {
+		return c.Next()
+	}
+	return imageCacheMiddleware(c)
+}
+
+// UploadAnImage godoc
+// @Summary Upload an image
+// @Description Upload a cover or an avatar, JPEG, PNG and GIF are supported.
+// @Description Set the url of the image as the cover of a book or the avatar of a user.
+// @Description The image is uploaded as multipart form field "file" or as the raw request body.
+// @Tags Image
+// @Accept multipart/form-data,image/jpeg,image/png,image/gif
+// @Produce json
+// @Param file formData file false "image"
+// @Success 201 {object} ImageResponse
+// @Router /images [post]
+func UploadAnImage(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var data []byte
+	if strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return BadRequest("file is required")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		if data, err = io.ReadAll(file); err != nil {
+			return err
+		}
+	} else {
+		data = c.Body()
+	}
+	if len(data) == 0 {
+		return BadRequest("image is empty")
+	}
+
+	image, err := SaveImage(DB, user.ID, data)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newImageResponse(image))
+}
+
+// GetAnImage godoc
+// @Summary Get an image or its thumbnail
+// @Description Images are immutable and cached, authorization is not required so that it can be used in img tags
+// @Tags Image
+// @Produce jpeg,png,gif
+// @Param id path string true "id"
+// @Param thumbnail query bool false "thumbnail"
+// @Success 200
+// @Success 304
+// @Router /images/{id} [get]
+func GetAnImage(c *fiber.Ctx) error {
+	id := c.Params("id")
+	thumbnail := c.QueryBool("thumbnail")
+	etag := `"` + id + `"`
+	if thumbnail {
+		etag = `"` + id + `_thumbnail"`
+	}
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	var image Image
+	if err := DB.Take(&image, "id = ?", id).Error; err != nil {
+		return err
+	}
+	key, contentType := image.ID, image.ContentType
+	if thumbnail {
+		key, contentType = image.ThumbnailKey(), image.ThumbnailContentType
+	}
+	data, err := ImageStorage.Get(key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderETag, etag)
+	return c.Send(data)
+}
+
+func newImageResponse(image *Image) *ImageResponse {
+	return &ImageResponse{
+		ID:           image.ID,
+		CreatedAt:    image.CreatedAt,
+		URL:          image.URL(),
+		ThumbnailURL: image.ThumbnailURL(),
+		ContentType:  image.ContentType,
+		Size:         image.Size,
+		Width:        image.Width,
+		Height:       image.Height,
+	}
+}
@@ -0,0 +1,314 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListLocations godoc
+// @Summary List locations
+// @Description Stock locations such as the shop floor, warehouses and branches, ordered by id
+// @Tags Location
+// @Produce json
+// @Success 200 {array} LocationResponse
+// @Router /locations [get]
+func ListLocations(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var locations []Location
+	if err := DB.Order("id asc").Find(&locations).Error; err != nil {
+		return err
+	}
+
+	var response = make([]LocationResponse, 0, len(locations))
+	if err := copier.Copy(&response, &locations); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// GetALocation godoc
+// @Summary Get a location by id
+// @Tags Location
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} LocationResponse
+// @Router /locations/{id} [get]
+func GetALocation(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	locationID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var location Location
+	if err := DB.First(&location, locationID).Error; err != nil {
+		return err
+	}
+
+	var response LocationResponse
+	if err := copier.Copy(&response, &location); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateALocation godoc
+// @Summary Create a location, admin only
+// @Tags Location
+// @Accept json
+// @Produce json
+// @Param json body LocationCreateRequest true "body"
+// @Success 201 {object} LocationResponse
+// @Router /locations [post]
+func CreateALocation(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var body LocationCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	location := Location{Name: body.Name, Description: body.Description}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Location{}).Where("name = ?", body.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return Conflict("库存位置已存在")
+		}
+
+		if err := tx.Create(&location).Error; err != nil {
+			return err
+		}
+		if body.IsDefault {
+			return location.SetDefault(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var response LocationResponse
+	if err = copier.Copy(&response, &location); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyALocation godoc
+// @Summary Modify a location, admin only
+// @Tags Location
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body LocationModifyRequest true "body"
+// @Success 200 {object} LocationResponse
+// @Router /locations/{id} [patch]
+func ModifyALocation(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	locationID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body LocationModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var location Location
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&location, locationID).Error; err != nil {
+			return err
+		}
+
+		if body.Name != nil {
+			var count int64
+			if err = tx.Model(&Location{}).Where("name = ? AND id <> ?", *body.Name, location.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return Conflict("库存位置已存在")
+			}
+		}
+
+		isDefault := location.IsDefault
+		if err = copier.CopyWithOption(&location, &body, copier.Option{IgnoreEmpty: true}); err != nil {
+			return err
+		}
+		location.IsDefault = isDefault // set by SetDefault
+		if err = tx.Save(&location).Error; err != nil {
+			return err
+		}
+		if body.IsDefault != nil && !isDefault {
+			return location.SetDefault(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var response LocationResponse
+	if err = copier.Copy(&response, &location); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteALocation godoc
+// @Summary Delete a location, admin only
+// @Description Only locations out of stock can be deleted, transfer the stock to other locations first. The default location cannot be deleted.
+// @Tags Location
+// @Param id path int true "id"
+// @Success 204
+// @Router /locations/{id} [delete]
+func DeleteALocation(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	locationID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var location Location
+		if err = tx.Clauses(LockClause).First(&location, locationID).Error; err != nil {
+			return err
+		}
+		return location.Delete(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListStockTransfers godoc
+// @Summary List stock transfers
+// @Tags Location
+// @Produce json
+// @Param json query StockTransferListRequest true "query"
+// @Success 200 {object} StockTransferListResponse
+// @Router /transfers [get]
+func ListStockTransfers(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query StockTransferListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.BookID != nil {
+		querySet = querySet.Where("book_id = ?", *query.BookID)
+	}
+	if query.LocationID != nil {
+		querySet = querySet.Where("from_location_id = ? OR to_location_id = ?", *query.LocationID, *query.LocationID)
+	}
+	if query.StartTime != nil {
+		querySet = querySet.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		querySet = querySet.Where("created_at <= ?", *query.EndTime)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var transfers []StockTransfer
+	if err := querySet.Find(&transfers).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&StockTransfer{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response StockTransferListResponse
+	if err := copier.Copy(&response.Transfers, &transfers); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// CreateAStockTransfer godoc
+// @Summary Transfer stock of a book between locations
+// @Description The total stock of the book is unchanged
+// @Tags Location
+// @Accept json
+// @Produce json
+// @Param json body StockTransferCreateRequest true "body"
+// @Success 201 {object} StockTransferResponse
+// @Router /transfers [post]
+func CreateAStockTransfer(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body StockTransferCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var transfer StockTransfer
+	if err := copier.Copy(&transfer, &body); err != nil {
+		return err
+	}
+	transfer.UserID = user.ID
+
+	// stock of the source location is checked in transfer hooks
+	if err := DB.Create(&transfer).Error; err != nil {
+		return err
+	}
+
+	var response StockTransferResponse
+	if err := copier.Copy(&response, &transfer); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
@@ -0,0 +1,304 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ListMembershipTiers godoc
+// @Summary List membership tiers
+// @Description Ordered by min spend, a customer is in the highest tier the spend in the rolling 12 months reaches
+// @Tags Loyalty
+// @Produce json
+// @Success 200 {array} MembershipTierResponse
+// @Router /membership_tiers [get]
+func ListMembershipTiers(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var tiers []MembershipTier
+	if err := DB.Order("min_spend").Find(&tiers).Error; err != nil {
+		return err
+	}
+
+	var response = make([]MembershipTierResponse, 0, len(tiers))
+	if err := copier.Copy(&response, &tiers); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// CreateAMembershipTier godoc
+// @Summary Create a membership tier, admin only
+// @Description Tiers of all customers are updated
+// @Tags Loyalty
+// @Accept json
+// @Produce json
+// @Param json body MembershipTierCreateRequest true "body"
+// @Success 201 {object} MembershipTierResponse
+// @Router /membership_tiers [post]
+func CreateAMembershipTier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var body MembershipTierCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	tier := MembershipTier{
+		Name:          body.Name,
+		MinSpend:      body.MinSpend(),
+		PointsPerYuan: body.PointsPerYuan,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tier.CheckUnique(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(&tier).Error; err != nil {
+			return err
+		}
+		_, err := UpdateTiers(tx, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var response MembershipTierResponse
+	if err = copier.Copy(&response, &tier); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyAMembershipTier godoc
+// @Summary Modify a membership tier, admin only
+// @Description Tiers of all customers are updated
+// @Tags Loyalty
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body MembershipTierModifyRequest true "body"
+// @Success 200 {object} MembershipTierResponse
+// @Router /membership_tiers/{id} [patch]
+func ModifyAMembershipTier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	tierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body MembershipTierModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var tier MembershipTier
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&tier, tierID).Error; err != nil {
+			return err
+		}
+
+		if body.Name != nil {
+			tier.Name = *body.Name
+		}
+		if minSpend := body.MinSpend(); minSpend != nil {
+			tier.MinSpend = *minSpend
+		}
+		if body.PointsPerYuan != nil {
+			tier.PointsPerYuan = *body.PointsPerYuan
+		}
+		if err = tier.CheckUnique(tx); err != nil {
+			return err
+		}
+		if err = tx.Save(&tier).Error; err != nil {
+			return err
+		}
+
+		_, err = UpdateTiers(tx, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var response MembershipTierResponse
+	if err = copier.Copy(&response, &tier); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteAMembershipTier godoc
+// @Summary Delete a membership tier, admin only
+// @Description Customers in the tier are moved to the highest tier they still reach
+// @Tags Loyalty
+// @Param id path int true "id"
+// @Success 204
+// @Router /membership_tiers/{id} [delete]
+func DeleteAMembershipTier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	tierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var tier MembershipTier
+		if err = tx.Clauses(LockClause).First(&tier, tierID).Error; err != nil {
+			return err
+		}
+
+		customers := tx.Model(&Customer{}).Unscoped().Where("tier_id = ?", tier.ID)
+		if err = customers.UpdateColumn("tier_id", nil).Error; err != nil {
+			return err
+		}
+		if err = tx.Delete(&tier).Error; err != nil {
+			return err
+		}
+
+		_, err = UpdateTiers(tx, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListCustomerPoints godoc
+// @Summary List the points records of a customer
+// @Tags Loyalty
+// @Produce json
+// @Param id path int true "id"
+// @Param json query PointRecordListRequest true "query"
+// @Success 200 {object} PointRecordListResponse
+// @Router /customers/{id}/points [get]
+func ListCustomerPoints(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query PointRecordListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var customer Customer
+	if err := DB.First(&customer, customerID).Error; err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort)).
+		Where("customer_id = ?", customer.ID)
+	if query.OperationType != nil {
+		querySet = querySet.Where("operation_type = ?", *query.OperationType)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var records []PointRecord
+	if err := querySet.Find(&records).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&PointRecord{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response PointRecordListResponse
+	if err := copier.Copy(&response.Records, &records); err != nil {
+		return err
+	}
+	if response.Records == nil {
+		response.Records = []PointRecordResponse{}
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// AdjustCustomerPoints godoc
+// @Summary Adjust the points of a customer manually, admin only
+// @Description The points of the customer cannot be negative after the adjustment
+// @Tags Loyalty
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body PointRecordCreateRequest true "body"
+// @Success 201 {object} PointRecordResponse
+// @Router /customers/{id}/points [post]
+func AdjustCustomerPoints(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	customerID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body PointRecordCreateRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	record := PointRecord{
+		CustomerID:    customerID,
+		UserID:        user.ID,
+		Change:        body.Change,
+		OperationType: PointOperationTypeManual,
+		Reason:        body.Reason,
+	}
+	if err = DB.Create(&record).Error; err != nil {
+		return err
+	}
+
+	var response PointRecordResponse
+	if err = copier.Copy(&response, &record); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
@@ -67,5 +67,23 @@ func GetMeta(c *fiber.Ctx) (err error) {
 	`).Scan(&metaInfo.BalanceCountByMonth).Error; err != nil {
 		return
 	}
+
+	// 统计每个顶级分类 (包含子分类) 的销售数量和金额, 同时属于多个子分类的书籍只计算一次
+	if err = DB.Raw(`
+		SELECT root.id AS category_id, root.name AS name,
+			COALESCE(SUM(sale.quantity - sale.refunded_quantity), 0) AS quantity,
+			COALESCE(SUM((sale.quantity - sale.refunded_quantity) * sale.price), 0) / 100.0 AS amount
+		FROM category root
+		JOIN sale ON sale.book_id IN (
+			SELECT book_category.book_id FROM book_category
+			JOIN category ON category.id = book_category.category_id
+			WHERE category.path LIKE root.path || '%'
+		)
+		WHERE root.parent_id IS NULL
+		GROUP BY root.id, root.name
+		ORDER BY amount DESC
+	`).Scan(&metaInfo.SaleByCategory).Error; err != nil {
+		return
+	}
 	return c.JSON(metaInfo)
 }
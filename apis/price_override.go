@@ -0,0 +1,101 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidApprover = BadRequest("授权人用户名或密码错误")
+
+// authenticateApprover checks the credentials of the user who approves price overrides, returns null if not given
+func authenticateApprover(approver *ApproverRequest) (*int, error) {
+	if approver == nil {
+		return nil, nil
+	}
+
+	var user User
+	if err := DB.Take(&user, "username = ?", approver.Username).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidApprover
+		}
+		return nil, err
+	}
+	if !CheckPassword(approver.Password, user.HashedPassword) {
+		return nil, ErrInvalidApprover
+	}
+	return &user.ID, nil
+}
+
+// ListPriceOverrides godoc
+// @Summary List price overrides, admin only
+// @Description Audit list of the sales whose price is given manually, with the reason and the approver
+// @Tags Sale
+// @Produce json
+// @Param json query PriceOverrideListRequest true "query"
+// @Success 200 {object} PriceOverrideListResponse
+// @Router /price_overrides [get]
+func ListPriceOverrides(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var query PriceOverrideListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.UserID != nil {
+		querySet = querySet.Where("user_id = ?", *query.UserID)
+	}
+	if query.ApproverID != nil {
+		querySet = querySet.Where("approver_id = ?", *query.ApproverID)
+	}
+	if query.BookID != nil {
+		querySet = querySet.Where("book_id = ?", *query.BookID)
+	}
+	if query.Approved != nil {
+		if *query.Approved {
+			querySet = querySet.Where("approver_id IS NOT NULL")
+		} else {
+			querySet = querySet.Where("approver_id IS NULL")
+		}
+	}
+	if query.StartTime != nil {
+		querySet = querySet.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		querySet = querySet.Where("created_at <= ?", *query.EndTime)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var overrides []PriceOverride
+	if err := querySet.Find(&overrides).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&PriceOverride{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response PriceOverrideListResponse
+	if err := copier.Copy(&response.Overrides, &overrides); err != nil {
+		return err
+	}
+	if response.Overrides == nil {
+		response.Overrides = []PriceOverrideResponse{}
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
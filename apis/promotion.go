@@ -0,0 +1,526 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// ListPromotions godoc
+// @Summary List promotions
+// @Tags Promotion
+// @Produce json
+// @Param json query PromotionListRequest true "query"
+// @Success 200 {object} PromotionListResponse
+// @Router /promotions [get]
+func ListPromotions(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query PromotionListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.Name != nil {
+		querySet = querySet.Where("name LIKE ?", "%"+*query.Name+"%")
+	}
+	if query.Type != nil {
+		querySet = querySet.Where("type = ?", *query.Type)
+	}
+	if query.Active != nil {
+		now := time.Now()
+		active := DB.Where("enabled = ?", true).
+			Where("start_at IS NULL OR start_at <= ?", now).
+			Where("end_at IS NULL OR end_at > ?", now)
+		if *query.Active {
+			querySet = querySet.Where(active)
+		} else {
+			querySet = querySet.Not(active)
+		}
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var promotions []Promotion
+	if err := querySet.Scopes(PreloadPromotionScope).Find(&promotions).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Promotion{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response PromotionListResponse
+	if err := copier.Copy(&response.Promotions, &promotions); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetAPromotion godoc
+// @Summary Get a promotion by id
+// @Tags Promotion
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} PromotionResponse
+// @Router /promotions/{id} [get]
+func GetAPromotion(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	promotionID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var promotion Promotion
+	if err := DB.Scopes(PreloadPromotionScope).First(&promotion, promotionID).Error; err != nil {
+		return err
+	}
+
+	var response PromotionResponse
+	if err := copier.Copy(&response, &promotion); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateAPromotion godoc
+// @Summary Create a promotion, admin only
+// @Description Promotions are applied when sales are created without a price: the best automatic promotion of the book,
+// @Description then the promotion of the coupon if given. Coupon only promotions are applied with their coupons only.
+// @Tags Promotion
+// @Accept json
+// @Produce json
+// @Param json body PromotionCreateRequest true "body"
+// @Success 201 {object} PromotionResponse
+// @Router /promotions [post]
+func CreateAPromotion(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var body PromotionCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	promotion := Promotion{
+		Name:         body.Name,
+		Type:         body.Type,
+		Value:        body.Value(),
+		BuyQuantity:  body.BuyQuantity,
+		FreeQuantity: body.FreeQuantity,
+		StartAt:      body.StartAt,
+		EndAt:        body.EndAt,
+		Enabled:      body.Enabled == nil || *body.Enabled,
+		CouponOnly:   body.CouponOnly,
+	}
+	if err := promotion.Check(); err != nil {
+		return err
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&promotion).Error; err != nil {
+			return err
+		}
+		return promotion.SetScope(tx, body.BookIDs, body.CategoryIDs, body.Presses)
+	})
+	if err != nil {
+		return err
+	}
+
+	var response PromotionResponse
+	if err = copier.Copy(&response, &promotion); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyAPromotion godoc
+// @Summary Modify a promotion, admin only
+// @Description Sales created before are not changed
+// @Tags Promotion
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body PromotionModifyRequest true "body"
+// @Success 200 {object} PromotionResponse
+// @Router /promotions/{id} [patch]
+func ModifyAPromotion(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	promotionID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body PromotionModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var promotion Promotion
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).Scopes(PreloadPromotionScope).First(&promotion, promotionID).Error; err != nil {
+			return err
+		}
+
+		if body.Name != nil {
+			promotion.Name = *body.Name
+		}
+		if body.Type != nil {
+			promotion.Type = *body.Type
+		}
+		if body.ValueFloat != nil {
+			promotion.Value = promotionValue(promotion.Type, *body.ValueFloat)
+		}
+		if body.BuyQuantity != nil {
+			promotion.BuyQuantity = *body.BuyQuantity
+		}
+		if body.FreeQuantity != nil {
+			promotion.FreeQuantity = *body.FreeQuantity
+		}
+		if body.StartAt != nil {
+			promotion.StartAt = body.StartAt
+		}
+		if body.EndAt != nil {
+			promotion.EndAt = body.EndAt
+		}
+		if body.Enabled != nil {
+			promotion.Enabled = *body.Enabled
+		}
+		if body.CouponOnly != nil {
+			promotion.CouponOnly = *body.CouponOnly
+		}
+		if err = promotion.Check(); err != nil {
+			return err
+		}
+		if err = tx.Omit(clause.Associations).Save(&promotion).Error; err != nil {
+			return err
+		}
+
+		if body.BookIDs == nil && body.CategoryIDs == nil && body.Presses == nil {
+			return nil
+		}
+		bookIDs, categoryIDs, presses := promotion.BookIDs(), promotion.CategoryIDs(), promotion.PressNames()
+		if body.BookIDs != nil {
+			bookIDs = body.BookIDs
+		}
+		if body.CategoryIDs != nil {
+			categoryIDs = body.CategoryIDs
+		}
+		if body.Presses != nil {
+			presses = body.Presses
+		}
+		return promotion.SetScope(tx, bookIDs, categoryIDs, presses)
+	})
+	if err != nil {
+		return err
+	}
+
+	var response PromotionResponse
+	if err = copier.Copy(&response, &promotion); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteAPromotion godoc
+// @Summary Delete a promotion, admin only
+// @Description The promotion and its coupons are no longer applied, the sales it applied to are kept
+// @Tags Promotion
+// @Param id path int true "id"
+// @Success 204
+// @Router /promotions/{id} [delete]
+func DeleteAPromotion(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	promotionID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	if err = DB.Delete(&Promotion{ID: promotionID}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPromotionSummaries godoc
+// @Summary Summarize the sales of promotions
+// @Description Sales, books sold and discount of each promotion applied in the time range, including deleted promotions
+// @Tags Promotion
+// @Produce json
+// @Param json query PromotionSummaryRequest true "query"
+// @Success 200 {array} PromotionSummaryResponse
+// @Router /promotions/_summary [get]
+func GetPromotionSummaries(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query PromotionSummaryRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	summaries, err := SummarizePromotions(DB, query.StartTime, query.EndTime)
+	if err != nil {
+		return err
+	}
+
+	var response = make([]PromotionSummaryResponse, 0, len(summaries))
+	if err = copier.Copy(&response, &summaries); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// ListCoupons godoc
+// @Summary List coupons
+// @Tags Promotion
+// @Produce json
+// @Param json query CouponListRequest true "query"
+// @Success 200 {object} CouponListResponse
+// @Router /coupons [get]
+func ListCoupons(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query CouponListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.PromotionID != nil {
+		querySet = querySet.Where("promotion_id = ?", *query.PromotionID)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var coupons []Coupon
+	if err := querySet.Find(&coupons).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Coupon{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response CouponListResponse
+	if err := copier.Copy(&response.Coupons, &coupons); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// LookupACoupon godoc
+// @Summary Look up a coupon by code
+// @Description Check a coupon at checkout, the code is case-insensitive
+// @Tags Promotion
+// @Produce json
+// @Param json query CouponLookupRequest true "query"
+// @Success 200 {object} CouponResponse
+// @Router /coupons/_lookup [get]
+func LookupACoupon(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query CouponLookupRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	var coupon Coupon
+	if err := DB.Where("code = ?", NormalizeCouponCode(query.Code)).First(&coupon).Error; err != nil {
+		return err
+	}
+
+	var response CouponResponse
+	if err := copier.Copy(&response, &coupon); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateACoupon godoc
+// @Summary Create a coupon of a promotion, admin only
+// @Description Codes are unique and case-insensitive, a sale or a receipt counts as one use
+// @Tags Promotion
+// @Accept json
+// @Produce json
+// @Param json body CouponCreateRequest true "body"
+// @Success 201 {object} CouponResponse
+// @Router /coupons [post]
+func CreateACoupon(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	var body CouponCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	coupon := Coupon{
+		Code:        NormalizeCouponCode(body.Code),
+		PromotionID: body.PromotionID,
+		UsageLimit:  body.UsageLimit,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := CheckPromotionExists(tx, coupon.PromotionID); err != nil {
+			return err
+		}
+		if err := coupon.CheckUnique(tx); err != nil {
+			return err
+		}
+		return tx.Create(&coupon).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CouponResponse
+	if err = copier.Copy(&response, &coupon); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyACoupon godoc
+// @Summary Modify the usage limit of a coupon, admin only
+// @Tags Promotion
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body CouponModifyRequest true "body"
+// @Success 200 {object} CouponResponse
+// @Router /coupons/{id} [patch]
+func ModifyACoupon(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	couponID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body CouponModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var coupon Coupon
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&coupon, couponID).Error; err != nil {
+			return err
+		}
+		if body.UsageLimit != nil {
+			coupon.UsageLimit = body.UsageLimit
+		}
+		return tx.Save(&coupon).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response CouponResponse
+	if err = copier.Copy(&response, &coupon); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteACoupon godoc
+// @Summary Delete an unused coupon, admin only
+// @Description A used coupon is referenced by sales, set its usage limit to the used count to disable it instead
+// @Tags Promotion
+// @Param id path int true "id"
+// @Success 204
+// @Router /coupons/{id} [delete]
+func DeleteACoupon(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	couponID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var coupon Coupon
+		if err = tx.Clauses(LockClause).First(&coupon, couponID).Error; err != nil {
+			return err
+		}
+		if coupon.UsedCount > 0 {
+			return ErrCouponHasBeenUsed
+		}
+		return tx.Delete(&coupon).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
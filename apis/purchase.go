@@ -6,12 +6,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 // ListPurchases godoc
 // @Summary List purchases
+// @Description Set export to csv, xlsx or ndjson to download all the filtered records as a file
 // @Tags Purchase
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
 // @Param json query PurchaseListRequest true "query"
 // @Success 200 {object} PurchaseListResponse
 // @Router /purchases [get]
@@ -28,16 +31,29 @@ func ListPurchases(c *fiber.Ctx) error {
 
 	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
 	if query.BookID != nil {
-		querySet = querySet.Where("book_id = ?", *query.BookID)
+		querySet = querySet.Where("id IN (?)", DB.Model(&PurchaseItem{}).Select("purchase_id").Where("book_id = ?", *query.BookID))
 	}
 	if query.UserID != nil {
 		querySet = querySet.Where("user_id = ?", *query.UserID)
 	}
+	if query.SupplierID != nil {
+		querySet = querySet.Where("supplier_id = ?", *query.SupplierID)
+	}
+	if query.Status != nil {
+		querySet = querySet.Where("status = ?", *query.Status)
+	}
 
 	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
 
+	if query.Export != "" {
+		return Export(c, "purchases", query.Export, querySet.Preload("Items.Book", WithDeleted), func(purchases []Purchase) (response []PurchaseResponse, err error) {
+			err = copier.Copy(&response, &purchases)
+			return
+		})
+	}
+
 	var purchases []Purchase
-	if err := querySet.Preload("Book").Find(&purchases).Error; err != nil {
+	if err := querySet.Preload("Items.Book", WithDeleted).Find(&purchases).Error; err != nil {
 		return err
 	}
 
@@ -50,11 +66,6 @@ func ListPurchases(c *fiber.Ctx) error {
 	if err := copier.Copy(&response.Purchases, &purchases); err != nil {
 		return err
 	}
-	for i := range response.Purchases {
-		if err := copier.Copy(&response.Purchases[i].Book, &purchases[i].Book); err != nil {
-			return err
-		}
-	}
 	response.PageTotal = int(pageTotal)
 
 	return c.JSON(response)
@@ -62,6 +73,7 @@ func ListPurchases(c *fiber.Ctx) error {
 
 // GetAPurchase godoc
 // @Summary Get a purchase by id
+// @Description Get a purchase with its items, arrivals and status transition history
 // @Tags Purchase
 // @Accept json
 // @Produce json
@@ -75,7 +87,10 @@ func GetAPurchase(c *fiber.Ctx) error {
 	}
 
 	var purchase Purchase
-	if err := DB.First(&purchase, c.Params("id")).Error; err != nil {
+	err := DB.Preload("Items.Book", WithDeleted).Preload("Arrivals.Items").Preload("Transitions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).First(&purchase, c.Params("id")).Error
+	if err != nil {
 		return NotFound()
 	}
 
@@ -89,6 +104,7 @@ func GetAPurchase(c *fiber.Ctx) error {
 
 // CreateAPurchase godoc
 // @Summary Create a purchase
+// @Description Create a purchase with one or more items
 // @Tags Purchase
 // @Accept json
 // @Produce json
@@ -106,6 +122,15 @@ func CreateAPurchase(c *fiber.Ctx) error {
 		return err
 	}
 
+	if body.SupplierID != nil {
+		if err := DB.First(&Supplier{}, *body.SupplierID).Error; err != nil {
+			return err
+		}
+	}
+	if err := CheckBooksExist(DB, PurchaseItemBookIDs(body.Items)); err != nil {
+		return err
+	}
+
 	var purchase Purchase
 	if err := copier.Copy(&purchase, &body); err != nil {
 		return err
@@ -126,7 +151,7 @@ func CreateAPurchase(c *fiber.Ctx) error {
 
 // ModifyAPurchase godoc
 // @Summary Modify a purchase
-// @Description Modify the quantity or price of a purchase by id
+// @Description Replace the items of a purchase by id
 // @Tags Purchase
 // @Accept json
 // @Produce json
@@ -151,25 +176,52 @@ func ModifyAPurchase(c *fiber.Ctx) error {
 	}
 
 	var purchase Purchase
-	if err := DB.First(&purchase, purchaseID).Error; err != nil {
-		return err
-	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&purchase, purchaseID).Error; err != nil {
+			return err
+		}
 
-	if purchase.Paid {
-		return BadRequest("Cannot modify a paid purchase")
-	}
-	if purchase.Returned {
-		return BadRequest("Cannot modify a returned purchase")
-	}
-	if purchase.Arrived {
-		return BadRequest("Cannot modify an arrived purchase")
-	}
+		if purchase.Status != PurchaseStatusDraft {
+			return BadRequest("Cannot modify a " + purchase.Status + " purchase")
+		}
 
-	if err := copier.CopyWithOption(&purchase, &body, copier.Option{IgnoreEmpty: true}); err != nil {
-		return err
-	}
+		if body.SupplierID != nil && *body.SupplierID == 0 {
+			purchase.SupplierID = nil
+		} else if body.SupplierID != nil {
+			if err = tx.First(&Supplier{}, *body.SupplierID).Error; err != nil {
+				return err
+			}
+			purchase.SupplierID = body.SupplierID
+		}
+
+		if body.Items != nil {
+			if err = CheckBooksExist(tx, PurchaseItemBookIDs(body.Items)); err != nil {
+				return err
+			}
+			if err = copier.Copy(&purchase.Items, &body.Items); err != nil {
+				return err
+			}
+			for i := range purchase.Items {
+				purchase.Items[i].PurchaseID = purchase.ID
+			}
+
+			// replace all items
+			if err = tx.Where("purchase_id = ?", purchase.ID).Delete(&PurchaseItem{}).Error; err != nil {
+				return err
+			}
+			if err = tx.Create(&purchase.Items).Error; err != nil {
+				return err
+			}
+		} else if err = tx.Where("purchase_id = ?", purchase.ID).Find(&purchase.Items).Error; err != nil {
+			return err
+		}
 
-	if err := DB.Save(&purchase).Error; err != nil {
+		return tx.Model(&purchase).Omit(clause.Associations).Updates(map[string]any{
+			"supplier_id": purchase.SupplierID,
+			"updated_at":  time.Now(),
+		}).Error
+	})
+	if err != nil {
 		return err
 	}
 
@@ -183,7 +235,7 @@ func ModifyAPurchase(c *fiber.Ctx) error {
 
 // PayAPurchase godoc
 // @Summary Pay a purchase
-// @Description Pay a purchase by id
+// @Description Pay all items of a purchase by id, write one balance record
 // @Tags Purchase
 // @Produce json
 // @Param id path int true "id"
@@ -202,25 +254,17 @@ func PayAPurchase(c *fiber.Ctx) error {
 
 	var purchase Purchase
 	err = DB.Transaction(func(tx *gorm.DB) error {
-		if err = tx.Clauses(LockClause).First(&purchase, purchaseID).Error; err != nil {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
 			return err
 		}
 
-		if purchase.Paid {
-			return BadRequest("Purchase has been paid")
-		}
-		if purchase.Returned {
-			return BadRequest("Purchase has been returned")
-		}
-
-		purchase.Paid = true
-		if err = tx.Model(&purchase).Update("paid", true).Error; err != nil {
+		if err = purchase.TransitTo(tx, PurchaseStatusPaid, user.ID); err != nil {
 			return err
 		}
 
 		balance := Balance{
 			UserID:        user.ID,
-			Change:        -purchase.Price * purchase.Quantity,
+			Change:        -purchase.Total(),
 			OperationType: OperationTypePurchase,
 			OperationID:   purchase.ID,
 		}
@@ -240,7 +284,7 @@ func PayAPurchase(c *fiber.Ctx) error {
 
 // ReturnAPurchase godoc
 // @Summary Return a purchase
-// @Description Return a purchase by id
+// @Description Return an unpaid purchase by id
 // @Tags Purchase
 // @Produce json
 // @Param id path int true "id"
@@ -259,15 +303,11 @@ func ReturnAPurchase(c *fiber.Ctx) error {
 
 	var purchase Purchase
 	err = DB.Transaction(func(tx *gorm.DB) error {
-		if err = tx.Clauses(LockClause).First(&purchase, purchaseID).Error; err != nil {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
 			return err
 		}
-		if purchase.Paid {
-			return BadRequest("Purchase has been paid")
-		}
 
-		purchase.Returned = true
-		return tx.Model(&purchase).Update("returned", true).Error
+		return purchase.TransitTo(tx, PurchaseStatusReturned, user.ID)
 	})
 	if err != nil {
 		return err
@@ -283,10 +323,14 @@ func ReturnAPurchase(c *fiber.Ctx) error {
 
 // ArriveAPurchase
 // @Summary Arrive a purchase
-// @Description Arrive a purchase by id
+// @Description Receive items of a paid purchase by id, may be called several times for partial arrivals.
+// @Description Receive all outstanding items if items is not set. The purchase is marked as arrived once all items are received.
+// @Description Items are received into the default location if location_id is not set.
 // @Tags Purchase
+// @Accept json
 // @Produce json
 // @Param id path int true "id"
+// @Param json body PurchaseArriveRequest false "body"
 // @Success 200 {object} PurchaseResponse
 // @Router /purchases/{id}/_arrive [post]
 func ArriveAPurchase(c *fiber.Ctx) error {
@@ -300,35 +344,146 @@ func ArriveAPurchase(c *fiber.Ctx) error {
 		return err
 	}
 
+	var body PurchaseArriveRequest
+	if len(c.Body()) > 0 {
+		if err = ValidateBody(c, &body); err != nil {
+			return err
+		}
+	}
+
 	var purchase Purchase
 	err = DB.Transaction(func(tx *gorm.DB) error {
-		if err = tx.Clauses(LockClause).Preload("Book").First(&purchase, purchaseID).Error; err != nil {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
 			return err
 		}
 
-		if !purchase.Paid {
-			return BadRequest("Purchase has not been paid")
+		return purchase.Receive(tx, body.Quantities(), body.LocationID, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var purchaseResponse PurchaseResponse
+	if err := copier.Copy(&purchaseResponse, &purchase); err != nil {
+		return err
+	}
+
+	return c.JSON(&purchaseResponse)
+}
+
+// CloseAPurchase godoc
+// @Summary Close a purchase short
+// @Description Close a paid purchase which will not be fully delivered, the supplier credits the missing units
+// @Tags Purchase
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} PurchaseResponse
+// @Router /purchases/{id}/_close [post]
+func CloseAPurchase(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	purchaseID, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	var purchase Purchase
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
+			return err
 		}
-		if purchase.Returned {
-			return BadRequest("Purchase has been returned")
+
+		return purchase.CloseShort(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var purchaseResponse PurchaseResponse
+	if err = copier.Copy(&purchaseResponse, &purchase); err != nil {
+		return err
+	}
+
+	return c.JSON(&purchaseResponse)
+}
+
+// RefundAPurchase godoc
+// @Summary Refund a purchase
+// @Description Refund a paid purchase which has not arrived, e.g. the supplier cancels the order
+// @Tags Purchase
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} PurchaseResponse
+// @Router /purchases/{id}/_refund [post]
+func RefundAPurchase(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	purchaseID, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	var purchase Purchase
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
+			return err
 		}
 
-		purchase.Arrived = true
-		if err = tx.Model(&purchase).Update("arrived", true).Error; err != nil {
+		return purchase.Refund(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var purchaseResponse PurchaseResponse
+	if err = copier.Copy(&purchaseResponse, &purchase); err != nil {
+		return err
+	}
+
+	return c.JSON(&purchaseResponse)
+}
+
+// CancelAPurchase godoc
+// @Summary Cancel a purchase
+// @Description Cancel an unpaid purchase by id
+// @Tags Purchase
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} PurchaseResponse
+// @Router /purchases/{id}/_cancel [post]
+func CancelAPurchase(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	purchaseID, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	var purchase Purchase
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).Preload("Items").First(&purchase, purchaseID).Error; err != nil {
 			return err
 		}
 
-		// update book stock
-		return tx.Model(&purchase.Book).Update("stock", gorm.Expr("stock + ?", purchase.Quantity)).Error
+		return purchase.TransitTo(tx, PurchaseStatusCancelled, user.ID)
 	})
 	if err != nil {
 		return err
 	}
 
 	var purchaseResponse PurchaseResponse
-	if err := copier.Copy(&purchaseResponse, &purchase); err != nil {
+	if err = copier.Copy(&purchaseResponse, &purchase); err != nil {
 		return err
 	}
 
-	return c.JSON(&purchase)
+	return c.JSON(&purchaseResponse)
 }
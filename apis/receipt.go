@@ -0,0 +1,150 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"sort"
+)
+
+// ListReceipts
+// @Summary List receipts
+// @Tags Receipt
+// @Produce json
+// @Param json query ReceiptListRequest true "query"
+// @Success 200 {object} ReceiptListResponse
+// @Router /receipts [get]
+func ListReceipts(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query ReceiptListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	// construct querySet
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.UserID != nil {
+		querySet = querySet.Where("user_id = ?", *query.UserID)
+	}
+	if query.CustomerID != nil {
+		querySet = querySet.Where("customer_id = ?", *query.CustomerID)
+	}
+	if query.StartTime != nil {
+		querySet = querySet.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		querySet = querySet.Where("created_at <= ?", *query.EndTime)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var receipts []Receipt
+	if err := querySet.Preload("Sales.Book", WithDeleted).Preload("Sales.Promotions").Preload("Sales.PriceOverride").Find(&receipts).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Receipt{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response ReceiptListResponse
+	if err := copier.Copy(&response.Receipts, &receipts); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetAReceipt
+// @Summary Get a receipt by id
+// @Tags Receipt
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} ReceiptResponse
+// @Router /receipts/{id} [get]
+func GetAReceipt(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	receiptID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var receipt Receipt
+	if err := DB.Preload("Sales.Book", WithDeleted).Preload("Sales.Promotions").Preload("Sales.PriceOverride").First(&receipt, receiptID).Error; err != nil {
+		return err
+	}
+
+	var receiptResponse ReceiptResponse
+	if err := copier.Copy(&receiptResponse, &receipt); err != nil {
+		return err
+	}
+
+	return c.JSON(receiptResponse)
+}
+
+// CreateAReceipt
+// @Summary Create a receipt
+// @Description Check out a cart of books in one transaction, write one balance record for the whole receipt
+// @Tags Receipt
+// @Accept json
+// @Produce json
+// @Param json body ReceiptCreateRequest true "body"
+// @Success 201 {object} ReceiptResponse
+// @Router /receipts [post]
+func CreateAReceipt(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body ReceiptCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	// lock books in the same order to avoid deadlocks between concurrent checkouts
+	sort.Slice(body.Sales, func(i, j int) bool {
+		return body.Sales[i].BookID < body.Sales[j].BookID
+	})
+
+	approverID, err := authenticateApprover(body.Approver)
+	if err != nil {
+		return err
+	}
+
+	var receipt Receipt
+	if err = copier.Copy(&receipt, &body); err != nil {
+		return err
+	}
+	receipt.UserID = user.ID
+	receipt.ApproverID = approverID
+	for i := range receipt.Sales {
+		if receipt.Sales[i].LocationID == 0 {
+			receipt.Sales[i].LocationID = body.LocationID
+		}
+		receipt.Sales[i].PriceOverride = body.Sales[i].NewPriceOverride(nil) // approved by the approver of the receipt
+	}
+
+	if err = DB.Transaction(receipt.Checkout); err != nil {
+		return err
+	}
+
+	var receiptResponse ReceiptResponse
+	if err = copier.Copy(&receiptResponse, &receipt); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(receiptResponse)
+}
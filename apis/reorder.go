@@ -0,0 +1,121 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ListReorderSuggestions godoc
+// @Summary List books need reordering
+// @Description Books on sale whose stock and quantity on order are not above the reorder point, evaluated hourly
+// @Tags Reorder
+// @Produce json
+// @Param json query ReorderSuggestionListRequest true "query"
+// @Success 200 {object} ReorderSuggestionListResponse
+// @Router /reorder_suggestions [get]
+func ListReorderSuggestions(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query ReorderSuggestionListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.SupplierID != nil {
+		querySet = querySet.Where("supplier_id = ?", *query.SupplierID)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var suggestions []ReorderSuggestion
+	if err := querySet.Preload("Book", WithDeleted).Find(&suggestions).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&ReorderSuggestion{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response ReorderSuggestionListResponse
+	if err := copier.Copy(&response.Suggestions, &suggestions); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// EvaluateReorderSuggestions godoc
+// @Summary Evaluate reorder suggestions now
+// @Description Evaluate the stock of books against their reorder points and recent sales, and replace all suggestions
+// @Tags Reorder
+// @Produce json
+// @Success 200 {object} ReorderEvaluateResponse
+// @Router /reorder_suggestions/_evaluate [post]
+func EvaluateReorderSuggestions(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	total, added, err := EvaluateReorders(DB, time.Now())
+	if err != nil {
+		return err
+	}
+
+	response := ReorderEvaluateResponse{Total: total, Added: added}
+	if response.Added == nil {
+		response.Added = []int{}
+	}
+	return c.JSON(&response)
+}
+
+// DraftPurchasesFromSuggestions godoc
+// @Summary Draft purchases from reorder suggestions
+// @Description Create draft purchases of the suggested quantities at the latest purchase prices, one purchase for each supplier
+// @Description and one for books whose supplier is unknown. Drafted suggestions are removed.
+// @Description Books never purchased have no price and are skipped, they are kept in the suggestions to be purchased manually.
+// @Tags Reorder
+// @Accept json
+// @Produce json
+// @Param json body ReorderDraftRequest false "body"
+// @Success 201 {array} PurchaseResponse
+// @Router /reorder_suggestions/_draft [post]
+func DraftPurchasesFromSuggestions(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body ReorderDraftRequest
+	if len(c.Body()) > 0 {
+		if err := ValidateBody(c, &body); err != nil {
+			return err
+		}
+	}
+
+	var purchases []Purchase
+	err := DB.Transaction(func(tx *gorm.DB) (err error) {
+		purchases, err = DraftReorderPurchases(tx, body.BookIDs, user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var response = make([]PurchaseResponse, 0, len(purchases))
+	if err = copier.Copy(&response, &purchases); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
@@ -33,8 +33,99 @@ func RegisterRoutes(app *fiber.App) {
 
 	// book
 	router.Get("/books", ListBooks)
+	router.Get("/books/:id", GetABook)
+	router.Post("/books/_import", ImportBooks)
 	router.Post("/books", CreateABook)
 	router.Patch("/books/:id", ModifyABook)
+	router.Delete("/books/:id", DeleteABook)
+	router.Post("/books/:id/_restore", RestoreABook)
+	router.Get("/books/:id/prices", ListBookPrices)
+	router.Post("/books/:id/prices", CreateABookPrice)
+	router.Delete("/books/:id/prices/:price_id", DeleteABookPrice)
+
+	// category
+	router.Get("/categories", ListCategories)
+	router.Get("/categories/:id", GetACategory)
+	router.Post("/categories", CreateACategory)
+	router.Patch("/categories/:id", ModifyACategory)
+	router.Delete("/categories/:id", DeleteACategory)
+
+	// tag
+	router.Get("/tags", ListTags)
+	router.Post("/tags", CreateATag)
+	router.Patch("/tags/:id", ModifyATag)
+	router.Delete("/tags/:id", DeleteATag)
+
+	// contributor
+	router.Get("/contributors", ListContributors)
+	router.Get("/contributors/:id", GetAContributor)
+	router.Post("/contributors", CreateAContributor)
+	router.Patch("/contributors/:id", ModifyAContributor)
+	router.Delete("/contributors/:id", DeleteAContributor)
+
+	// image
+	router.Post("/images", UploadAnImage)
+	router.Get("/images/:id", imageCache, GetAnImage)
+
+	// location
+	router.Get("/locations", ListLocations)
+	router.Get("/locations/:id", GetALocation)
+	router.Post("/locations", CreateALocation)
+	router.Patch("/locations/:id", ModifyALocation)
+	router.Delete("/locations/:id", DeleteALocation)
+	router.Get("/transfers", ListStockTransfers)
+	router.Post("/transfers", CreateAStockTransfer)
+
+	// stock movement
+	router.Get("/stock_movements", ListStockMovements)
+	router.Post("/stock_movements", CreateAStockMovement)
+
+	// stocktake
+	router.Get("/stocktakes", ListStocktakes)
+	router.Get("/stocktakes/:id", GetAStocktake)
+	router.Post("/stocktakes", CreateAStocktake)
+	router.Post("/stocktakes/:id/counts", AddStocktakeCounts)
+	router.Get("/stocktakes/:id/variances", GetStocktakeVariances)
+	router.Post("/stocktakes/:id/_post", PostAStocktake)
+	router.Post("/stocktakes/:id/_cancel", CancelAStocktake)
+
+	// supplier
+	router.Get("/suppliers", ListSuppliers)
+	router.Get("/suppliers/:id", GetASupplier)
+	router.Get("/suppliers/:id/summary", GetASupplierSummary)
+	router.Post("/suppliers", CreateASupplier)
+	router.Patch("/suppliers/:id", ModifyASupplier)
+	router.Delete("/suppliers/:id", DeleteASupplier)
+
+	// customer
+	router.Get("/customers", ListCustomers)
+	router.Get("/customers/_lookup", LookupACustomer)
+	router.Get("/customers/:id", GetACustomer)
+	router.Get("/customers/:id/history", GetACustomerHistory)
+	router.Get("/customers/:id/points", ListCustomerPoints)
+	router.Post("/customers/:id/points", AdjustCustomerPoints)
+	router.Post("/customers", CreateACustomer)
+	router.Patch("/customers/:id", ModifyACustomer)
+	router.Delete("/customers/:id", DeleteACustomer)
+
+	// loyalty
+	router.Get("/membership_tiers", ListMembershipTiers)
+	router.Post("/membership_tiers", CreateAMembershipTier)
+	router.Patch("/membership_tiers/:id", ModifyAMembershipTier)
+	router.Delete("/membership_tiers/:id", DeleteAMembershipTier)
+
+	// promotion
+	router.Get("/promotions", ListPromotions)
+	router.Get("/promotions/_summary", GetPromotionSummaries)
+	router.Get("/promotions/:id", GetAPromotion)
+	router.Post("/promotions", CreateAPromotion)
+	router.Patch("/promotions/:id", ModifyAPromotion)
+	router.Delete("/promotions/:id", DeleteAPromotion)
+	router.Get("/coupons", ListCoupons)
+	router.Get("/coupons/_lookup", LookupACoupon)
+	router.Post("/coupons", CreateACoupon)
+	router.Patch("/coupons/:id", ModifyACoupon)
+	router.Delete("/coupons/:id", DeleteACoupon)
 
 	// purchase
 	router.Get("/purchases", ListPurchases)
@@ -44,6 +135,14 @@ func RegisterRoutes(app *fiber.App) {
 	router.Post("/purchases/:id/_pay", PayAPurchase)
 	router.Post("/purchases/:id/_return", ReturnAPurchase)
 	router.Post("/purchases/:id/_arrive", ArriveAPurchase)
+	router.Post("/purchases/:id/_cancel", CancelAPurchase)
+	router.Post("/purchases/:id/_close", CloseAPurchase)
+	router.Post("/purchases/:id/_refund", RefundAPurchase)
+
+	// reorder
+	router.Get("/reorder_suggestions", ListReorderSuggestions)
+	router.Post("/reorder_suggestions/_evaluate", EvaluateReorderSuggestions)
+	router.Post("/reorder_suggestions/_draft", DraftPurchasesFromSuggestions)
 
 	// balance
 	router.Get("/balances", ListBalances)
@@ -54,4 +153,12 @@ func RegisterRoutes(app *fiber.App) {
 	router.Get("/sales", ListSales)
 	router.Get("/sales/:id", GetASale)
 	router.Post("/sales", CreateASale)
+	router.Post("/sales/:id/_refund", RefundASale)
+	router.Get("/sales/:id/refunds", ListSaleRefunds)
+	router.Get("/price_overrides", ListPriceOverrides)
+
+	// receipt
+	router.Get("/receipts", ListReceipts)
+	router.Get("/receipts/:id", GetAReceipt)
+	router.Post("/receipts", CreateAReceipt)
 }
@@ -10,8 +10,9 @@ import (
 
 // ListSales
 // @Summary List sales
+// @Description Set export to csv, xlsx or ndjson to download all the filtered records as a file
 // @Tags Sale
-// @Produce json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
 // @Param json query SaleListRequest true "query"
 // @Success 200 {object} SaleListResponse
 // @Router /sales [get]
@@ -34,6 +35,15 @@ func ListSales(c *fiber.Ctx) error {
 	if query.UserID != nil {
 		querySet = querySet.Where("user_id = ?", *query.UserID)
 	}
+	if query.ReceiptID != nil {
+		querySet = querySet.Where("receipt_id = ?", *query.ReceiptID)
+	}
+	if query.CustomerID != nil {
+		querySet = querySet.Where("customer_id = ?", *query.CustomerID)
+	}
+	if query.PromotionID != nil {
+		querySet = querySet.Where("id IN (?)", DB.Model(&SalePromotion{}).Select("sale_id").Where("promotion_id = ?", *query.PromotionID))
+	}
 	if query.StartTime != nil {
 		querySet = querySet.Where("created_at >= ?", *query.StartTime)
 	}
@@ -43,8 +53,22 @@ func ListSales(c *fiber.Ctx) error {
 
 	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
 
+	if query.Export != "" {
+		return Export(c, "sales", query.Export, querySet.Preload("Book", WithDeleted).Preload("Promotions").Preload("PriceOverride"), func(sales []Sale) (response []SaleResponse, err error) {
+			if err = copier.Copy(&response, &sales); err != nil {
+				return nil, err
+			}
+			for i := range response {
+				if err = copier.Copy(&response[i].Book, &sales[i].Book); err != nil {
+					return nil, err
+				}
+			}
+			return response, nil
+		})
+	}
+
 	var sales []Sale
-	if err := querySet.Preload("Book").Find(&sales).Error; err != nil {
+	if err := querySet.Preload("Book", WithDeleted).Preload("Promotions").Preload("PriceOverride").Find(&sales).Error; err != nil {
 		return err
 	}
 
@@ -82,7 +106,7 @@ func GetASale(c *fiber.Ctx) error {
 	}
 
 	var sale Sale
-	if err := DB.First(&sale, c.Params("id")).Error; err != nil {
+	if err := DB.Preload("Book", WithDeleted).Preload("Promotions").Preload("PriceOverride").First(&sale, c.Params("id")).Error; err != nil {
 		return err
 	}
 
@@ -113,20 +137,105 @@ func CreateASale(c *fiber.Ctx) error {
 		return err
 	}
 
+	approverID, err := authenticateApprover(body.Approver)
+	if err != nil {
+		return err
+	}
+
 	var sale Sale
-	if err := copier.Copy(&sale, &body); err != nil {
+	if err = copier.Copy(&sale, &body); err != nil {
 		return err
 	}
 	sale.UserID = user.ID
+	sale.PriceOverride = body.NewPriceOverride(approverID)
 
-	if err := DB.Create(&sale).Error; err != nil {
+	if err = DB.Create(&sale).Error; err != nil {
 		return err
 	}
 
 	var saleResponse SaleResponse
-	if err := copier.Copy(&saleResponse, &sale); err != nil {
+	if err = copier.Copy(&saleResponse, &sale); err != nil {
 		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(saleResponse)
 }
+
+// RefundASale
+// @Summary Refund a sale
+// @Description Refund a sale fully or partially by quantity, restore the stock and write a negative balance. Archived books must be restored first
+// @Tags Sale
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body SaleRefundRequest false "body, refund all remaining quantity if empty"
+// @Success 201 {object} SaleRefundResponse
+// @Router /sales/{id}/_refund [post]
+func RefundASale(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	saleID, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	var body SaleRefundRequest
+	if len(c.Body()) > 0 {
+		if err = ValidateBody(c, &body); err != nil {
+			return err
+		}
+	}
+
+	var refund SaleRefund
+	if err = copier.Copy(&refund, &body); err != nil {
+		return err
+	}
+	refund.SaleID = saleID
+	refund.UserID = user.ID
+
+	// refundable quantity is checked under lock in refund hooks
+	if err = DB.Create(&refund).Error; err != nil {
+		return err
+	}
+
+	var refundResponse SaleRefundResponse
+	if err = copier.Copy(&refundResponse, &refund); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(refundResponse)
+}
+
+// ListSaleRefunds
+// @Summary List refunds of a sale
+// @Tags Sale
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {array} SaleRefundResponse
+// @Router /sales/{id}/refunds [get]
+func ListSaleRefunds(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	saleID, err := c.ParamsInt("id")
+	if err != nil {
+		return err
+	}
+
+	var refunds []SaleRefund
+	if err = DB.Where("sale_id = ?", saleID).Order("id asc").Find(&refunds).Error; err != nil {
+		return err
+	}
+
+	var response = make([]SaleRefundResponse, 0, len(refunds))
+	if err = copier.Copy(&response, &refunds); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
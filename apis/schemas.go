@@ -2,23 +2,38 @@ package apis
 
 import (
 	"book_management_system_backend/models"
+	"book_management_system_backend/utils"
 	"time"
 )
 
+// ExportRequest 导出完整的筛选结果而不是分页的 json, 分页参数将被忽略
+type ExportRequest struct {
+	Export string `json:"export" query:"export" validate:"omitempty,oneof=csv xlsx ndjson"`
+}
+
 type CountByMonth struct {
 	Month string
 	Count int64
 }
 
+// SaleByCategory 顶级分类的销售统计, 包含子分类, 已扣除退款
+type SaleByCategory struct {
+	CategoryID int     `json:"category_id"`
+	Name       string  `json:"name"`
+	Quantity   int     `json:"quantity"`
+	Amount     float64 `json:"amount"`
+}
+
 type MetaInfo struct {
-	UserCount            int64          `json:"user_count"`
-	BookCount            int64          `json:"book_count"`
-	PurchaseCount        int64          `json:"purchase_count"`
-	PurchaseCountByMonth []CountByMonth `json:"purchase_count_by_month"`
-	SaleCount            int64          `json:"sale_count"`
-	SaleCountByMonth     []CountByMonth `json:"sale_count_by_month"`
-	BalanceCount         int64          `json:"balance_count"`
-	BalanceCountByMonth  []CountByMonth `json:"balance_count_by_month"`
+	UserCount            int64            `json:"user_count"`
+	BookCount            int64            `json:"book_count"`
+	PurchaseCount        int64            `json:"purchase_count"`
+	PurchaseCountByMonth []CountByMonth   `json:"purchase_count_by_month"`
+	SaleCount            int64            `json:"sale_count"`
+	SaleCountByMonth     []CountByMonth   `json:"sale_count_by_month"`
+	BalanceCount         int64            `json:"balance_count"`
+	BalanceCountByMonth  []CountByMonth   `json:"balance_count_by_month"`
+	SaleByCategory       []SaleByCategory `json:"sale_by_category"`
 }
 
 func ToOrderString(orderBy string, sort string) string {
@@ -27,7 +42,7 @@ func ToOrderString(orderBy string, sort string) string {
 
 type UserInfo struct {
 	IsAdmin  bool    `json:"is_admin" default:"false"`
-	Avatar   *string `json:"avatar,omitempty"`
+	Avatar   *string `json:"avatar,omitempty"` // http(s) url or the url of an uploaded image
 	RealName *string `json:"real_name,omitempty"`
 	Gender   *string `json:"gender,omitempty"`
 	StaffID  *string `json:"staff_id,omitempty"`
@@ -77,6 +92,7 @@ type UserListResponse struct {
 
 type BookListRequest struct {
 	models.PageRequest
+	ExportRequest
 	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id isbn updated_at created_at title author press published_date price stock" default:"id"`
 	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
 	Title   *string `json:"title" query:"title"`
@@ -85,18 +101,42 @@ type BookListRequest struct {
 	OnSale  *bool   `json:"on_sale" query:"on_sale"`
 	ID      *int    `json:"id" query:"id"`
 	ISBN    *string `json:"isbn" query:"isbn"`
+	Q       *string `json:"q" query:"q"` // search title, author, press, description and isbn, ordered by relevance before order_by
+
+	CategoryID *int    `json:"category_id" query:"category_id"` // including descendant categories
+	Tag        *string `json:"tag" query:"tag"`                 // tag name
+
+	ContributorID   *int    `json:"contributor_id" query:"contributor_id"`
+	ContributorRole *string `json:"contributor_role" query:"contributor_role" validate:"omitempty,oneof=author translator editor illustrator"` // only with contributor_id
+
+	LocationID *int `json:"location_id" query:"location_id"` // books in stock at the location
+
+	IncludeDeleted bool `json:"include_deleted" query:"include_deleted"` // include archived books, admin only
+}
+
+// BookContributorRequest a contributor given by id, or by name which is created if not exist
+type BookContributorRequest struct {
+	ContributorID *int    `json:"contributor_id" validate:"required_without=Name,omitempty,min=1"`
+	Name          *string `json:"name" validate:"required_without=ContributorID,omitempty,min=1,max=128"`
+	Role          string  `json:"role" validate:"oneof=author translator editor illustrator" default:"author"`
 }
 
 type BookCreateRequest struct {
-	ISBN          string     `json:"isbn" validate:"required,min=1"`
-	Title         string     `json:"title" validate:"required,min=1"`
-	Description   *string    `json:"description"`
-	Author        string     `json:"author" validate:"required,min=1"`
-	Press         string     `json:"press" validate:"required,min=1"`
-	PublishedDate *time.Time `json:"published_date"`
-	PriceFloat    *float64   `json:"price" validate:"omitempty,min=0"`
-	Cover         *string    `json:"cover"` // cover url or base64, null if not set
-	OnSale        bool       `json:"on_sale" default:"false"`
+	ISBN          string                   `json:"isbn" validate:"required,isbn"` // ISBN-10 or ISBN-13, stored as ISBN-13 without hyphens
+	Title         string                   `json:"title" validate:"required,min=1"`
+	Description   *string                  `json:"description"`
+	Author        string                   `json:"author" validate:"required_without=Contributors,omitempty,min=1"` // split into contributors if contributors is not set, defaults to the byline of contributors
+	Press         string                   `json:"press" validate:"required,min=1"`
+	PublishedDate *time.Time               `json:"published_date"`
+	PriceFloat    *float64                 `json:"price" validate:"omitempty,min=0"`
+	Cover         *string                  `json:"cover"` // http(s) url or the url of an uploaded image, null if not set
+	OnSale        bool                     `json:"on_sale" default:"false"`
+	CategoryIDs   []int                    `json:"category_ids" validate:"omitempty,unique,dive,min=1"`
+	TagNames      []string                 `json:"tags" validate:"omitempty,unique,dive,min=1,max=64"` // tags are created if not exist
+	Contributors  []BookContributorRequest `json:"contributors" validate:"omitempty,min=1,dive"`       // in order
+	// 补货点和补货数量, null 表示按近期销量计算
+	ReorderPoint    *int `json:"reorder_point" validate:"omitempty,min=0"`
+	ReorderQuantity *int `json:"reorder_quantity" validate:"omitempty,min=1"`
 }
 
 func (b *BookCreateRequest) Price() *int {
@@ -108,14 +148,20 @@ func (b *BookCreateRequest) Price() *int {
 }
 
 type BookModifyRequest struct {
-	Title         *string    `json:"title" validate:"omitempty,min=1"`
-	Description   *string    `json:"description"`
-	Author        *string    `json:"author" validate:"omitempty,min=1"`
-	Press         *string    `json:"press" validate:"omitempty,min=1"`
-	PublishedDate *time.Time `json:"published_date"`
-	PriceFloat    *float64   `json:"price" validate:"omitempty,min=0"`
-	Cover         *string    `json:"cover"` // cover url or base64, null if not set
-	OnSale        *bool      `json:"on_sale"`
+	Title         *string                  `json:"title" validate:"omitempty,min=1"`
+	Description   *string                  `json:"description"`
+	Author        *string                  `json:"author" validate:"omitempty,min=1"` // split into contributors if contributors is not set
+	Press         *string                  `json:"press" validate:"omitempty,min=1"`
+	PublishedDate *time.Time               `json:"published_date"`
+	PriceFloat    *float64                 `json:"price" validate:"omitempty,min=0"`
+	Cover         *string                  `json:"cover"` // http(s) url or the url of an uploaded image, null if not set
+	OnSale        *bool                    `json:"on_sale"`
+	CategoryIDs   []int                    `json:"category_ids" validate:"omitempty,unique,dive,min=1"` // replace categories if set, [] to clear
+	TagNames      []string                 `json:"tags" validate:"omitempty,unique,dive,min=1,max=64"`  // replace tags if set, [] to clear
+	Contributors  []BookContributorRequest `json:"contributors" validate:"omitempty,min=1,dive"`        // replace contributors if set, author defaults to their byline
+	// 补货点和补货数量
+	ReorderPoint    *int `json:"reorder_point" validate:"omitempty,min=0"`
+	ReorderQuantity *int `json:"reorder_quantity" validate:"omitempty,min=1"`
 }
 
 func (b *BookModifyRequest) Price() *int {
@@ -137,10 +183,99 @@ type BookResponse struct {
 	Author        string     `json:"author" gorm:"not null"`
 	Press         string     `json:"press" gorm:"not null"`
 	PublishedDate *time.Time `json:"published_date"`
-	Cover         *string    `json:"cover"` // cover url or base64, null if not set
+	Cover         *string    `json:"cover"` // http(s) url or the url of an uploaded image, null if not set
 	PriceFloat    *float64   `json:"price"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
 	Stock         int        `json:"stock" gorm:"default:0;not null"`
 	OnSale        bool       `json:"on_sale" gorm:"default:false;not null"`
+	// 补货点和补货数量, null 表示按近期销量计算
+	ReorderPoint    *int                      `json:"reorder_point"`
+	ReorderQuantity *int                      `json:"reorder_quantity"`
+	ArchivedAt      *time.Time                `json:"archived_at,omitempty"` // null if not archived
+	Snippet         *string                   `json:"snippet,omitempty"`     // highlighted with <mark>, only returned when searching with q
+	Categories      []CategoryResponse        `json:"categories"`
+	Tags            []TagResponse             `json:"tags"`
+	Contributors    []BookContributorResponse `json:"contributors"`
+	Stocks          []BookStockResponse       `json:"stocks"` // stock of each location, locations out of stock are omitted
+}
+
+type BookStockResponse struct {
+	LocationID int `json:"location_id"`
+	Stock      int `json:"stock"`
+}
+
+type BookContributorResponse struct {
+	ContributorID int    `json:"contributor_id"`
+	Name          string `json:"name"`
+	Role          string `json:"role"`
+}
+
+type BookSummaryResponse struct {
+	BookResponse
+	TotalSold           int        `json:"total_sold"`            // 销售数量, 已扣除退款
+	TotalPurchased      int        `json:"total_purchased"`       // 已付款的采购数量, 结单的采购单只计算已收货部分
+	PendingArrivals     int        `json:"pending_arrivals"`      // 已付款未收货的数量
+	LastSaleTime        *time.Time `json:"last_sale_time"`        // null if never sold
+	AveragePurchaseCost *float64   `json:"average_purchase_cost"` // 加权平均采购单价, null if never purchased
+	Margin              *float64   `json:"margin"`                // (price - average_purchase_cost) / price, null if not available
+}
+
+type BookPriceListRequest struct {
+	models.PageRequest
+	Sort    string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"desc"` // by effective_at
+	At      *time.Time `json:"at" query:"at"`                                              // changes effective at the time, the first one in desc order is the price at the time
+	Pending *bool      `json:"pending" query:"pending"`                                    // scheduled changes not applied yet
+}
+
+type BookPriceCreateRequest struct {
+	PriceFloat  float64    `json:"price" validate:"min=0"`
+	EffectiveAt *time.Time `json:"effective_at"` // null to take effect immediately, must not be in the past
+}
+
+func (p *BookPriceCreateRequest) Price() int {
+	return int(p.PriceFloat * 100)
+}
+
+type BookPriceResponse struct {
+	ID          int        `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	BookID      int        `json:"book_id"`
+	UserID      int        `json:"user_id"` // user who change the price
+	PriceFloat  float64    `json:"price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	AppliedAt   *time.Time `json:"applied_at"` // null if pending
+}
+
+type BookPriceListResponse struct {
+	Prices    []BookPriceResponse `json:"prices"`
+	PageTotal int                 `json:"page_total"`
+}
+
+type BookImportRequest struct {
+	Format string `json:"format" query:"format" validate:"oneof=csv onix" default:"csv"`
+	DryRun bool   `json:"dry_run" query:"dry_run"` // validate and report only, nothing is saved
+}
+
+const (
+	BookImportStatusCreated = "created"
+	BookImportStatusUpdated = "updated"
+	BookImportStatusFailed  = "failed"
+)
+
+type BookImportRowResponse struct {
+	Row     int                `json:"row"` // csv data row number excluding the header, or ONIX Product index, starts from 1
+	ISBN    string             `json:"isbn"`
+	Status  string             `json:"status"`            // created, updated or failed
+	BookID  int                `json:"book_id,omitempty"` // empty if failed, or created in dry run
+	Message string             `json:"message,omitempty"`
+	Detail  *utils.ErrorDetail `json:"detail,omitempty"`
+}
+
+type BookImportResponse struct {
+	DryRun  bool                    `json:"dry_run"`
+	Created int                     `json:"created"`
+	Updated int                     `json:"updated"`
+	Failed  int                     `json:"failed"`
+	Rows    []BookImportRowResponse `json:"rows"`
 }
 
 type BookListResponse struct {
@@ -152,47 +287,116 @@ type BookListResponse struct {
 
 type PurchaseListRequest struct {
 	models.PageRequest
-	OrderBy string `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at book_id user_id" default:"id"`
-	Sort    string `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
-	BookID  *int   `json:"book_id" query:"book_id"`
-	UserID  *int   `json:"user_id" query:"user_id"`
+	ExportRequest
+	OrderBy    string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at user_id supplier_id status" default:"id"`
+	Sort       string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	BookID     *int    `json:"book_id" query:"book_id"`
+	UserID     *int    `json:"user_id" query:"user_id"`
+	SupplierID *int    `json:"supplier_id" query:"supplier_id"`
+	Status     *string `json:"status" query:"status" validate:"omitempty,oneof=draft paid arrived returned cancelled refunded closed"`
 }
 
-type PurchaseCreateRequest struct {
+type PurchaseItemRequest struct {
 	BookID     int     `json:"book_id" validate:"required,min=1"`
 	Quantity   int     `json:"quantity" validate:"required,min=1"`
 	PriceFloat float64 `json:"price" validate:"required,min=0"`
 }
 
-func (p *PurchaseCreateRequest) Price() int {
+func (p *PurchaseItemRequest) Price() int {
 	return int(p.PriceFloat * 100)
 }
 
+func PurchaseItemBookIDs(items []PurchaseItemRequest) []int {
+	bookIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		bookIDs = append(bookIDs, item.BookID)
+	}
+	return bookIDs
+}
+
+type PurchaseCreateRequest struct {
+	SupplierID *int                  `json:"supplier_id" validate:"omitempty,min=1"`
+	Items      []PurchaseItemRequest `json:"items" validate:"required,min=1,unique=BookID,dive"`
+}
+
+// PurchaseModifyRequest changes the supplier or replaces all items of a purchase
 type PurchaseModifyRequest struct {
-	Quantity   *int     `json:"quantity" validate:"omitempty,min=1"`
-	PriceFloat *float64 `json:"price" validate:"omitempty,min=0"`
+	SupplierID *int                  `json:"supplier_id" validate:"omitempty,min=0"` // 0 removes the supplier
+	Items      []PurchaseItemRequest `json:"items" validate:"omitempty,min=1,unique=BookID,dive"`
+}
+
+type PurchaseArriveItemRequest struct {
+	BookID   int `json:"book_id" validate:"required,min=1"`
+	Quantity int `json:"quantity" validate:"required,min=1"`
+}
+
+type PurchaseArriveRequest struct {
+	Items      []PurchaseArriveItemRequest `json:"items" validate:"omitempty,min=1,unique=BookID,dive"` // receive all outstanding items if not set
+	LocationID int                         `json:"location_id" validate:"omitempty,min=1"`              // the default location if not set
 }
 
-func (p *PurchaseModifyRequest) Price() *int {
-	if p.PriceFloat == nil {
+// Quantities maps book id to received quantity, nil means all outstanding items
+func (p *PurchaseArriveRequest) Quantities() map[int]int {
+	if p.Items == nil {
 		return nil
 	}
-	price := int(*p.PriceFloat * 100)
-	return &price
+	quantities := make(map[int]int, len(p.Items))
+	for _, item := range p.Items {
+		quantities[item.BookID] = item.Quantity
+	}
+	return quantities
+}
+
+type PurchaseItemResponse struct {
+	ID                  int           `json:"id"`
+	BookID              int           `json:"book_id"`
+	Quantity            int           `json:"quantity"`
+	ReceivedQuantity    int           `json:"received_quantity"`
+	OutstandingQuantity int           `json:"outstanding_quantity"`
+	PriceFloat          float64       `json:"price"`
+	Book                *BookResponse `json:"book,omitempty"`
+}
+
+type PurchaseArrivalItemResponse struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
+
+type PurchaseArrivalResponse struct {
+	ID         int                           `json:"id"`
+	CreatedAt  time.Time                     `json:"created_at"`
+	UserID     int                           `json:"user_id"`
+	LocationID int                           `json:"location_id"`
+	Items      []PurchaseArrivalItemResponse `json:"items"`
 }
 
 type PurchaseResponse struct {
-	ID         int           `json:"id"`
-	CreatedAt  time.Time     `json:"created_at"`
-	UpdatedAt  time.Time     `json:"updated_at"`
-	BookID     int           `json:"book_id"`
-	UserID     int           `json:"user_id"`
-	Quantity   int           `json:"quantity"`
-	PriceFloat float64       `json:"price"`
-	Paid       bool          `json:"paid"`
-	Arrived    bool          `json:"arrived"`
-	Returned   bool          `json:"returned"`
-	Book       *BookResponse `json:"book,omitempty"`
+	ID                    int                          `json:"id"`
+	CreatedAt             time.Time                    `json:"created_at"`
+	UpdatedAt             time.Time                    `json:"updated_at"`
+	UserID                int                          `json:"user_id"`
+	SupplierID            *int                         `json:"supplier_id"`
+	TotalFloat            float64                      `json:"total"`
+	OutstandingTotalFloat float64                      `json:"outstanding_total"`
+	Status                string                       `json:"status"`
+	PaidAt                *time.Time                   `json:"paid_at"`
+	PaidBy                *int                         `json:"paid_by"`
+	ArrivedAt             *time.Time                   `json:"arrived_at"`
+	ArrivedBy             *int                         `json:"arrived_by"`
+	ReturnedAt            *time.Time                   `json:"returned_at"`
+	ReturnedBy            *int                         `json:"returned_by"`
+	CancelledAt           *time.Time                   `json:"cancelled_at"`
+	CancelledBy           *int                         `json:"cancelled_by"`
+	Items                 []PurchaseItemResponse       `json:"items"`
+	Arrivals              []PurchaseArrivalResponse    `json:"arrivals,omitempty"`
+	Transitions           []PurchaseTransitionResponse `json:"transitions,omitempty"`
+}
+
+type PurchaseTransitionResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int       `json:"user_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
 }
 
 type PurchaseListResponse struct {
@@ -200,10 +404,687 @@ type PurchaseListResponse struct {
 	PageTotal int                `json:"page_total"`
 }
 
+/* Reorder */
+
+type ReorderSuggestionListRequest struct {
+	models.PageRequest
+	OrderBy    string `json:"order_by" query:"order_by" validate:"oneof=book_id supplier_id daily_sales quantity" default:"book_id"`
+	Sort       string `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	SupplierID *int   `json:"supplier_id" query:"supplier_id"`
+}
+
+type ReorderDraftRequest struct {
+	BookIDs []int `json:"book_ids" validate:"omitempty,min=1,unique,dive,min=1"` // draft all suggestions if not set
+}
+
+type ReorderSuggestionResponse struct {
+	BookID       int           `json:"book_id"`
+	Book         *BookResponse `json:"book,omitempty"`
+	SupplierID   *int          `json:"supplier_id"` // supplier of the latest purchase of the book, null if unknown
+	Stock        int           `json:"stock"`
+	OnOrder      int           `json:"on_order"`      // outstanding quantity of draft and paid purchases
+	DailySales   float64       `json:"daily_sales"`   // average daily sales in recent days
+	ReorderPoint int           `json:"reorder_point"` // reorder point of the book, or sales during the lead time if not set
+	Quantity     int           `json:"quantity"`      // suggested quantity to purchase
+	PriceFloat   *float64      `json:"price"`         // price of the latest purchase, null if never purchased
+	EvaluatedAt  time.Time     `json:"evaluated_at"`
+}
+
+type ReorderSuggestionListResponse struct {
+	Suggestions []ReorderSuggestionResponse `json:"suggestions"`
+	PageTotal   int                         `json:"page_total"`
+}
+
+type ReorderEvaluateResponse struct {
+	Total int   `json:"total"` // number of books need reordering
+	Added []int `json:"added"` // books newly need reordering since last evaluated
+}
+
+/* Category */
+
+type CategoryListRequest struct {
+	models.PageRequest
+	OrderBy  string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at code name path" default:"id"`
+	Sort     string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	ParentID *int    `json:"parent_id" query:"parent_id"` // direct children of the category
+	Root     bool    `json:"root" query:"root"`           // top level categories only
+	Code     *string `json:"code" query:"code"`           // code prefix, e.g. I2 matches I247
+	Name     *string `json:"name" query:"name"`
+}
+
+type CategoryCreateRequest struct {
+	ParentID *int    `json:"parent_id" validate:"omitempty,min=1"` // null for top level
+	Code     *string `json:"code" validate:"omitempty,min=1,max=32"`
+	Name     string  `json:"name" validate:"required,min=1,max=128"`
+}
+
+type CategoryModifyRequest struct {
+	ParentID *int    `json:"parent_id" validate:"omitempty,min=0"` // 0 moves the category to the top level
+	Code     *string `json:"code" validate:"omitempty,min=1,max=32"`
+	Name     *string `json:"name" validate:"omitempty,min=1,max=128"`
+}
+
+type CategoryResponse struct {
+	ID        int                `json:"id"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	ParentID  *int               `json:"parent_id"`
+	Code      *string            `json:"code"`
+	Name      string             `json:"name"`
+	Path      string             `json:"path"`
+	Children  []CategoryResponse `json:"children,omitempty"` // direct children, only returned by GetACategory
+}
+
+type CategoryListResponse struct {
+	Categories []CategoryResponse `json:"categories"`
+	PageTotal  int                `json:"page_total"`
+}
+
+/* Tag */
+
+type TagListRequest struct {
+	models.PageRequest
+	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id created_at name" default:"id"`
+	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	Name    *string `json:"name" query:"name"`
+}
+
+type TagCreateRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+type TagModifyRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+type TagResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type TagListResponse struct {
+	Tags      []TagResponse `json:"tags"`
+	PageTotal int           `json:"page_total"`
+}
+
+/* Contributor */
+
+type ContributorListRequest struct {
+	models.PageRequest
+	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at name" default:"id"`
+	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	Name    *string `json:"name" query:"name"`
+}
+
+type ContributorCreateRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=128"`
+}
+
+type ContributorModifyRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=128"` // the author field of its books is updated
+}
+
+type ContributorResponse struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+type ContributorListResponse struct {
+	Contributors []ContributorResponse `json:"contributors"`
+	PageTotal    int                   `json:"page_total"`
+}
+
+/* Image */
+
+type ImageResponse struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	URL          string    `json:"url"`           // set as the cover of a book or the avatar of a user
+	ThumbnailURL string    `json:"thumbnail_url"` // fits in IMAGE_THUMBNAIL_SIZE
+	ContentType  string    `json:"content_type"`
+	Size         int       `json:"size"` // bytes
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+}
+
+/* Location */
+
+type LocationCreateRequest struct {
+	Name        string  `json:"name" validate:"required,min=1,max=64"`
+	Description *string `json:"description"`
+	IsDefault   bool    `json:"is_default"` // the previous default location is unset
+}
+
+type LocationModifyRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=64"`
+	Description *string `json:"description"`
+	IsDefault   *bool   `json:"is_default" validate:"omitempty,eq=true"` // set another location as default to unset it
+}
+
+type LocationResponse struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	IsDefault   bool      `json:"is_default"`
+}
+
+type StockTransferListRequest struct {
+	models.PageRequest
+	OrderBy    string     `json:"order_by" query:"order_by" validate:"oneof=id created_at" default:"id"`
+	Sort       string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	BookID     *int       `json:"book_id" query:"book_id"`
+	LocationID *int       `json:"location_id" query:"location_id"` // transfers from or to the location
+	StartTime  *time.Time `json:"start_time" query:"start_time"`
+	EndTime    *time.Time `json:"end_time" query:"end_time"`
+}
+
+type StockTransferCreateRequest struct {
+	BookID         int     `json:"book_id" validate:"required,min=1"`
+	FromLocationID int     `json:"from_location_id" validate:"required,min=1"`
+	ToLocationID   int     `json:"to_location_id" validate:"required,min=1,nefield=FromLocationID"`
+	Quantity       int     `json:"quantity" validate:"required,min=1"`
+	Reason         *string `json:"reason"`
+}
+
+type StockTransferResponse struct {
+	ID             int       `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UserID         int       `json:"user_id"`
+	BookID         int       `json:"book_id"`
+	FromLocationID int       `json:"from_location_id"`
+	ToLocationID   int       `json:"to_location_id"`
+	Quantity       int       `json:"quantity"`
+	Reason         *string   `json:"reason"`
+}
+
+type StockTransferListResponse struct {
+	Transfers []StockTransferResponse `json:"transfers"`
+	PageTotal int                     `json:"page_total"`
+}
+
+/* Stock movement */
+
+type StockMovementListRequest struct {
+	models.PageRequest
+	ExportRequest
+	OrderBy    string     `json:"order_by" query:"order_by" validate:"oneof=id created_at" default:"id"`
+	Sort       string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	BookID     *int       `json:"book_id" query:"book_id"`
+	LocationID *int       `json:"location_id" query:"location_id"`
+	UserID     *int       `json:"user_id" query:"user_id"`
+	Type       *string    `json:"type" query:"type" validate:"omitempty,oneof=initialize sale refund arrival transfer stocktake adjustment damage loss"`
+	StartTime  *time.Time `json:"start_time" query:"start_time"`
+	EndTime    *time.Time `json:"end_time" query:"end_time"`
+}
+
+// StockMovementCreateRequest a manual stock adjustment
+type StockMovementCreateRequest struct {
+	BookID     int    `json:"book_id" validate:"required,min=1"`
+	LocationID int    `json:"location_id" validate:"omitempty,min=1"` // the default location if not set
+	Type       string `json:"type" validate:"oneof=adjustment damage loss" default:"adjustment"`
+	Change     int    `json:"change" validate:"required"` // negative for damage and loss
+	Reason     string `json:"reason" validate:"required,min=1"`
+}
+
+type StockMovementResponse struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	BookID      int       `json:"book_id"`
+	LocationID  int       `json:"location_id"`
+	UserID      int       `json:"user_id"`
+	Type        string    `json:"type"`
+	Change      int       `json:"change"`       // 数量变化, 出库为负
+	Stock       int       `json:"stock"`        // 变动后该位置的库存
+	OperationID *int      `json:"operation_id"` // id of the sale, refund, purchase arrival, transfer or stocktake, null for manual adjustments
+	Reason      *string   `json:"reason"`
+}
+
+type StockMovementListResponse struct {
+	Movements []StockMovementResponse `json:"movements"`
+	PageTotal int                     `json:"page_total"`
+}
+
+/* Stocktake */
+
+type StocktakeListRequest struct {
+	models.PageRequest
+	OrderBy    string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at" default:"id"`
+	Sort       string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	LocationID *int    `json:"location_id" query:"location_id"`
+	Status     *string `json:"status" query:"status" validate:"omitempty,oneof=open posted cancelled"`
+}
+
+type StocktakeCreateRequest struct {
+	LocationID int     `json:"location_id" validate:"omitempty,min=1"` // the default location if not set
+	Note       *string `json:"note"`                                   // also the reason of the stock movements when posted
+}
+
+type StocktakeCountItemRequest struct {
+	BookID   int `json:"book_id" validate:"required,min=1"`
+	Quantity int `json:"quantity" validate:"min=0"`
+}
+
+// StocktakeCountRequest a batch of counted quantities, added to the quantities counted before
+type StocktakeCountRequest struct {
+	Items []StocktakeCountItemRequest `json:"items" validate:"required,min=1,unique=BookID,dive"`
+}
+
+// Quantities maps book id to counted quantity
+func (s *StocktakeCountRequest) Quantities() map[int]int {
+	quantities := make(map[int]int, len(s.Items))
+	for _, item := range s.Items {
+		quantities[item.BookID] = item.Quantity
+	}
+	return quantities
+}
+
+type StocktakeVarianceRequest struct {
+	OnlyVariance bool `json:"only_variance" query:"only_variance"` // omit books whose counted quantity equals the system stock
+}
+
+type StocktakeItemResponse struct {
+	BookID      int  `json:"book_id"`
+	Counted     int  `json:"counted"`
+	SystemStock *int `json:"system_stock"` // 过账时的系统库存, null if not posted
+}
+
+type StocktakeCountResponse struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int       `json:"user_id"`
+	BookID    int       `json:"book_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+type StocktakeResponse struct {
+	ID         int                      `json:"id"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+	UserID     int                      `json:"user_id"`
+	LocationID int                      `json:"location_id"`
+	Status     string                   `json:"status"`
+	Note       *string                  `json:"note"`
+	ClosedAt   *time.Time               `json:"closed_at"`
+	ClosedBy   *int                     `json:"closed_by"`
+	Items      []StocktakeItemResponse  `json:"items,omitempty"`
+	Counts     []StocktakeCountResponse `json:"counts,omitempty"`
+}
+
+type StocktakeListResponse struct {
+	Stocktakes []StocktakeResponse `json:"stocktakes"`
+	PageTotal  int                 `json:"page_total"`
+}
+
+type StocktakeVarianceItemResponse struct {
+	BookID      int           `json:"book_id"`
+	Book        *BookResponse `json:"book,omitempty"`
+	Counted     int           `json:"counted"`
+	SystemStock int           `json:"system_stock"` // 过账时的系统库存, 未过账时为当前系统库存
+	Variance    int           `json:"variance"`     // 盘盈为正, 盘亏为负
+}
+
+type StocktakeVarianceResponse struct {
+	StocktakeID int                             `json:"stocktake_id"`
+	Status      string                          `json:"status"`
+	Items       []StocktakeVarianceItemResponse `json:"items"`
+	Surplus     int                             `json:"surplus"`  // 盘盈总数量
+	Shortage    int                             `json:"shortage"` // 盘亏总数量, 正数
+}
+
+/* Supplier */
+
+type SupplierListRequest struct {
+	models.PageRequest
+	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at name lead_time" default:"id"`
+	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	Name    *string `json:"name" query:"name"`
+}
+
+type SupplierCreateRequest struct {
+	Name     string  `json:"name" validate:"required,min=1"`
+	Contact  *string `json:"contact"`
+	TaxID    *string `json:"tax_id" validate:"omitempty,max=64"`
+	LeadTime int     `json:"lead_time" validate:"min=0"` // 默认交货周期, 以天为单位
+	Notes    *string `json:"notes"`
+}
+
+type SupplierModifyRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=1"`
+	Contact  *string `json:"contact"`
+	TaxID    *string `json:"tax_id" validate:"omitempty,max=64"`
+	LeadTime *int    `json:"lead_time" validate:"omitempty,min=0"`
+	Notes    *string `json:"notes"`
+}
+
+type SupplierResponse struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Contact   *string   `json:"contact"`
+	TaxID     *string   `json:"tax_id"`
+	LeadTime  int       `json:"lead_time"`
+	Notes     *string   `json:"notes"`
+}
+
+type SupplierListResponse struct {
+	Suppliers []SupplierResponse `json:"suppliers"`
+	PageTotal int                `json:"page_total"`
+}
+
+type SupplierSummaryResponse struct {
+	SupplierID          int      `json:"supplier_id"`
+	PurchaseCount       int64    `json:"purchase_count"`
+	TotalSpend          float64  `json:"total_spend"`           // 已付款采购总额, 结单的采购单只计算已收货部分
+	OpenUnpaid          float64  `json:"open_unpaid"`           // 未付款且未退货、未取消的采购总额
+	AverageDeliveryDays *float64 `json:"average_delivery_days"` // 付款到收货的平均天数, null if no purchase arrived
+}
+
+/* Customer */
+
+type CustomerListRequest struct {
+	models.PageRequest
+	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at name" default:"id"`
+	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	Q       *string `json:"q" query:"q"` // search name, phone, email and member number
+}
+
+// CustomerLookupRequest finds a customer by phone, email or member number exactly, e.g. at checkout. All given fields must match
+type CustomerLookupRequest struct {
+	Phone        *string `json:"phone" query:"phone" validate:"required_without_all=Email MemberNumber"`
+	Email        *string `json:"email" query:"email" validate:"required_without_all=Phone MemberNumber"`
+	MemberNumber *string `json:"member_number" query:"member_number" validate:"required_without_all=Phone Email"`
+}
+
+type CustomerCreateRequest struct {
+	Name         string  `json:"name" validate:"required,min=1,max=128"`
+	Phone        *string `json:"phone" validate:"omitempty,min=1,max=32"`
+	Email        *string `json:"email" validate:"omitempty,email,max=128"`
+	MemberNumber *string `json:"member_number" validate:"omitempty,min=1,max=32"`
+	Notes        *string `json:"notes"`
+}
+
+type CustomerModifyRequest struct {
+	Name         *string `json:"name" validate:"omitempty,min=1,max=128"`
+	Phone        *string `json:"phone" validate:"omitempty,min=1,max=32"`
+	Email        *string `json:"email" validate:"omitempty,email,max=128"`
+	MemberNumber *string `json:"member_number" validate:"omitempty,min=1,max=32"`
+	Notes        *string `json:"notes"`
+}
+
+type CustomerResponse struct {
+	ID           int       `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	Phone        *string   `json:"phone"`
+	Email        *string   `json:"email"`
+	MemberNumber *string   `json:"member_number"`
+	Notes        *string   `json:"notes"`
+	TierID       *int      `json:"tier_id"` // null if no tier is reached
+	Points       int       `json:"points"`
+}
+
+type CustomerListResponse struct {
+	Customers []CustomerResponse `json:"customers"`
+	PageTotal int                `json:"page_total"`
+}
+
+type CustomerBookResponse struct {
+	BookID       int           `json:"book_id"`
+	Book         *BookResponse `json:"book,omitempty"`
+	Quantity     int           `json:"quantity"` // 购买数量, 已扣除退款
+	SpentFloat   float64       `json:"spent"`    // 已扣除退款
+	LastBoughtAt time.Time     `json:"last_bought_at"`
+}
+
+type CustomerHistoryResponse struct {
+	CustomerID int                    `json:"customer_id"`
+	SaleCount  int                    `json:"sale_count"`
+	VisitCount int                    `json:"visit_count"` // a receipt or a sale not in a receipt is one visit
+	Quantity   int                    `json:"quantity"`    // 购买总数量, 已扣除退款
+	SpentFloat float64                `json:"total_spent"` // 消费总额, 已扣除退款
+	FirstVisit *time.Time             `json:"first_visit"` // null if never bought
+	LastVisit  *time.Time             `json:"last_visit"`  // null if never bought
+	Books      []CustomerBookResponse `json:"books"`       // latest bought first
+}
+
+/* Loyalty */
+
+type MembershipTierCreateRequest struct {
+	Name          string  `json:"name" validate:"required,min=1,max=64"`
+	MinSpendFloat float64 `json:"min_spend" validate:"min=0"` // spend in the rolling 12 months to reach the tier
+	PointsPerYuan int     `json:"points_per_yuan" validate:"min=0"`
+}
+
+func (t *MembershipTierCreateRequest) MinSpend() int {
+	return int(t.MinSpendFloat * 100)
+}
+
+type MembershipTierModifyRequest struct {
+	Name          *string  `json:"name" validate:"omitempty,min=1,max=64"`
+	MinSpendFloat *float64 `json:"min_spend" validate:"omitempty,min=0"`
+	PointsPerYuan *int     `json:"points_per_yuan" validate:"omitempty,min=0"`
+}
+
+func (t *MembershipTierModifyRequest) MinSpend() *int {
+	if t.MinSpendFloat == nil {
+		return nil
+	}
+	minSpend := int(*t.MinSpendFloat * 100)
+	return &minSpend
+}
+
+type MembershipTierResponse struct {
+	ID            int       `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	MinSpendFloat float64   `json:"min_spend"`
+	PointsPerYuan int       `json:"points_per_yuan"`
+}
+
+type PointRecordListRequest struct {
+	models.PageRequest
+	OrderBy       string `json:"order_by" query:"order_by" validate:"oneof=id created_at change" default:"id"`
+	Sort          string `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	OperationType *int   `json:"operation_type" query:"operation_type"`
+}
+
+type PointRecordCreateRequest struct {
+	Change int     `json:"change" validate:"required"`
+	Reason *string `json:"reason"`
+}
+
+type PointRecordResponse struct {
+	ID            int       `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	CustomerID    int       `json:"customer_id"`
+	UserID        int       `json:"user_id"`
+	Change        int       `json:"change"`
+	Total         int       `json:"total"` // points of the customer after the change
+	OperationType int       `json:"operation_type"`
+	OperationID   int       `json:"operation_id"`
+	Reason        *string   `json:"reason"`
+	Info          string    `json:"info"`
+}
+
+type PointRecordListResponse struct {
+	Records   []PointRecordResponse `json:"records"`
+	PageTotal int                   `json:"page_total"`
+}
+
+/* Promotion */
+
+type PromotionListRequest struct {
+	models.PageRequest
+	OrderBy string  `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at start_at end_at" default:"id"`
+	Sort    string  `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	Name    *string `json:"name" query:"name"`
+	Type    *int    `json:"type" query:"type"`
+	Active  *bool   `json:"active" query:"active"` // true: enabled and in the date range now, false: otherwise, nil: all
+}
+
+type PromotionCreateRequest struct {
+	Name         string     `json:"name" validate:"required,min=1,max=128"`
+	Type         int        `json:"type" validate:"required,oneof=1 2 3"` // 1: percentage, 2: fixed amount off each book, 3: buy N get M
+	ValueFloat   float64    `json:"value" validate:"min=0"`               // percent off for type 1, yuan off for type 2
+	BuyQuantity  int        `json:"buy_quantity" validate:"min=0"`        // N of type 3
+	FreeQuantity int        `json:"free_quantity" validate:"min=0"`       // M of type 3
+	StartAt      *time.Time `json:"start_at"`                             // started if not set
+	EndAt        *time.Time `json:"end_at"`                               // never ends if not set
+	Enabled      *bool      `json:"enabled"`                              // true if not set
+	CouponOnly   bool       `json:"coupon_only"`                          // only applied with its coupons
+	// scope of the promotion, all books if none is set
+	BookIDs     []int    `json:"book_ids" validate:"omitempty,unique,dive,min=1"`
+	CategoryIDs []int    `json:"category_ids" validate:"omitempty,unique,dive,min=1"` // including subcategories
+	Presses     []string `json:"presses" validate:"omitempty,unique,dive,min=1,max=255"`
+}
+
+func (p *PromotionCreateRequest) Value() int {
+	return promotionValue(p.Type, p.ValueFloat)
+}
+
+// promotionValue converts the value in the request to the value of the model, yuan to cents for fixed discounts
+func promotionValue(promotionType int, value float64) int {
+	if promotionType == models.PromotionTypeFixed {
+		return int(value * 100)
+	}
+	return int(value)
+}
+
+type PromotionModifyRequest struct {
+	Name         *string    `json:"name" validate:"omitempty,min=1,max=128"`
+	Type         *int       `json:"type" validate:"omitempty,oneof=1 2 3"`
+	ValueFloat   *float64   `json:"value" validate:"omitempty,min=0"`
+	BuyQuantity  *int       `json:"buy_quantity" validate:"omitempty,min=0"`
+	FreeQuantity *int       `json:"free_quantity" validate:"omitempty,min=0"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	Enabled      *bool      `json:"enabled"`
+	CouponOnly   *bool      `json:"coupon_only"`
+	BookIDs      []int      `json:"book_ids" validate:"omitempty,unique,dive,min=1"`        // replace books if set, [] to clear
+	CategoryIDs  []int      `json:"category_ids" validate:"omitempty,unique,dive,min=1"`    // replace categories if set, [] to clear
+	Presses      []string   `json:"presses" validate:"omitempty,unique,dive,min=1,max=255"` // replace presses if set, [] to clear
+}
+
+type PromotionResponse struct {
+	ID           int        `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Name         string     `json:"name"`
+	Type         int        `json:"type"`
+	ValueFloat   float64    `json:"value"`
+	BuyQuantity  int        `json:"buy_quantity"`
+	FreeQuantity int        `json:"free_quantity"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	Enabled      bool       `json:"enabled"`
+	CouponOnly   bool       `json:"coupon_only"`
+	BookIDs      []int      `json:"book_ids"`
+	CategoryIDs  []int      `json:"category_ids"`
+	Presses      []string   `json:"presses" copier:"PressNames"`
+}
+
+type PromotionListResponse struct {
+	Promotions []PromotionResponse `json:"promotions"`
+	PageTotal  int                 `json:"page_total"`
+}
+
+type PromotionSummaryRequest struct {
+	StartTime *time.Time `json:"start_time" query:"start_time"`
+	EndTime   *time.Time `json:"end_time" query:"end_time"`
+}
+
+type PromotionSummaryResponse struct {
+	PromotionID      int                `json:"promotion_id"`
+	Promotion        *PromotionResponse `json:"promotion"` // deleted promotions included
+	SaleCount        int                `json:"sale_count"`
+	Quantity         int                `json:"quantity"`
+	RefundedQuantity int                `json:"refunded_quantity"`
+	DiscountFloat    float64            `json:"discount"` // of the whole sales including refunded books
+	RevenueFloat     float64            `json:"revenue"`  // refunds excluded
+}
+
+type CouponListRequest struct {
+	models.PageRequest
+	OrderBy     string `json:"order_by" query:"order_by" validate:"oneof=id created_at used_count" default:"id"`
+	Sort        string `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	PromotionID *int   `json:"promotion_id" query:"promotion_id"`
+}
+
+type CouponLookupRequest struct {
+	Code string `json:"code" query:"code" validate:"required"`
+}
+
+type CouponCreateRequest struct {
+	PromotionID int    `json:"promotion_id" validate:"required,min=1"`
+	Code        string `json:"code" validate:"required,min=1,max=64"`  // case-insensitive
+	UsageLimit  *int   `json:"usage_limit" validate:"omitempty,min=0"` // unlimited if not set
+}
+
+type CouponModifyRequest struct {
+	UsageLimit *int `json:"usage_limit" validate:"omitempty,min=0"` // set to the used count to disable the coupon
+}
+
+type CouponResponse struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Code        string    `json:"code"`
+	PromotionID int       `json:"promotion_id"`
+	UsageLimit  *int      `json:"usage_limit"`
+	UsedCount   int       `json:"used_count"`
+}
+
+type CouponListResponse struct {
+	Coupons   []CouponResponse `json:"coupons"`
+	PageTotal int              `json:"page_total"`
+}
+
+/* Price Override */
+
+type PriceOverrideListRequest struct {
+	models.PageRequest
+	OrderBy    string     `json:"order_by" query:"order_by" validate:"oneof=id created_at" default:"id"`
+	Sort       string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	UserID     *int       `json:"user_id" query:"user_id"`
+	ApproverID *int       `json:"approver_id" query:"approver_id"`
+	BookID     *int       `json:"book_id" query:"book_id"`
+	Approved   *bool      `json:"approved" query:"approved"` // true: approved by an admin, false: within the limit of the user, nil: all
+	StartTime  *time.Time `json:"start_time" query:"start_time"`
+	EndTime    *time.Time `json:"end_time" query:"end_time"`
+}
+
+type PriceOverrideResponse struct {
+	ID              int       `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	SaleID          int       `json:"sale_id"`
+	BookID          int       `json:"book_id"`
+	UserID          int       `json:"user_id"`
+	ApproverID      *int      `json:"approver_id"`
+	ListPriceFloat  *float64  `json:"list_price"`
+	CostFloat       *float64  `json:"cost"` // price of the latest received purchase, null if never purchased
+	PriceFloat      float64   `json:"price"`
+	DiscountPercent float64   `json:"discount_percent"`
+	Quantity        int       `json:"quantity"`
+	Reason          string    `json:"reason"`
+}
+
+type PriceOverrideListResponse struct {
+	Overrides []PriceOverrideResponse `json:"overrides"`
+	PageTotal int                     `json:"page_total"`
+}
+
 /* Balance */
 
 type BalanceListRequest struct {
 	models.PageRequest
+	ExportRequest
 	OrderBy   string     `json:"order_by" query:"order_by" validate:"oneof=id created_at user_id change" default:"id"`
 	Sort      string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
 	UserID    *int       `json:"user_id" query:"user_id"`
@@ -229,6 +1110,7 @@ type BalanceResponse struct {
 	Total         float64   `json:"balance" copier:"TotalFloat"`
 	OperationType int       `json:"operation_type"`
 	OperationID   int       `json:"operation_id"`
+	RelatedID     *int      `json:"related_id"`
 	Info          string    `json:"info"`
 }
 
@@ -241,18 +1123,50 @@ type BalanceListResponse struct {
 
 type SaleListRequest struct {
 	models.PageRequest
-	OrderBy   string     `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at book_id user_id" default:"id"`
-	Sort      string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
-	BookID    *int       `json:"book_id" query:"book_id"`
-	UserID    *int       `json:"user_id" query:"user_id"`
-	StartTime *time.Time `json:"start_time" query:"start_time"`
-	EndTime   *time.Time `json:"end_time" query:"end_time"`
+	ExportRequest
+	OrderBy    string     `json:"order_by" query:"order_by" validate:"oneof=id created_at updated_at book_id user_id" default:"id"`
+	Sort       string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	BookID     *int       `json:"book_id" query:"book_id"`
+	UserID     *int       `json:"user_id" query:"user_id"`
+	ReceiptID  *int       `json:"receipt_id" query:"receipt_id"`
+	CustomerID *int       `json:"customer_id" query:"customer_id"`
+	StartTime  *time.Time `json:"start_time" query:"start_time"`
+	EndTime    *time.Time `json:"end_time" query:"end_time"`
+	// sales the promotion is applied to
+	PromotionID *int `json:"promotion_id" query:"promotion_id"`
 }
 
 type SaleCreateRequest struct {
 	BookID     int     `json:"book_id" validate:"required,min=1"`
 	Quantity   int     `json:"quantity" validate:"required,min=1"`
 	PriceFloat float64 `json:"price"`
+	LocationID int     `json:"location_id" validate:"omitempty,min=1"` // location sold from, the default location if not set
+	CustomerID *int    `json:"customer_id" validate:"omitempty,min=1"` // ignored in a receipt, use customer_id of the receipt instead
+	// points of the customer to redeem as payment, ignored in a receipt
+	PointsRedeemed int `json:"redeem_points" validate:"omitempty,min=1"`
+	// promotions apply only if price is not set, the coupon is ignored in a receipt
+	CouponCode *string `json:"coupon_code" validate:"omitempty,min=1,max=64"`
+	// required if price is set and differs from the book price
+	PriceReason *string `json:"price_reason" validate:"omitempty,max=256"`
+	// credentials of an admin to approve a discount above the limit of the user, ignored in a receipt
+	Approver *ApproverRequest `json:"approver"`
+}
+
+type ApproverRequest struct {
+	Username string `json:"username" validate:"required,min=1"`
+	Password string `json:"password" validate:"required,min=8,max=30"`
+}
+
+// NewPriceOverride returns the audit record to create with the sale, null if neither reason nor approver is given
+func (s *SaleCreateRequest) NewPriceOverride(approverID *int) *models.PriceOverride {
+	if s.PriceReason == nil && approverID == nil {
+		return nil
+	}
+	override := models.PriceOverride{ApproverID: approverID}
+	if s.PriceReason != nil {
+		override.Reason = *s.PriceReason
+	}
+	return &override
 }
 
 func (s *SaleCreateRequest) Price() int {
@@ -260,17 +1174,99 @@ func (s *SaleCreateRequest) Price() int {
 }
 
 type SaleResponse struct {
-	ID         int           `json:"id"`
-	CreatedAt  time.Time     `json:"created_at"`
-	UpdatedAt  time.Time     `json:"updated_at"`
-	BookID     int           `json:"book_id"`
-	UserID     int           `json:"user_id"`
-	Quantity   int           `json:"quantity"`
-	PriceFloat float64       `json:"price"`
-	Book       *BookResponse `json:"book,omitempty"`
+	ID               int                     `json:"id"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+	BookID           int                     `json:"book_id"`
+	UserID           int                     `json:"user_id"`
+	ReceiptID        *int                    `json:"receipt_id"`
+	LocationID       int                     `json:"location_id"`
+	CustomerID       *int                    `json:"customer_id"`
+	Quantity         int                     `json:"quantity"`
+	RefundedQuantity int                     `json:"refunded_quantity"`
+	PriceFloat       float64                 `json:"price"`    // unit price after discount
+	DiscountFloat    float64                 `json:"discount"` // discount of the whole sale
+	Promotions       []SalePromotionResponse `json:"promotions"`
+	PriceOverride    *PriceOverrideResponse  `json:"price_override"`  // null if the price is not given manually
+	PointsRedeemed   int                     `json:"points_redeemed"` // 0 for sales in a receipt, see the receipt
+	PointsPaidFloat  float64                 `json:"points_paid"`     // amount paid by points
+	PointsEarned     int                     `json:"points_earned"`
+	Book             *BookResponse           `json:"book,omitempty"`
+}
+
+type SalePromotionResponse struct {
+	PromotionID   int     `json:"promotion_id"`
+	CouponID      *int    `json:"coupon_id"` // null if applied automatically
+	DiscountFloat float64 `json:"discount"`
 }
 
 type SaleListResponse struct {
 	Sales     []SaleResponse `json:"sales"`
 	PageTotal int            `json:"page_total"`
 }
+
+type SaleRefundRequest struct {
+	Quantity   int     `json:"quantity" validate:"omitempty,min=1"` // refund all remaining quantity if not set
+	Reason     *string `json:"reason"`
+	LocationID int     `json:"location_id" validate:"omitempty,min=1"` // location the books are returned to, the location of the sale if not set
+}
+
+type SaleRefundResponse struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	SaleID      int       `json:"sale_id"`
+	BookID      int       `json:"book_id"`
+	UserID      int       `json:"user_id"`
+	LocationID  int       `json:"location_id"`
+	Quantity    int       `json:"quantity"`
+	PriceFloat  float64   `json:"price"`
+	AmountFloat float64   `json:"amount"`
+	Reason      *string   `json:"reason"`
+	// the amount paid by points is returned as points first, the rest is returned in cash
+	CashFloat       float64 `json:"cash"`
+	PointsPaidFloat float64 `json:"points_paid"`
+	PointsReturned  int     `json:"points_returned"`
+	PointsReversed  int     `json:"points_reversed"` // points earned by the refunded amount
+}
+
+/* Receipt */
+
+type ReceiptListRequest struct {
+	models.PageRequest
+	OrderBy    string     `json:"order_by" query:"order_by" validate:"oneof=id created_at user_id total" default:"id"`
+	Sort       string     `json:"sort" query:"sort" validate:"oneof=asc desc" default:"asc"`
+	UserID     *int       `json:"user_id" query:"user_id"`
+	CustomerID *int       `json:"customer_id" query:"customer_id"`
+	StartTime  *time.Time `json:"start_time" query:"start_time"`
+	EndTime    *time.Time `json:"end_time" query:"end_time"`
+}
+
+type ReceiptCreateRequest struct {
+	Sales      []SaleCreateRequest `json:"sales" validate:"required,min=1,unique=BookID,dive"`
+	LocationID int                 `json:"location_id" validate:"omitempty,min=1"` // location of the sales without location_id, the default location if not set
+	CustomerID *int                `json:"customer_id" validate:"omitempty,min=1"` // customer of all the sales
+	// points of the customer to redeem as payment of the whole receipt
+	PointsRedeemed int `json:"redeem_points" validate:"omitempty,min=1"`
+	// coupon to apply to each sale it applies to, counted as one use
+	CouponCode *string `json:"coupon_code" validate:"omitempty,min=1,max=64"`
+	// credentials of an admin to approve the discounts of the sales above the limit of the user
+	Approver *ApproverRequest `json:"approver"`
+}
+
+type ReceiptResponse struct {
+	ID         int            `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UserID     int            `json:"user_id"`
+	CustomerID *int           `json:"customer_id"`
+	TotalFloat float64        `json:"total"`
+	Sales      []SaleResponse `json:"sales"`
+	// 积分抵扣和累积
+	PointsRedeemed  int     `json:"points_redeemed"`
+	PointsPaidFloat float64 `json:"points_paid"` // amount paid by points
+	PointsEarned    int     `json:"points_earned"`
+}
+
+type ReceiptListResponse struct {
+	Receipts  []ReceiptResponse `json:"receipts"`
+	PageTotal int               `json:"page_total"`
+}
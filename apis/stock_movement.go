@@ -0,0 +1,128 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListStockMovements godoc
+// @Summary List stock movements
+// @Description Every change of stock is recorded, including sales, refunds, arrivals, transfers and manual adjustments.
+// @Description Set export to csv, xlsx or ndjson to download all the filtered records as a file
+// @Tags Stock
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param json query StockMovementListRequest true "query"
+// @Success 200 {object} StockMovementListResponse
+// @Router /stock_movements [get]
+func ListStockMovements(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query StockMovementListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.BookID != nil {
+		querySet = querySet.Where("book_id = ?", *query.BookID)
+	}
+	if query.LocationID != nil {
+		querySet = querySet.Where("location_id = ?", *query.LocationID)
+	}
+	if query.UserID != nil {
+		querySet = querySet.Where("user_id = ?", *query.UserID)
+	}
+	if query.Type != nil {
+		querySet = querySet.Where("type = ?", *query.Type)
+	}
+	if query.StartTime != nil {
+		querySet = querySet.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		querySet = querySet.Where("created_at <= ?", *query.EndTime)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	if query.Export != "" {
+		return Export(c, "stock_movements", query.Export, querySet, func(movements []StockMovement) (response []StockMovementResponse, err error) {
+			err = copier.Copy(&response, &movements)
+			return
+		})
+	}
+
+	var movements []StockMovement
+	if err := querySet.Find(&movements).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&StockMovement{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response StockMovementListResponse
+	if err := copier.Copy(&response.Movements, &movements); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// CreateAStockMovement godoc
+// @Summary Adjust stock manually
+// @Description Correct the stock, or write off damaged and lost books with a negative change. The reason is required.
+// @Tags Stock
+// @Accept json
+// @Produce json
+// @Param json body StockMovementCreateRequest true "body"
+// @Success 201 {object} StockMovementResponse
+// @Router /stock_movements [post]
+func CreateAStockMovement(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body StockMovementCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+	if body.Type != StockMovementTypeAdjustment && body.Change > 0 {
+		return ErrStockMovementChange
+	}
+
+	movement := StockMovement{
+		BookID: body.BookID,
+		UserID: user.ID,
+		Type:   body.Type,
+		Change: body.Change,
+		Reason: &body.Reason,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) (err error) {
+		if err = CheckBooksExist(tx, []int{body.BookID}); err != nil {
+			return err
+		}
+		if movement.LocationID, err = ResolveLocation(tx, body.LocationID); err != nil {
+			return err
+		}
+		return ChangeStock(tx, &movement)
+	})
+	if err != nil {
+		return err
+	}
+
+	var response StockMovementResponse
+	if err = copier.Copy(&response, &movement); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
@@ -0,0 +1,311 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListStocktakes godoc
+// @Summary List stocktakes
+// @Tags Stocktake
+// @Produce json
+// @Param json query StocktakeListRequest true "query"
+// @Success 200 {object} StocktakeListResponse
+// @Router /stocktakes [get]
+func ListStocktakes(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query StocktakeListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.LocationID != nil {
+		querySet = querySet.Where("location_id = ?", *query.LocationID)
+	}
+	if query.Status != nil {
+		querySet = querySet.Where("status = ?", *query.Status)
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var stocktakes []Stocktake
+	if err := querySet.Find(&stocktakes).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Stocktake{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response StocktakeListResponse
+	if err := copier.Copy(&response.Stocktakes, &stocktakes); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetAStocktake godoc
+// @Summary Get a stocktake by id
+// @Description Get a stocktake with its items and all the submitted counts
+// @Tags Stocktake
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} StocktakeResponse
+// @Router /stocktakes/{id} [get]
+func GetAStocktake(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	stocktakeID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var stocktake Stocktake
+	if err := DB.Scopes(PreloadStocktakeDetails).First(&stocktake, stocktakeID).Error; err != nil {
+		return err
+	}
+
+	var response StocktakeResponse
+	if err := copier.Copy(&response, &stocktake); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// CreateAStocktake godoc
+// @Summary Open a stocktake
+// @Description Open a stocktake of a location, then submit the counted quantities and post it to adjust the stock
+// @Tags Stocktake
+// @Accept json
+// @Produce json
+// @Param json body StocktakeCreateRequest true "body"
+// @Success 201 {object} StocktakeResponse
+// @Router /stocktakes [post]
+func CreateAStocktake(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body StocktakeCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	stocktake := Stocktake{UserID: user.ID, Note: body.Note}
+	err := DB.Transaction(func(tx *gorm.DB) (err error) {
+		if stocktake.LocationID, err = ResolveLocation(tx, body.LocationID); err != nil {
+			return err
+		}
+		return tx.Create(&stocktake).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response StocktakeResponse
+	if err = copier.Copy(&response, &stocktake); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// AddStocktakeCounts godoc
+// @Summary Submit counted quantities
+// @Description Submit a batch of counted quantities of an open stocktake, which are added to the quantities counted before,
+// @Description so that different staff may count different shelves of the same book
+// @Tags Stocktake
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body StocktakeCountRequest true "body"
+// @Success 201 {object} StocktakeResponse
+// @Router /stocktakes/{id}/counts [post]
+func AddStocktakeCounts(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	stocktakeID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body StocktakeCountRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var stocktake Stocktake
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&stocktake, stocktakeID).Error; err != nil {
+			return err
+		}
+		if err = stocktake.AddCounts(tx, body.Quantities(), user.ID); err != nil {
+			return err
+		}
+		return tx.Scopes(PreloadStocktakeDetails).First(&stocktake, stocktakeID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response StocktakeResponse
+	if err = copier.Copy(&response, &stocktake); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// GetStocktakeVariances godoc
+// @Summary Get the variance report of a stocktake
+// @Description Compare the counted quantities with the system stock, which is the current stock before posted, ordered by book id
+// @Tags Stocktake
+// @Produce json
+// @Param id path int true "id"
+// @Param json query StocktakeVarianceRequest true "query"
+// @Success 200 {object} StocktakeVarianceResponse
+// @Router /stocktakes/{id}/variances [get]
+func GetStocktakeVariances(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query StocktakeVarianceRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	stocktakeID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var stocktake Stocktake
+	err = DB.Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("book_id") }).
+		Preload("Items.Book", WithDeleted).
+		First(&stocktake, stocktakeID).Error
+	if err != nil {
+		return err
+	}
+
+	bookIDs := make([]int, 0, len(stocktake.Items))
+	for _, item := range stocktake.Items {
+		bookIDs = append(bookIDs, item.BookID)
+	}
+	var stocks []BookStock
+	if err = DB.Where("location_id = ? AND book_id IN ?", stocktake.LocationID, bookIDs).Find(&stocks).Error; err != nil {
+		return err
+	}
+	systemStocks := make(map[int]int, len(stocks)) // book id -> current stock at the location
+	for _, stock := range stocks {
+		systemStocks[stock.BookID] = stock.Stock
+	}
+
+	response := StocktakeVarianceResponse{
+		StocktakeID: stocktake.ID,
+		Status:      stocktake.Status,
+		Items:       make([]StocktakeVarianceItemResponse, 0, len(stocktake.Items)),
+	}
+	for _, item := range stocktake.Items {
+		variance := item.Variance(systemStocks[item.BookID])
+		if query.OnlyVariance && variance == 0 {
+			continue
+		}
+		var row StocktakeVarianceItemResponse
+		if err = copier.Copy(&row, &item); err != nil {
+			return err
+		}
+		row.SystemStock = item.Counted - variance
+		row.Variance = variance
+		if variance > 0 {
+			response.Surplus += variance
+		} else {
+			response.Shortage -= variance
+		}
+		response.Items = append(response.Items, row)
+	}
+
+	return c.JSON(&response)
+}
+
+// PostAStocktake godoc
+// @Summary Post a stocktake, admin only
+// @Description Adjust the stock of the counted books at the location to the counted quantities in one transaction,
+// @Description with a stock movement for each book with variance. Books not counted are unchanged.
+// @Description Sales of the counted books wait until the posting is done.
+// @Tags Stocktake
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} StocktakeResponse
+// @Router /stocktakes/{id}/_post [post]
+func PostAStocktake(c *fiber.Ctx) error {
+	return closeAStocktake(c, (*Stocktake).Post)
+}
+
+// CancelAStocktake godoc
+// @Summary Cancel a stocktake, admin only
+// @Description Discard an open stocktake, the stock is unchanged
+// @Tags Stocktake
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} StocktakeResponse
+// @Router /stocktakes/{id}/_cancel [post]
+func CancelAStocktake(c *fiber.Ctx) error {
+	return closeAStocktake(c, (*Stocktake).Cancel)
+}
+
+func closeAStocktake(c *fiber.Ctx, action func(*Stocktake, *gorm.DB, int) error) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	stocktakeID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var stocktake Stocktake
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&stocktake, stocktakeID).Error; err != nil {
+			return err
+		}
+		if err = action(&stocktake, tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Scopes(PreloadStocktakeDetails).First(&stocktake, stocktakeID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response StocktakeResponse
+	if err = copier.Copy(&response, &stocktake); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
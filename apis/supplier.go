@@ -0,0 +1,279 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ListSuppliers godoc
+// @Summary List suppliers
+// @Tags Supplier
+// @Produce json
+// @Param json query SupplierListRequest true "query"
+// @Success 200 {object} SupplierListResponse
+// @Router /suppliers [get]
+func ListSuppliers(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query SupplierListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.Name != nil {
+		querySet = querySet.Where("name LIKE ?", "%"+*query.Name+"%")
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var suppliers []Supplier
+	if err := querySet.Find(&suppliers).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Supplier{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response SupplierListResponse
+	if err := copier.Copy(&response.Suppliers, &suppliers); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// GetASupplier godoc
+// @Summary Get a supplier by id
+// @Tags Supplier
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} SupplierResponse
+// @Router /suppliers/{id} [get]
+func GetASupplier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	supplierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var supplier Supplier
+	if err := DB.First(&supplier, supplierID).Error; err != nil {
+		return err
+	}
+
+	var supplierResponse SupplierResponse
+	if err := copier.Copy(&supplierResponse, &supplier); err != nil {
+		return err
+	}
+
+	return c.JSON(&supplierResponse)
+}
+
+// CreateASupplier godoc
+// @Summary Create a supplier
+// @Tags Supplier
+// @Accept json
+// @Produce json
+// @Param json body SupplierCreateRequest true "body"
+// @Success 201 {object} SupplierResponse
+// @Router /suppliers [post]
+func CreateASupplier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body SupplierCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var supplier Supplier
+	if err := copier.Copy(&supplier, &body); err != nil {
+		return err
+	}
+	if err := DB.Create(&supplier).Error; err != nil {
+		return err
+	}
+
+	var supplierResponse SupplierResponse
+	if err := copier.Copy(&supplierResponse, &supplier); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&supplierResponse)
+}
+
+// ModifyASupplier godoc
+// @Summary Modify a supplier
+// @Tags Supplier
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body SupplierModifyRequest true "body"
+// @Success 200 {object} SupplierResponse
+// @Router /suppliers/{id} [patch]
+func ModifyASupplier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	supplierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body SupplierModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var supplier Supplier
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&supplier, supplierID).Error; err != nil {
+			return err
+		}
+
+		if err = copier.CopyWithOption(&supplier, &body, copier.Option{IgnoreEmpty: true}); err != nil {
+			return err
+		}
+
+		return tx.Save(&supplier).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var supplierResponse SupplierResponse
+	if err = copier.Copy(&supplierResponse, &supplier); err != nil {
+		return err
+	}
+
+	return c.JSON(&supplierResponse)
+}
+
+// DeleteASupplier godoc
+// @Summary Delete a supplier, admin only
+// @Tags Supplier
+// @Param id path int true "id"
+// @Success 204
+// @Router /suppliers/{id} [delete]
+func DeleteASupplier(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	supplierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	result := DB.Delete(&Supplier{ID: supplierID})
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return NotFound()
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetASupplierSummary godoc
+// @Summary Get the purchase summary of a supplier
+// @Description Total spend, open unpaid amount and average delivery time from paid to arrived
+// @Tags Supplier
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} SupplierSummaryResponse
+// @Router /suppliers/{id}/summary [get]
+func GetASupplierSummary(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	supplierID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var supplier Supplier
+	if err := DB.First(&supplier, supplierID).Error; err != nil {
+		return err
+	}
+
+	response := SupplierSummaryResponse{SupplierID: supplier.ID}
+	if err := DB.Model(&Purchase{}).Where("supplier_id = ?", supplier.ID).Count(&response.PurchaseCount).Error; err != nil {
+		return err
+	}
+
+	// sum of price * quantity of purchase items, 以分为单位
+	sumOfItems := func(quantity string, statuses ...PurchaseStatus) (total int, err error) {
+		err = DB.Model(&PurchaseItem{}).
+			Select("COALESCE(SUM(purchase_item.price * "+quantity+"), 0)").
+			Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+			Where("purchase.supplier_id = ? AND purchase.status IN ?", supplier.ID, statuses).
+			Scan(&total).Error
+		return
+	}
+
+	totalSpend, err := sumOfItems("purchase_item.quantity", PurchaseStatusPaid, PurchaseStatusArrived)
+	if err != nil {
+		return err
+	}
+	// missing units of closed purchases are credited by the supplier
+	closedSpend, err := sumOfItems("purchase_item.received_quantity", PurchaseStatusClosed)
+	if err != nil {
+		return err
+	}
+	totalSpend += closedSpend
+	openUnpaid, err := sumOfItems("purchase_item.quantity", PurchaseStatusDraft)
+	if err != nil {
+		return err
+	}
+	response.TotalSpend = float64(totalSpend) / 100
+	response.OpenUnpaid = float64(openUnpaid) / 100
+
+	// average delivery time, calculated in go to be database independent
+	var deliveries []struct {
+		PaidAt    time.Time
+		ArrivedAt time.Time
+	}
+	err = DB.Model(&Purchase{}).
+		Select("paid_at", "arrived_at").
+		Where("supplier_id = ? AND paid_at IS NOT NULL AND arrived_at IS NOT NULL", supplier.ID).
+		Scan(&deliveries).Error
+	if err != nil {
+		return err
+	}
+	if len(deliveries) > 0 {
+		var total time.Duration
+		for _, delivery := range deliveries {
+			total += delivery.ArrivedAt.Sub(delivery.PaidAt)
+		}
+		days := (total / time.Duration(len(deliveries))).Hours() / 24
+		response.AverageDeliveryDays = &days
+	}
+
+	return c.JSON(&response)
+}
@@ -0,0 +1,182 @@
+package apis
+
+import (
+	. "book_management_system_backend/models"
+	. "book_management_system_backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+// ListTags godoc
+// @Summary List tags
+// @Tags Tag
+// @Produce json
+// @Param json query TagListRequest true "query"
+// @Success 200 {object} TagListResponse
+// @Router /tags [get]
+func ListTags(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var query TagListRequest
+	if err := ValidateQuery(c, &query); err != nil {
+		return err
+	}
+
+	querySet := query.QuerySet(DB).Order(ToOrderString(query.OrderBy, query.Sort))
+	if query.Name != nil {
+		querySet = querySet.Where("name LIKE ?", "%"+*query.Name+"%")
+	}
+
+	querySet = querySet.Session(&gorm.Session{}) // mark as safe to reuse
+
+	var tags []Tag
+	if err := querySet.Find(&tags).Error; err != nil {
+		return err
+	}
+
+	var pageTotal int64
+	if err := querySet.Model(&Tag{}).Offset(-1).Limit(-1).Count(&pageTotal).Error; err != nil {
+		return err
+	}
+
+	var response TagListResponse
+	if err := copier.Copy(&response.Tags, &tags); err != nil {
+		return err
+	}
+	response.PageTotal = int(pageTotal)
+
+	return c.JSON(response)
+}
+
+// CreateATag godoc
+// @Summary Create a tag
+// @Description Tags are also created when a book is created or modified with tags
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param json body TagCreateRequest true "body"
+// @Success 201 {object} TagResponse
+// @Router /tags [post]
+func CreateATag(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	var body TagCreateRequest
+	if err := ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := DB.Model(&Tag{}).Where("name = ?", body.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return Conflict("标签已存在")
+	}
+
+	tag := Tag{Name: body.Name}
+	if err := DB.Create(&tag).Error; err != nil {
+		return err
+	}
+
+	var response TagResponse
+	if err := copier.Copy(&response, &tag); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&response)
+}
+
+// ModifyATag godoc
+// @Summary Rename a tag
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param json body TagModifyRequest true "body"
+// @Success 200 {object} TagResponse
+// @Router /tags/{id} [patch]
+func ModifyATag(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+
+	tagID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	var body TagModifyRequest
+	if err = ValidateBody(c, &body); err != nil {
+		return err
+	}
+
+	var tag Tag
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Clauses(LockClause).First(&tag, tagID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err = tx.Model(&Tag{}).Where("name = ? AND id <> ?", body.Name, tag.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return Conflict("标签已存在")
+		}
+
+		return tx.Model(&tag).Update("name", body.Name).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var response TagResponse
+	if err = copier.Copy(&response, &tag); err != nil {
+		return err
+	}
+
+	return c.JSON(&response)
+}
+
+// DeleteATag godoc
+// @Summary Delete a tag, admin only
+// @Description The books are unlinked
+// @Tags Tag
+// @Param id path int true "id"
+// @Success 204
+// @Router /tags/{id} [delete]
+func DeleteATag(c *fiber.Ctx) error {
+	var user User
+	if err := GetCurrentUser(c, &user); err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return Forbidden()
+	}
+
+	tagID, err := c.ParamsInt("id")
+	if err != nil {
+		return BadRequest()
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var tag Tag
+		if err = tx.Clauses(LockClause).First(&tag, tagID).Error; err != nil {
+			return err
+		}
+		return tag.Delete(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -17,7 +17,13 @@ import (
 
 func InitFiberApp() *fiber.App {
 	config.InitConfig()
+	models.InitStorage()
 	models.InitDB()
+	if config.Config.Mode != config.ModeTest && config.Config.Mode != config.ModeBench {
+		models.StartPriceScheduler(time.Minute)
+		models.StartReorderScheduler(time.Hour)
+		models.StartTierScheduler(24 * time.Hour)
+	}
 
 	app := fiber.New(fiber.Config{
 		AppName:               config.Config.AppName,
@@ -25,6 +31,7 @@ func InitFiberApp() *fiber.App {
 		JSONEncoder:           json.Marshal,
 		JSONDecoder:           json.Unmarshal,
 		DisableStartupMessage: true,
+		BodyLimit:             config.Config.BodyLimit,
 	})
 
 	registerMiddlewares(app)
@@ -11,6 +11,29 @@ var Config struct {
 	PostgresDSN url.URL `env:"POSTGRES_DSN"`
 	AppName     string  `env:"APP_NAME" envDefault:"book_management_system"`
 	Hostname    string  `env:"HOSTNAME" envDefault:"localhost"`
+	BodyLimit   int     `env:"BODY_LIMIT" envDefault:"33554432"` // 请求体大小上限, 默认 32MB, 用于批量导入
+
+	// 图片存储, local 存储在 ImageDir 目录, s3 存储在 S3 兼容的对象存储
+	ImageStorage       string `env:"IMAGE_STORAGE" envDefault:"local"`
+	ImageDir           string `env:"IMAGE_DIR" envDefault:"images"`
+	ImageMaxSize       int    `env:"IMAGE_MAX_SIZE" envDefault:"5242880"`   // 上传图片大小上限, 默认 5MB
+	ImageThumbnailSize int    `env:"IMAGE_THUMBNAIL_SIZE" envDefault:"256"` // 缩略图的最大宽高
+	S3Endpoint         string `env:"S3_ENDPOINT"`                           // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	S3Region           string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket           string `env:"S3_BUCKET"`
+	S3AccessKey        string `env:"S3_ACCESS_KEY"`
+	S3SecretKey        string `env:"S3_SECRET_KEY"`
+
+	// 补货建议, see models.EvaluateReorders
+	ReorderSalesDays int `env:"REORDER_SALES_DAYS" envDefault:"30"` // 按最近多少天的销量计算销售速度, 也是默认补货数量覆盖的天数
+	ReorderLeadTime  int `env:"REORDER_LEAD_TIME" envDefault:"7"`   // 供应商未知或未设置交货周期时的默认交货周期, 以天为单位
+
+	LoyaltyPointValue int `env:"LOYALTY_POINT_VALUE" envDefault:"1"` // 1 积分抵扣的金额, 以分为单位, 默认 100 积分抵扣 1 元
+
+	// 销售时手动改价的最大折扣百分比, 超过时或低于成本价时需要另一位管理员授权, see models.PriceOverride
+	PriceOverrideStaffMaxDiscount    int `env:"PRICE_OVERRIDE_STAFF_MAX_DISCOUNT" envDefault:"10"`
+	PriceOverrideAdminMaxDiscount    int `env:"PRICE_OVERRIDE_ADMIN_MAX_DISCOUNT" envDefault:"30"`
+	PriceOverrideApprovedMaxDiscount int `env:"PRICE_OVERRIDE_APPROVED_MAX_DISCOUNT" envDefault:"100"` // 授权后的最大折扣百分比
 }
 
 func InitConfig() {
@@ -26,3 +49,8 @@ const (
 	ModeProduction = "production"
 	ModeBench      = "bench"
 )
+
+const (
+	ImageStorageLocal = "local"
+	ImageStorageS3    = "s3"
+)
@@ -27,8 +27,12 @@ const docTemplate = `{
     "paths": {
         "/balances": {
             "get": {
+                "description": "Set export to csv, xlsx or ndjson to download all the filtered records as a file",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "Balance"
@@ -40,6 +44,16 @@ const docTemplate = `{
                         "name": "end_time",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "name": "export",
+                        "in": "query"
+                    },
                     {
                         "enum": [
                             "id",
@@ -166,11 +180,15 @@ const docTemplate = `{
         },
         "/books": {
             "get": {
+                "description": "Set export to csv, xlsx or ndjson to download all the filtered records as a file",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "Book"
@@ -182,16 +200,61 @@ const docTemplate = `{
                         "name": "author",
                         "in": "query"
                     },
+                    {
+                        "type": "integer",
+                        "description": "including descendant categories",
+                        "name": "category_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "contributor_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "author",
+                            "translator",
+                            "editor",
+                            "illustrator"
+                        ],
+                        "type": "string",
+                        "description": "only with contributor_id",
+                        "name": "contributor_role",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "name": "export",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "name": "id",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "description": "include archived books, admin only",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "name": "isbn",
                         "in": "query"
                     },
+                    {
+                        "type": "integer",
+                        "description": "books in stock at the location",
+                        "name": "location_id",
+                        "in": "query"
+                    },
                     {
                         "type": "boolean",
                         "name": "on_sale",
@@ -233,6 +296,12 @@ const docTemplate = `{
                         "name": "press",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "search title, author, press, description and isbn, ordered by relevance before order_by",
+                        "name": "q",
+                        "in": "query"
+                    },
                     {
                         "enum": [
                             "asc",
@@ -243,6 +312,12 @@ const docTemplate = `{
                         "name": "sort",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "tag name",
+                        "name": "tag",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "name": "title",
@@ -290,18 +365,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{id}": {
-            "patch": {
+        "/books/_import": {
+            "post": {
+                "description": "Upsert books by isbn, each row is validated as BookCreateRequest.\nFor existing books, only the columns present in the row are updated.\nThe file is uploaded as multipart form field \"file\" or as the raw request body.",
                 "consumes": [
+                    "multipart/form-data",
+                    "text/csv",
+                    "application/xml"
+                ],
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Book"
+                ],
+                "summary": "Import books from csv or ONIX 3.0, admin only",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "validate and report only, nothing is saved",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "onix"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "csv or ONIX file",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.BookImportResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}": {
+            "get": {
+                "description": "Get a book with its sale and purchase summary",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Book"
                 ],
-                "summary": "Modify a book",
+                "summary": "Get a book by id",
                 "parameters": [
                     {
                         "type": "integer",
@@ -309,29 +431,39 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "body",
-                        "name": "json",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/apis.BookModifyRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.BookResponse"
+                            "$ref": "#/definitions/apis.BookSummaryResponse"
                         }
                     }
                 }
-            }
-        },
-        "/login": {
-            "post": {
+            },
+            "delete": {
+                "description": "Soft delete a book, blocked while the book has stock or open purchases",
+                "tags": [
+                    "Book"
+                ],
+                "summary": "Archive a book, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
                 "consumes": [
                     "application/json"
                 ],
@@ -339,17 +471,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Book"
                 ],
-                "summary": "Login",
+                "summary": "Modify a book",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "json",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.LoginRequest"
+                            "$ref": "#/definitions/apis.BookModifyRequest"
                         }
                     }
                 ],
@@ -357,57 +496,62 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.BookResponse"
                         }
                     }
                 }
             }
         },
-        "/meta": {
-            "get": {
+        "/books/{id}/_restore": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Meta Module"
+                    "Book"
+                ],
+                "summary": "Restore an archived book, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "获取统计信息",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.MetaInfo"
+                            "$ref": "#/definitions/apis.BookResponse"
                         }
                     }
                 }
             }
         },
-        "/purchases": {
+        "/books/{id}/prices": {
             "get": {
+                "description": "Including the scheduled changes. Set at to list the changes effective at the time, the first one in desc order is the price at the time",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase"
+                    "Book"
                 ],
-                "summary": "List purchases",
+                "summary": "List the price history of a book",
                 "parameters": [
                     {
                         "type": "integer",
-                        "name": "book_id",
-                        "in": "query"
+                        "description": "book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "enum": [
-                            "id",
-                            "created_at",
-                            "updated_at",
-                            "book_id",
-                            "user_id"
-                        ],
                         "type": "string",
-                        "default": "id",
-                        "name": "order_by",
+                        "description": "changes effective at the time, the first one in desc order is the price at the time",
+                        "name": "at",
                         "in": "query"
                     },
                     {
@@ -423,32 +567,35 @@ const docTemplate = `{
                         "name": "page_size",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "description": "scheduled changes not applied yet",
+                        "name": "pending",
+                        "in": "query"
+                    },
                     {
                         "enum": [
                             "asc",
                             "desc"
                         ],
                         "type": "string",
-                        "default": "asc",
+                        "default": "desc",
+                        "description": "by effective_at",
                         "name": "sort",
                         "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "name": "user_id",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseListResponse"
+                            "$ref": "#/definitions/apis.BookPriceListResponse"
                         }
                     }
                 }
             },
             "post": {
+                "description": "Takes effect immediately if effective_at is not set, otherwise the book price is updated automatically at the time",
                 "consumes": [
                     "application/json"
                 ],
@@ -456,17 +603,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase"
+                    "Book"
                 ],
-                "summary": "Create a purchase",
+                "summary": "Change the price of a book",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "json",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseCreateRequest"
+                            "$ref": "#/definitions/apis.BookPriceCreateRequest"
                         }
                     }
                 ],
@@ -474,150 +628,164 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
+                            "$ref": "#/definitions/apis.BookPriceResponse"
                         }
                     }
                 }
             }
         },
-        "/purchases/{id}": {
-            "get": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+        "/books/{id}/prices/{price_id}": {
+            "delete": {
+                "description": "Only changes not yet effective can be cancelled, by the user who scheduled it or an admin",
                 "tags": [
-                    "Purchase"
+                    "Book"
                 ],
-                "summary": "Get a purchase by id",
+                "summary": "Cancel a scheduled price change",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id",
+                        "description": "book id",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
-                        }
-                    }
-                }
-            },
-            "patch": {
-                "description": "Modify the quantity or price of a purchase by id",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Purchase"
-                ],
-                "summary": "Modify a purchase",
-                "parameters": [
+                    },
                     {
                         "type": "integer",
-                        "description": "id",
-                        "name": "id",
+                        "description": "price change id",
+                        "name": "price_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "body",
-                        "name": "json",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/apis.PurchaseModifyRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/purchases/{id}/_arrive": {
-            "post": {
-                "description": "Arrive a purchase by id",
+        "/categories": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase"
+                    "Category"
                 ],
-                "summary": "Arrive a purchase",
+                "summary": "List categories",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                        "type": "string",
+                        "description": "code prefix, e.g. I2 matches I247",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "code",
+                            "name",
+                            "path"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "direct children of the category",
+                        "name": "parent_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "top level categories only",
+                        "name": "root",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
+                            "$ref": "#/definitions/apis.CategoryListResponse"
                         }
                     }
                 }
-            }
-        },
-        "/purchases/{id}/_pay": {
+            },
             "post": {
-                "description": "Pay a purchase by id",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase"
+                    "Category"
                 ],
-                "summary": "Pay a purchase",
+                "summary": "Create a category",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.CategoryCreateRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
+                            "$ref": "#/definitions/apis.CategoryResponse"
                         }
                     }
                 }
             }
         },
-        "/purchases/{id}/_return": {
-            "post": {
-                "description": "Return a purchase by id",
+        "/categories/{id}": {
+            "get": {
+                "description": "Get a category with its direct children",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase"
+                    "Category"
                 ],
-                "summary": "Return a purchase",
+                "summary": "Get a category by id",
                 "parameters": [
                     {
                         "type": "integer",
@@ -631,14 +799,34 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.PurchaseResponse"
+                            "$ref": "#/definitions/apis.CategoryResponse"
                         }
                     }
                 }
-            }
-        },
-        "/register": {
-            "post": {
+            },
+            "delete": {
+                "description": "Only categories without children can be deleted, the books are unlinked",
+                "tags": [
+                    "Category"
+                ],
+                "summary": "Delete a category, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Set parent_id to move the category with all its descendants, 0 moves it to the top level",
                 "consumes": [
                     "application/json"
                 ],
@@ -646,48 +834,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Category"
                 ],
-                "summary": "Register, admin only",
+                "summary": "Modify a category",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "json",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.RegisterRequest"
+                            "$ref": "#/definitions/apis.CategoryModifyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.CategoryResponse"
                         }
                     }
                 }
             }
         },
-        "/sales": {
+        "/contributors": {
             "get": {
+                "description": "Authors, translators, editors and illustrators, list their books with contributor_id of ListBooks",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Sale"
+                    "Contributor"
                 ],
-                "summary": "List sales",
+                "summary": "List contributors",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "name": "book_id",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "name": "end_time",
+                        "name": "name",
                         "in": "query"
                     },
                     {
@@ -695,8 +886,7 @@ const docTemplate = `{
                             "id",
                             "created_at",
                             "updated_at",
-                            "book_id",
-                            "user_id"
+                            "name"
                         ],
                         "type": "string",
                         "default": "id",
@@ -725,28 +915,19 @@ const docTemplate = `{
                         "default": "asc",
                         "name": "sort",
                         "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "name": "start_time",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "name": "user_id",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.SaleListResponse"
+                            "$ref": "#/definitions/apis.ContributorListResponse"
                         }
                     }
                 }
             },
             "post": {
+                "description": "Contributors given by name are also created when a book is created or modified",
                 "consumes": [
                     "application/json"
                 ],
@@ -754,9 +935,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sale"
+                    "Contributor"
                 ],
-                "summary": "Create a sale",
+                "summary": "Create a contributor",
                 "parameters": [
                     {
                         "description": "body",
@@ -764,7 +945,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.SaleCreateRequest"
+                            "$ref": "#/definitions/apis.ContributorCreateRequest"
                         }
                     }
                 ],
@@ -772,14 +953,62 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/apis.SaleResponse"
+                            "$ref": "#/definitions/apis.ContributorResponse"
                         }
                     }
                 }
             }
         },
-        "/sales/{id}": {
+        "/contributors/{id}": {
             "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Contributor"
+                ],
+                "summary": "Get a contributor by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ContributorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Only contributors without books can be deleted",
+                "tags": [
+                    "Contributor"
+                ],
+                "summary": "Delete a contributor, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "description": "The author field of its books is updated",
                 "consumes": [
                     "application/json"
                 ],
@@ -787,9 +1016,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sale"
+                    "Contributor"
                 ],
-                "summary": "Get a sale by id",
+                "summary": "Rename a contributor",
                 "parameters": [
                     {
                         "type": "integer",
@@ -797,38 +1026,42 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.ContributorModifyRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.SaleResponse"
+                            "$ref": "#/definitions/apis.ContributorResponse"
                         }
                     }
                 }
             }
         },
-        "/users": {
+        "/coupons": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Promotion"
                 ],
-                "summary": "list users, admin only",
+                "summary": "List coupons",
                 "parameters": [
                     {
                         "enum": [
                             "id",
-                            "username",
-                            "staff_id",
-                            "register_time",
-                            "last_login"
+                            "created_at",
+                            "used_count"
                         ],
                         "type": "string",
                         "default": "id",
@@ -848,6 +1081,11 @@ const docTemplate = `{
                         "name": "page_size",
                         "in": "query"
                     },
+                    {
+                        "type": "integer",
+                        "name": "promotion_id",
+                        "in": "query"
+                    },
                     {
                         "enum": [
                             "asc",
@@ -863,14 +1101,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apis.UserListResponse"
+                            "$ref": "#/definitions/apis.CouponListResponse"
                         }
                     }
                 }
-            }
-        },
-        "/users/me": {
-            "get": {
+            },
+            "post": {
+                "description": "Codes are unique and case-insensitive, a sale or a receipt counts as one use",
                 "consumes": [
                     "application/json"
                 ],
@@ -878,33 +1115,78 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Promotion"
+                ],
+                "summary": "Create a coupon of a promotion, admin only",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.CouponCreateRequest"
+                        }
+                    }
                 ],
-                "summary": "Get current user",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.CouponResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/coupons/_lookup": {
+            "get": {
+                "description": "Check a coupon at checkout, the code is case-insensitive",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Promotion"
                 ],
-                "summary": "delete self",
-                "responses": {
-                    "204": {
-                        "description": "No Content"
-                    }
+                "summary": "Look up a coupon by code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.CouponResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/{id}": {
+            "delete": {
+                "description": "A used coupon is referenced by sales, set its usage limit to the used count to disable it instead",
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Delete an unused coupon, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
                 }
             },
             "patch": {
@@ -915,17 +1197,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Promotion"
                 ],
-                "summary": "modify current user",
+                "summary": "Modify the usage limit of a coupon, admin only",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "json",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.UserModifyRequest"
+                            "$ref": "#/definitions/apis.CouponModifyRequest"
                         }
                     }
                 ],
@@ -933,14 +1222,75 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.CouponResponse"
                         }
                     }
                 }
             }
         },
-        "/users/{id}": {
+        "/customers": {
             "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customer"
+                ],
+                "summary": "List customers",
+                "parameters": [
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "name"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "search name, phone, email and member number",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.CustomerListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Phone and member number are unique among customers",
                 "consumes": [
                     "application/json"
                 ],
@@ -948,9 +1298,76 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Customer"
                 ],
-                "summary": "get a user by id/username/staff_id, admin only",
+                "summary": "Create a customer",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.CustomerCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.CustomerResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/_lookup": {
+            "get": {
+                "description": "Find a customer by phone, email or member number exactly, e.g. at checkout",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customer"
+                ],
+                "summary": "Look up a customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "member_number",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "phone",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.CustomerResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customer"
+                ],
+                "summary": "Get a customer by id",
                 "parameters": [
                     {
                         "type": "integer",
@@ -964,22 +1381,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.CustomerResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "The sales of the customer are kept",
                 "tags": [
-                    "Account"
+                    "Customer"
                 ],
-                "summary": "delete a user by id, admin only",
+                "summary": "Delete a customer, admin only",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1003,9 +1415,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "Customer"
                 ],
-                "summary": "modify a user by id, admin only",
+                "summary": "Modify a customer",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1016,11 +1428,11 @@ const docTemplate = `{
                     },
                     {
                         "description": "body",
-                        "name": "body",
+                        "name": "json",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/apis.UserModifyRequest"
+                            "$ref": "#/definitions/apis.CustomerModifyRequest"
                         }
                     }
                 ],
@@ -1028,123 +1440,5197 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/apis.CustomerResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "apis.BalanceCreateRequest": {
-            "type": "object",
-            "required": [
-                "change"
-            ],
-            "properties": {
-                "change": {
-                    "type": "number"
-                },
-                "reason": {
-                    "type": "string"
-                }
-            }
         },
-        "apis.BalanceListResponse": {
-            "type": "object",
-            "properties": {
-                "balances": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/apis.BalanceResponse"
+        "/customers/{id}/history": {
+            "get": {
+                "description": "Books bought, total spent and visits of a customer, refunds excluded",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customer"
+                ],
+                "summary": "Get the purchase history of a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.CustomerHistoryResponse"
+                        }
                     }
-                },
-                "page_total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "apis.BalanceResponse": {
-            "type": "object",
-            "properties": {
-                "balance": {
-                    "type": "number"
-                },
-                "change": {
-                    "type": "number"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "info": {
-                    "type": "string"
-                },
-                "operation_id": {
-                    "type": "integer"
-                },
-                "operation_type": {
-                    "type": "integer"
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
-        "apis.BookCreateRequest": {
-            "type": "object",
-            "required": [
-                "author",
-                "isbn",
-                "press",
-                "title"
-            ],
-            "properties": {
-                "author": {
-                    "type": "string",
-                    "minLength": 1
-                },
-                "cover": {
-                    "description": "cover url or base64, null if not set",
+        "/customers/{id}/points": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "List the points records of a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "name": "operation_type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "change"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PointRecordListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "The points of the customer cannot be negative after the adjustment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "Adjust the points of a customer manually, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.PointRecordCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PointRecordResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/images": {
+            "post": {
+                "description": "Upload a cover or an avatar, JPEG, PNG and GIF are supported.\nSet the url of the image as the cover of a book or the avatar of a user.\nThe image is uploaded as multipart form field \"file\" or as the raw request body.",
+                "consumes": [
+                    "multipart/form-data",
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "Upload an image",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "image",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ImageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/{id}": {
+            "get": {
+                "description": "Images are immutable and cached, authorization is not required so that it can be used in img tags",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "Get an image or its thumbnail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "thumbnail",
+                        "name": "thumbnail",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    }
+                }
+            }
+        },
+        "/locations": {
+            "get": {
+                "description": "Stock locations such as the shop floor, warehouses and branches, ordered by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "List locations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apis.LocationResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Create a location, admin only",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.LocationCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.LocationResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/locations/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Get a location by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.LocationResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Only locations out of stock can be deleted, transfer the stock to other locations first. The default location cannot be deleted.",
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Delete a location, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Modify a location, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.LocationModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.LocationResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/membership_tiers": {
+            "get": {
+                "description": "Ordered by min spend, a customer is in the highest tier the spend in the rolling 12 months reaches",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "List membership tiers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apis.MembershipTierResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Tiers of all customers are updated",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "Create a membership tier, admin only",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.MembershipTierCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.MembershipTierResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/membership_tiers/{id}": {
+            "delete": {
+                "description": "Customers in the tier are moved to the highest tier they still reach",
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "Delete a membership tier, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Tiers of all customers are updated",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Loyalty"
+                ],
+                "summary": "Modify a membership tier, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.MembershipTierModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.MembershipTierResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/meta": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meta Module"
+                ],
+                "summary": "获取统计信息",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.MetaInfo"
+                        }
+                    }
+                }
+            }
+        },
+        "/price_overrides": {
+            "get": {
+                "description": "Audit list of the sales whose price is given manually, with the reason and the approver",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "List price overrides, admin only",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "true: approved by an admin, false: within the limit of the user, nil: all",
+                        "name": "approved",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "approver_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PriceOverrideListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "List promotions",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "true: enabled and in the date range now, false: otherwise, nil: all",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "start_at",
+                            "end_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "type",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Promotions are applied when sales are created without a price: the best automatic promotion of the book,\nthen the promotion of the coupon if given. Coupon only promotions are applied with their coupons only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Create a promotion, admin only",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions/_summary": {
+            "get": {
+                "description": "Sales, books sold and discount of each promotion applied in the time range, including deleted promotions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Summarize the sales of promotions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apis.PromotionSummaryResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/promotions/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Get a promotion by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "The promotion and its coupons are no longer applied, the sales it applied to are kept",
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Delete a promotion, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Sales created before are not changed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Promotion"
+                ],
+                "summary": "Modify a promotion, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PromotionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases": {
+            "get": {
+                "description": "Set export to csv, xlsx or ndjson to download all the filtered records as a file",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "List purchases",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "name": "export",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "user_id",
+                            "supplier_id",
+                            "status"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "paid",
+                            "arrived",
+                            "returned",
+                            "cancelled",
+                            "refunded",
+                            "closed"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "supplier_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a purchase with one or more items",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Create a purchase",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}": {
+            "get": {
+                "description": "Get a purchase with its items, arrivals and status transition history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Get a purchase by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Replace the items of a purchase by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Modify a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_arrive": {
+            "post": {
+                "description": "Receive items of a paid purchase by id, may be called several times for partial arrivals.\nReceive all outstanding items if items is not set. The purchase is marked as arrived once all items are received.\nItems are received into the default location if location_id is not set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Arrive a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseArriveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_cancel": {
+            "post": {
+                "description": "Cancel an unpaid purchase by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Cancel a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_close": {
+            "post": {
+                "description": "Close a paid purchase which will not be fully delivered, the supplier credits the missing units",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Close a purchase short",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_pay": {
+            "post": {
+                "description": "Pay all items of a purchase by id, write one balance record",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Pay a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_refund": {
+            "post": {
+                "description": "Refund a paid purchase which has not arrived, e.g. the supplier cancels the order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Refund a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases/{id}/_return": {
+            "post": {
+                "description": "Return an unpaid purchase by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase"
+                ],
+                "summary": "Return a purchase",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.PurchaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/receipts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Receipt"
+                ],
+                "summary": "List receipts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "user_id",
+                            "total"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReceiptListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Check out a cart of books in one transaction, write one balance record for the whole receipt",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Receipt"
+                ],
+                "summary": "Create a receipt",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReceiptCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReceiptResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/receipts/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Receipt"
+                ],
+                "summary": "Get a receipt by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReceiptResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Register, admin only",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/reorder_suggestions": {
+            "get": {
+                "description": "Books on sale whose stock and quantity on order are not above the reorder point, evaluated hourly",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reorder"
+                ],
+                "summary": "List books need reordering",
+                "parameters": [
+                    {
+                        "enum": [
+                            "book_id",
+                            "supplier_id",
+                            "daily_sales",
+                            "quantity"
+                        ],
+                        "type": "string",
+                        "default": "book_id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "supplier_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReorderSuggestionListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reorder_suggestions/_draft": {
+            "post": {
+                "description": "Create draft purchases of the suggested quantities at the latest purchase prices, one purchase for each supplier\nand one for books whose supplier is unknown. Drafted suggestions are removed.\nBooks never purchased have no price and are skipped, they are kept in the suggestions to be purchased manually.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reorder"
+                ],
+                "summary": "Draft purchases from reorder suggestions",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReorderDraftRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apis.PurchaseResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reorder_suggestions/_evaluate": {
+            "post": {
+                "description": "Evaluate the stock of books against their reorder points and recent sales, and replace all suggestions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reorder"
+                ],
+                "summary": "Evaluate reorder suggestions now",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.ReorderEvaluateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales": {
+            "get": {
+                "description": "Set export to csv, xlsx or ndjson to download all the filtered records as a file",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "List sales",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "name": "export",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "book_id",
+                            "user_id"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "sales the promotion is applied to",
+                        "name": "promotion_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "receipt_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "Create a sale",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "Get a sale by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}/_refund": {
+            "post": {
+                "description": "Refund a sale fully or partially by quantity, restore the stock and write a negative balance. Archived books must be restored first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "Refund a sale",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body, refund all remaining quantity if empty",
+                        "name": "json",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleRefundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SaleRefundResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}/refunds": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sale"
+                ],
+                "summary": "List refunds of a sale",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apis.SaleRefundResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/stock_movements": {
+            "get": {
+                "description": "Every change of stock is recorded, including sales, refunds, arrivals, transfers and manual adjustments.\nSet export to csv, xlsx or ndjson to download all the filtered records as a file",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "List stock movements",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "name": "export",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "location_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "initialize",
+                            "sale",
+                            "refund",
+                            "arrival",
+                            "transfer",
+                            "stocktake",
+                            "adjustment",
+                            "damage",
+                            "loss"
+                        ],
+                        "type": "string",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockMovementListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Correct the stock, or write off damaged and lost books with a negative change. The reason is required.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stock"
+                ],
+                "summary": "Adjust stock manually",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockMovementCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockMovementResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "List stocktakes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "location_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "open",
+                            "posted",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Open a stocktake of a location, then submit the counted quantities and post it to adjust the stock",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Open a stocktake",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes/{id}": {
+            "get": {
+                "description": "Get a stocktake with its items and all the submitted counts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Get a stocktake by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes/{id}/_cancel": {
+            "post": {
+                "description": "Discard an open stocktake, the stock is unchanged",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Cancel a stocktake, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes/{id}/_post": {
+            "post": {
+                "description": "Adjust the stock of the counted books at the location to the counted quantities in one transaction,\nwith a stock movement for each book with variance. Books not counted are unchanged.\nSales of the counted books wait until the posting is done.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Post a stocktake, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes/{id}/counts": {
+            "post": {
+                "description": "Submit a batch of counted quantities of an open stocktake, which are added to the quantities counted before,\nso that different staff may count different shelves of the same book",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Submit counted quantities",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeCountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stocktakes/{id}/variances": {
+            "get": {
+                "description": "Compare the counted quantities with the system stock, which is the current stock before posted, ordered by book id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stocktake"
+                ],
+                "summary": "Get the variance report of a stocktake",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "omit books whose counted quantity equals the system stock",
+                        "name": "only_variance",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StocktakeVarianceResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suppliers": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "List suppliers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "name",
+                            "lead_time"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "Create a supplier",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suppliers/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "Get a supplier by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "Delete a supplier, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "Modify a supplier",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suppliers/{id}/summary": {
+            "get": {
+                "description": "Total spend, open unpaid amount and average delivery time from paid to arrived",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Supplier"
+                ],
+                "summary": "Get the purchase summary of a supplier",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.SupplierSummaryResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "List tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "name"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.TagListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Tags are also created when a book is created or modified with tags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Create a tag",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.TagCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.TagResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{id}": {
+            "delete": {
+                "description": "The books are unlinked",
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Delete a tag, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Rename a tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.TagModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.TagResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "List stock transfers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "transfers from or to the location",
+                        "name": "location_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_time",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockTransferListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "The total stock of the book is unchanged",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Transfer stock of a book between locations",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockTransferCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apis.StockTransferResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "list users, admin only",
+                "parameters": [
+                    {
+                        "enum": [
+                            "id",
+                            "username",
+                            "staff_id",
+                            "register_time",
+                            "last_login"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_num",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 10,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apis.UserListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "delete self",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "modify current user",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "json",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.UserModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "get a user by id/username/staff_id, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "delete a user by id, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "modify a user by id, admin only",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apis.UserModifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apis.ApproverRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 30,
+                    "minLength": 8
+                },
+                "username": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.BalanceCreateRequest": {
+            "type": "object",
+            "required": [
+                "change"
+            ],
+            "properties": {
+                "change": {
+                    "type": "number"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.BalanceListResponse": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BalanceResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "change": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "info": {
+                    "type": "string"
+                },
+                "operation_id": {
+                    "type": "integer"
+                },
+                "operation_type": {
+                    "type": "integer"
+                },
+                "related_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookContributorRequest": {
+            "type": "object",
+            "properties": {
+                "contributor_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "role": {
+                    "type": "string",
+                    "default": "author",
+                    "enum": [
+                        "author",
+                        "translator",
+                        "editor",
+                        "illustrator"
+                    ]
+                }
+            }
+        },
+        "apis.BookContributorResponse": {
+            "type": "object",
+            "properties": {
+                "contributor_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.BookCreateRequest": {
+            "type": "object",
+            "required": [
+                "isbn",
+                "press",
+                "title"
+            ],
+            "properties": {
+                "author": {
+                    "description": "split into contributors if contributors is not set, defaults to the byline of contributors",
+                    "type": "string",
+                    "minLength": 1
+                },
+                "category_ids": {
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "contributors": {
+                    "description": "in order",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/apis.BookContributorRequest"
+                    }
+                },
+                "cover": {
+                    "description": "http(s) url or the url of an uploaded image, null if not set",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "isbn": {
+                    "description": "ISBN-10 or ISBN-13, stored as ISBN-13 without hyphens",
+                    "type": "string"
+                },
+                "on_sale": {
+                    "type": "boolean",
+                    "default": false
+                },
+                "press": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "price": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "published_date": {
+                    "type": "string"
+                },
+                "reorder_point": {
+                    "description": "补货点和补货数量, null 表示按近期销量计算",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "reorder_quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "tags": {
+                    "description": "tags are created if not exist",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.BookImportResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookImportRowResponse"
+                    }
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookImportRowResponse": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "description": "empty if failed, or created in dry run",
+                    "type": "integer"
+                },
+                "detail": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.ErrorDetailElement"
+                    }
+                },
+                "isbn": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "row": {
+                    "description": "csv data row number excluding the header, or ONIX Product index, starts from 1",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "created, updated or failed",
+                    "type": "string"
+                }
+            }
+        },
+        "apis.BookListResponse": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookModifyRequest": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "split into contributors if contributors is not set",
+                    "type": "string",
+                    "minLength": 1
+                },
+                "category_ids": {
+                    "description": "replace categories if set, [] to clear",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "contributors": {
+                    "description": "replace contributors if set, author defaults to their byline",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/apis.BookContributorRequest"
+                    }
+                },
+                "cover": {
+                    "description": "http(s) url or the url of an uploaded image, null if not set",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "on_sale": {
+                    "type": "boolean"
+                },
+                "press": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "price": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "published_date": {
+                    "type": "string"
+                },
+                "reorder_point": {
+                    "description": "补货点和补货数量",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "reorder_quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "tags": {
+                    "description": "replace tags if set, [] to clear",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.BookPriceCreateRequest": {
+            "type": "object",
+            "properties": {
+                "effective_at": {
+                    "description": "null to take effect immediately, must not be in the past",
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.BookPriceListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookPriceResponse"
+                    }
+                }
+            }
+        },
+        "apis.BookPriceResponse": {
+            "type": "object",
+            "properties": {
+                "applied_at": {
+                    "description": "null if pending",
+                    "type": "string"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "description": "user who change the price",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookResponse": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "description": "null if not archived",
+                    "type": "string"
+                },
+                "author": {
+                    "type": "string"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CategoryResponse"
+                    }
+                },
+                "contributors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookContributorResponse"
+                    }
+                },
+                "cover": {
+                    "description": "http(s) url or the url of an uploaded image, null if not set",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isbn": {
+                    "type": "string"
+                },
+                "on_sale": {
+                    "type": "boolean"
+                },
+                "press": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "单价, 用 int 表示以分为单位，避免浮点数精度问题",
+                    "type": "number"
+                },
+                "published_date": {
+                    "type": "string"
+                },
+                "reorder_point": {
+                    "description": "补货点和补货数量, null 表示按近期销量计算",
+                    "type": "integer"
+                },
+                "reorder_quantity": {
+                    "type": "integer"
+                },
+                "snippet": {
+                    "description": "highlighted with \u003cmark\u003e, only returned when searching with q",
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "stocks": {
+                    "description": "stock of each location, locations out of stock are omitted",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookStockResponse"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.TagResponse"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "user who create the book",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookStockResponse": {
+            "type": "object",
+            "properties": {
+                "location_id": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.BookSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "description": "null if not archived",
+                    "type": "string"
+                },
+                "author": {
+                    "type": "string"
+                },
+                "average_purchase_cost": {
+                    "description": "加权平均采购单价, null if never purchased",
+                    "type": "number"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CategoryResponse"
+                    }
+                },
+                "contributors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookContributorResponse"
+                    }
+                },
+                "cover": {
+                    "description": "http(s) url or the url of an uploaded image, null if not set",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isbn": {
+                    "type": "string"
+                },
+                "last_sale_time": {
+                    "description": "null if never sold",
+                    "type": "string"
+                },
+                "margin": {
+                    "description": "(price - average_purchase_cost) / price, null if not available",
+                    "type": "number"
+                },
+                "on_sale": {
+                    "type": "boolean"
+                },
+                "pending_arrivals": {
+                    "description": "已付款未收货的数量",
+                    "type": "integer"
+                },
+                "press": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "单价, 用 int 表示以分为单位，避免浮点数精度问题",
+                    "type": "number"
+                },
+                "published_date": {
+                    "type": "string"
+                },
+                "reorder_point": {
+                    "description": "补货点和补货数量, null 表示按近期销量计算",
+                    "type": "integer"
+                },
+                "reorder_quantity": {
+                    "type": "integer"
+                },
+                "snippet": {
+                    "description": "highlighted with \u003cmark\u003e, only returned when searching with q",
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "stocks": {
+                    "description": "stock of each location, locations out of stock are omitted",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.BookStockResponse"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.TagResponse"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total_purchased": {
+                    "description": "已付款的采购数量, 结单的采购单只计算已收货部分",
+                    "type": "integer"
+                },
+                "total_sold": {
+                    "description": "销售数量, 已扣除退款",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "user who create the book",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CategoryCreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "parent_id": {
+                    "description": "null for top level",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.CategoryListResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CategoryResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CategoryModifyRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "parent_id": {
+                    "description": "0 moves the category to the top level",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.CategoryResponse": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "description": "direct children, only returned by GetACategory",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CategoryResponse"
+                    }
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.ContributorCreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.ContributorListResponse": {
+            "type": "object",
+            "properties": {
+                "contributors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.ContributorResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.ContributorModifyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "the author field of its books is updated",
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.ContributorResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.CountByMonth": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.CouponCreateRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "promotion_id"
+            ],
+            "properties": {
+                "code": {
+                    "description": "case-insensitive",
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                },
+                "promotion_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "usage_limit": {
+                    "description": "unlimited if not set",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.CouponListResponse": {
+            "type": "object",
+            "properties": {
+                "coupons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CouponResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CouponModifyRequest": {
+            "type": "object",
+            "properties": {
+                "usage_limit": {
+                    "description": "set to the used count to disable the coupon",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.CouponResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "promotion_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "usage_limit": {
+                    "type": "integer"
+                },
+                "used_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CustomerBookResponse": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "$ref": "#/definitions/apis.BookResponse"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "last_bought_at": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "购买数量, 已扣除退款",
+                    "type": "integer"
+                },
+                "spent": {
+                    "description": "已扣除退款",
+                    "type": "number"
+                }
+            }
+        },
+        "apis.CustomerCreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "member_number": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.CustomerHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "description": "latest bought first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CustomerBookResponse"
+                    }
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "first_visit": {
+                    "description": "null if never bought",
+                    "type": "string"
+                },
+                "last_visit": {
+                    "description": "null if never bought",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "购买总数量, 已扣除退款",
+                    "type": "integer"
+                },
+                "sale_count": {
+                    "type": "integer"
+                },
+                "total_spent": {
+                    "description": "消费总额, 已扣除退款",
+                    "type": "number"
+                },
+                "visit_count": {
+                    "description": "a receipt or a sale not in a receipt is one visit",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CustomerListResponse": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CustomerResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.CustomerModifyRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "member_number": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "member_number": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "tier_id": {
+                    "description": "null if no tier is reached",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.ImageResponse": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "bytes",
+                    "type": "integer"
+                },
+                "thumbnail_url": {
+                    "description": "fits in IMAGE_THUMBNAIL_SIZE",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "set as the cover of a book or the avatar of a user",
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.LocationCreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "is_default": {
+                    "description": "the previous default location is unset",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.LocationModifyRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "is_default": {
+                    "description": "set another location as default to unset it",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.LocationResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_default": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 30,
+                    "minLength": 8
+                },
+                "username": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.MembershipTierCreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "min_spend": {
+                    "description": "spend in the rolling 12 months to reach the tier",
+                    "type": "number",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                },
+                "points_per_yuan": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.MembershipTierModifyRequest": {
+            "type": "object",
+            "properties": {
+                "min_spend": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                },
+                "points_per_yuan": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.MembershipTierResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "min_spend": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "points_per_yuan": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.MetaInfo": {
+            "type": "object",
+            "properties": {
+                "balance_count": {
+                    "type": "integer"
+                },
+                "balance_count_by_month": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CountByMonth"
+                    }
+                },
+                "book_count": {
+                    "type": "integer"
+                },
+                "purchase_count": {
+                    "type": "integer"
+                },
+                "purchase_count_by_month": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CountByMonth"
+                    }
+                },
+                "sale_by_category": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.SaleByCategory"
+                    }
+                },
+                "sale_count": {
+                    "type": "integer"
+                },
+                "sale_count_by_month": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.CountByMonth"
+                    }
+                },
+                "user_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PointRecordCreateRequest": {
+            "type": "object",
+            "required": [
+                "change"
+            ],
+            "properties": {
+                "change": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.PointRecordListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PointRecordResponse"
+                    }
+                }
+            }
+        },
+        "apis.PointRecordResponse": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "info": {
+                    "type": "string"
+                },
+                "operation_id": {
+                    "type": "integer"
+                },
+                "operation_type": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "points of the customer after the change",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PriceOverrideListResponse": {
+            "type": "object",
+            "properties": {
+                "overrides": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PriceOverrideResponse"
+                    }
+                },
+                "page_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PriceOverrideResponse": {
+            "type": "object",
+            "properties": {
+                "approver_id": {
+                    "type": "integer"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "price of the latest received purchase, null if never purchased",
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "discount_percent": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "list_price": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "sale_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PromotionCreateRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "type"
+            ],
+            "properties": {
+                "book_ids": {
+                    "description": "scope of the promotion, all books if none is set",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "buy_quantity": {
+                    "description": "N of type 3",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "category_ids": {
+                    "description": "including subcategories",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "coupon_only": {
+                    "description": "only applied with its coupons",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "description": "true if not set",
+                    "type": "boolean"
+                },
+                "end_at": {
+                    "description": "never ends if not set",
+                    "type": "string"
+                },
+                "free_quantity": {
+                    "description": "M of type 3",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "presses": {
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "start_at": {
+                    "description": "started if not set",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "1: percentage, 2: fixed amount off each book, 3: buy N get M",
+                    "type": "integer",
+                    "enum": [
+                        1,
+                        2,
+                        3
+                    ]
+                },
+                "value": {
+                    "description": "percent off for type 1, yuan off for type 2",
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.PromotionListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "promotions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PromotionResponse"
+                    }
+                }
+            }
+        },
+        "apis.PromotionModifyRequest": {
+            "type": "object",
+            "properties": {
+                "book_ids": {
+                    "description": "replace books if set, [] to clear",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "buy_quantity": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "category_ids": {
+                    "description": "replace categories if set, [] to clear",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "coupon_only": {
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "free_quantity": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 1
+                },
+                "presses": {
+                    "description": "replace presses if set, [] to clear",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "start_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "integer",
+                    "enum": [
+                        1,
+                        2,
+                        3
+                    ]
+                },
+                "value": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.PromotionResponse": {
+            "type": "object",
+            "properties": {
+                "book_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "buy_quantity": {
+                    "type": "integer"
+                },
+                "category_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "coupon_only": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "free_quantity": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "presses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "start_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "apis.PromotionSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "discount": {
+                    "description": "of the whole sales including refunded books",
+                    "type": "number"
+                },
+                "promotion": {
+                    "description": "deleted promotions included",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apis.PromotionResponse"
+                        }
+                    ]
+                },
+                "promotion_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "refunded_quantity": {
+                    "type": "integer"
+                },
+                "revenue": {
+                    "description": "refunds excluded",
+                    "type": "number"
+                },
+                "sale_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PurchaseArrivalItemResponse": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PurchaseArrivalResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseArrivalItemResponse"
+                    }
+                },
+                "location_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PurchaseArriveItemRequest": {
+            "type": "object",
+            "required": [
+                "book_id",
+                "quantity"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.PurchaseArriveRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "receive all outstanding items if not set",
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseArriveItemRequest"
+                    }
+                },
+                "location_id": {
+                    "description": "the default location if not set",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.PurchaseCreateRequest": {
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseItemRequest"
+                    }
+                },
+                "supplier_id": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.PurchaseItemRequest": {
+            "type": "object",
+            "required": [
+                "book_id",
+                "price",
+                "quantity"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "price": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.PurchaseItemResponse": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "$ref": "#/definitions/apis.BookResponse"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "outstanding_quantity": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "received_quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PurchaseListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "purchases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseResponse"
+                    }
+                }
+            }
+        },
+        "apis.PurchaseModifyRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseItemRequest"
+                    }
+                },
+                "supplier_id": {
+                    "description": "0 removes the supplier",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.PurchaseResponse": {
+            "type": "object",
+            "properties": {
+                "arrivals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseArrivalResponse"
+                    }
+                },
+                "arrived_at": {
+                    "type": "string"
+                },
+                "arrived_by": {
+                    "type": "integer"
+                },
+                "cancelled_at": {
+                    "type": "string"
+                },
+                "cancelled_by": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseItemResponse"
+                    }
+                },
+                "outstanding_total": {
+                    "type": "number"
+                },
+                "paid_at": {
+                    "type": "string"
+                },
+                "paid_by": {
+                    "type": "integer"
+                },
+                "returned_at": {
+                    "type": "string"
+                },
+                "returned_by": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "supplier_id": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.PurchaseTransitionResponse"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.PurchaseTransitionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.ReceiptCreateRequest": {
+            "type": "object",
+            "required": [
+                "sales"
+            ],
+            "properties": {
+                "approver": {
+                    "description": "credentials of an admin to approve the discounts of the sales above the limit of the user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apis.ApproverRequest"
+                        }
+                    ]
+                },
+                "coupon_code": {
+                    "description": "coupon to apply to each sale it applies to, counted as one use",
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                },
+                "customer_id": {
+                    "description": "customer of all the sales",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "location_id": {
+                    "description": "location of the sales without location_id, the default location if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "redeem_points": {
+                    "description": "points of the customer to redeem as payment of the whole receipt",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "sales": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/apis.SaleCreateRequest"
+                    }
+                }
+            }
+        },
+        "apis.ReceiptListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "receipts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.ReceiptResponse"
+                    }
+                }
+            }
+        },
+        "apis.ReceiptResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "points_earned": {
+                    "type": "integer"
+                },
+                "points_paid": {
+                    "description": "amount paid by points",
+                    "type": "number"
+                },
+                "points_redeemed": {
+                    "description": "积分抵扣和累积",
+                    "type": "integer"
+                },
+                "sales": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.SaleResponse"
+                    }
+                },
+                "total": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "avatar": {
+                    "description": "http(s) url or the url of an uploaded image",
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "is_admin": {
+                    "type": "boolean",
+                    "default": false
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 30,
+                    "minLength": 8
+                },
+                "real_name": {
+                    "type": "string"
+                },
+                "staff_id": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.ReorderDraftRequest": {
+            "type": "object",
+            "properties": {
+                "book_ids": {
+                    "description": "draft all suggestions if not set",
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "apis.ReorderEvaluateResponse": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "description": "books newly need reordering since last evaluated",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "total": {
+                    "description": "number of books need reordering",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.ReorderSuggestionListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "suggestions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.ReorderSuggestionResponse"
+                    }
+                }
+            }
+        },
+        "apis.ReorderSuggestionResponse": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "$ref": "#/definitions/apis.BookResponse"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "daily_sales": {
+                    "description": "average daily sales in recent days",
+                    "type": "number"
+                },
+                "evaluated_at": {
+                    "type": "string"
+                },
+                "on_order": {
+                    "description": "outstanding quantity of draft and paid purchases",
+                    "type": "integer"
+                },
+                "price": {
+                    "description": "price of the latest purchase, null if never purchased",
+                    "type": "number"
+                },
+                "quantity": {
+                    "description": "suggested quantity to purchase",
+                    "type": "integer"
+                },
+                "reorder_point": {
+                    "description": "reorder point of the book, or sales during the lead time if not set",
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "supplier_id": {
+                    "description": "supplier of the latest purchase of the book, null if unknown",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.SaleByCategory": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.SaleCreateRequest": {
+            "type": "object",
+            "required": [
+                "book_id",
+                "quantity"
+            ],
+            "properties": {
+                "approver": {
+                    "description": "credentials of an admin to approve a discount above the limit of the user, ignored in a receipt",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apis.ApproverRequest"
+                        }
+                    ]
+                },
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "coupon_code": {
+                    "description": "promotions apply only if price is not set, the coupon is ignored in a receipt",
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                },
+                "customer_id": {
+                    "description": "ignored in a receipt, use customer_id of the receipt instead",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "location_id": {
+                    "description": "location sold from, the default location if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "price": {
+                    "type": "number"
+                },
+                "price_reason": {
+                    "description": "required if price is set and differs from the book price",
+                    "type": "string",
+                    "maxLength": 256
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "redeem_points": {
+                    "description": "points of the customer to redeem as payment, ignored in a receipt",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.SaleListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "sales": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.SaleResponse"
+                    }
+                }
+            }
+        },
+        "apis.SalePromotionResponse": {
+            "type": "object",
+            "properties": {
+                "coupon_id": {
+                    "description": "null if applied automatically",
+                    "type": "integer"
+                },
+                "discount": {
+                    "type": "number"
+                },
+                "promotion_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.SaleRefundRequest": {
+            "type": "object",
+            "properties": {
+                "location_id": {
+                    "description": "location the books are returned to, the location of the sale if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "quantity": {
+                    "description": "refund all remaining quantity if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "apis.SaleRefundResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "cash": {
+                    "description": "the amount paid by points is returned as points first, the rest is returned in cash",
+                    "type": "number"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "id": {
+                    "type": "integer"
+                },
+                "location_id": {
+                    "type": "integer"
+                },
+                "points_paid": {
+                    "type": "number"
+                },
+                "points_returned": {
+                    "type": "integer"
+                },
+                "points_reversed": {
+                    "description": "points earned by the refunded amount",
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reason": {
                     "type": "string"
                 },
-                "isbn": {
-                    "type": "string",
-                    "minLength": 1
+                "sale_id": {
+                    "type": "integer"
                 },
-                "on_sale": {
-                    "type": "boolean",
-                    "default": false
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.SaleResponse": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "$ref": "#/definitions/apis.BookResponse"
                 },
-                "press": {
-                    "type": "string",
-                    "minLength": 1
+                "book_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "discount": {
+                    "description": "discount of the whole sale",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "location_id": {
+                    "type": "integer"
+                },
+                "points_earned": {
+                    "type": "integer"
+                },
+                "points_paid": {
+                    "description": "amount paid by points",
+                    "type": "number"
+                },
+                "points_redeemed": {
+                    "description": "0 for sales in a receipt, see the receipt",
+                    "type": "integer"
                 },
                 "price": {
-                    "type": "number",
-                    "minimum": 0
+                    "description": "unit price after discount",
+                    "type": "number"
                 },
-                "published_date": {
+                "price_override": {
+                    "description": "null if the price is not given manually",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apis.PriceOverrideResponse"
+                        }
+                    ]
+                },
+                "promotions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.SalePromotionResponse"
+                    }
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "receipt_id": {
+                    "type": "integer"
+                },
+                "refunded_quantity": {
+                    "type": "integer"
+                },
+                "updated_at": {
                     "type": "string"
                 },
-                "title": {
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.StockMovementCreateRequest": {
+            "type": "object",
+            "required": [
+                "book_id",
+                "change",
+                "reason"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "change": {
+                    "description": "negative for damage and loss",
+                    "type": "integer"
+                },
+                "location_id": {
+                    "description": "the default location if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "reason": {
                     "type": "string",
                     "minLength": 1
+                },
+                "type": {
+                    "type": "string",
+                    "default": "adjustment",
+                    "enum": [
+                        "adjustment",
+                        "damage",
+                        "loss"
+                    ]
                 }
             }
         },
-        "apis.BookListResponse": {
+        "apis.StockMovementListResponse": {
             "type": "object",
             "properties": {
-                "books": {
+                "movements": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/apis.BookResponse"
+                        "$ref": "#/definitions/apis.StockMovementResponse"
                     }
                 },
                 "page_total": {
@@ -1152,217 +6638,372 @@ const docTemplate = `{
                 }
             }
         },
-        "apis.BookModifyRequest": {
+        "apis.StockMovementResponse": {
             "type": "object",
             "properties": {
-                "author": {
-                    "type": "string",
-                    "minLength": 1
+                "book_id": {
+                    "type": "integer"
                 },
-                "cover": {
-                    "description": "cover url or base64, null if not set",
+                "change": {
+                    "description": "数量变化, 出库为负",
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "id": {
+                    "type": "integer"
+                },
+                "location_id": {
+                    "type": "integer"
+                },
+                "operation_id": {
+                    "description": "id of the sale, refund, purchase arrival, transfer or stocktake, null for manual adjustments",
+                    "type": "integer"
+                },
+                "reason": {
                     "type": "string"
                 },
-                "on_sale": {
-                    "type": "boolean"
+                "stock": {
+                    "description": "变动后该位置的库存",
+                    "type": "integer"
                 },
-                "press": {
-                    "type": "string",
-                    "minLength": 1
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.StockTransferCreateRequest": {
+            "type": "object",
+            "required": [
+                "book_id",
+                "from_location_id",
+                "quantity",
+                "to_location_id"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "from_location_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_location_id": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "apis.StockTransferListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.StockTransferResponse"
+                    }
+                }
+            }
+        },
+        "apis.StockTransferResponse": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_location_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_location_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.StocktakeCountItemRequest": {
+            "type": "object",
+            "required": [
+                "book_id"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "apis.StocktakeCountRequest": {
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/apis.StocktakeCountItemRequest"
+                    }
+                }
+            }
+        },
+        "apis.StocktakeCountResponse": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.StocktakeCreateRequest": {
+            "type": "object",
+            "properties": {
+                "location_id": {
+                    "description": "the default location if not set",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "note": {
+                    "description": "also the reason of the stock movements when posted",
+                    "type": "string"
+                }
+            }
+        },
+        "apis.StocktakeItemResponse": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
                 },
-                "price": {
-                    "type": "number",
-                    "minimum": 0
+                "counted": {
+                    "type": "integer"
                 },
-                "published_date": {
-                    "type": "string"
+                "system_stock": {
+                    "description": "过账时的系统库存, null if not posted",
+                    "type": "integer"
+                }
+            }
+        },
+        "apis.StocktakeListResponse": {
+            "type": "object",
+            "properties": {
+                "page_total": {
+                    "type": "integer"
                 },
-                "title": {
-                    "type": "string",
-                    "minLength": 1
+                "stocktakes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.StocktakeResponse"
+                    }
                 }
             }
         },
-        "apis.BookResponse": {
+        "apis.StocktakeResponse": {
             "type": "object",
             "properties": {
-                "author": {
+                "closed_at": {
                     "type": "string"
                 },
-                "cover": {
-                    "description": "cover url or base64, null if not set",
-                    "type": "string"
+                "closed_by": {
+                    "type": "integer"
                 },
-                "created_at": {
-                    "type": "string"
+                "counts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.StocktakeCountResponse"
+                    }
                 },
-                "description": {
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "isbn": {
-                    "type": "string"
-                },
-                "on_sale": {
-                    "type": "boolean"
-                },
-                "press": {
-                    "type": "string"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apis.StocktakeItemResponse"
+                    }
                 },
-                "price": {
-                    "description": "单价, 用 int 表示以分为单位，避免浮点数精度问题",
-                    "type": "number"
+                "location_id": {
+                    "type": "integer"
                 },
-                "published_date": {
+                "note": {
                     "type": "string"
                 },
-                "stock": {
-                    "type": "integer"
-                },
-                "title": {
+                "status": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
                 },
                 "user_id": {
-                    "description": "user who create the book",
                     "type": "integer"
                 }
             }
         },
-        "apis.CountByMonth": {
+        "apis.StocktakeVarianceItemResponse": {
             "type": "object",
             "properties": {
-                "count": {
+                "book": {
+                    "$ref": "#/definitions/apis.BookResponse"
+                },
+                "book_id": {
                     "type": "integer"
                 },
-                "month": {
-                    "type": "string"
-                }
-            }
-        },
-        "apis.LoginRequest": {
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
-                    "type": "string",
-                    "maxLength": 30,
-                    "minLength": 8
+                "counted": {
+                    "type": "integer"
                 },
-                "username": {
-                    "type": "string",
-                    "minLength": 1
+                "system_stock": {
+                    "description": "过账时的系统库存, 未过账时为当前系统库存",
+                    "type": "integer"
+                },
+                "variance": {
+                    "description": "盘盈为正, 盘亏为负",
+                    "type": "integer"
                 }
             }
         },
-        "apis.MetaInfo": {
+        "apis.StocktakeVarianceResponse": {
             "type": "object",
             "properties": {
-                "balance_count": {
-                    "type": "integer"
-                },
-                "balance_count_by_month": {
+                "items": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/apis.CountByMonth"
+                        "$ref": "#/definitions/apis.StocktakeVarianceItemResponse"
                     }
                 },
-                "book_count": {
-                    "type": "integer"
-                },
-                "purchase_count": {
+                "shortage": {
+                    "description": "盘亏总数量, 正数",
                     "type": "integer"
                 },
-                "purchase_count_by_month": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/apis.CountByMonth"
-                    }
+                "status": {
+                    "type": "string"
                 },
-                "sale_count": {
+                "stocktake_id": {
                     "type": "integer"
                 },
-                "sale_count_by_month": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/apis.CountByMonth"
-                    }
-                },
-                "user_count": {
+                "surplus": {
+                    "description": "盘盈总数量",
                     "type": "integer"
                 }
             }
         },
-        "apis.PurchaseCreateRequest": {
+        "apis.SupplierCreateRequest": {
             "type": "object",
             "required": [
-                "book_id",
-                "price",
-                "quantity"
+                "name"
             ],
             "properties": {
-                "book_id": {
-                    "type": "integer",
-                    "minimum": 1
+                "contact": {
+                    "type": "string"
                 },
-                "price": {
-                    "type": "number",
+                "lead_time": {
+                    "description": "默认交货周期, 以天为单位",
+                    "type": "integer",
                     "minimum": 0
                 },
-                "quantity": {
-                    "type": "integer",
-                    "minimum": 1
+                "name": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "tax_id": {
+                    "type": "string",
+                    "maxLength": 64
                 }
             }
         },
-        "apis.PurchaseListResponse": {
+        "apis.SupplierListResponse": {
             "type": "object",
             "properties": {
                 "page_total": {
                     "type": "integer"
                 },
-                "purchases": {
+                "suppliers": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/apis.PurchaseResponse"
+                        "$ref": "#/definitions/apis.SupplierResponse"
                     }
                 }
             }
         },
-        "apis.PurchaseModifyRequest": {
+        "apis.SupplierModifyRequest": {
             "type": "object",
             "properties": {
-                "price": {
-                    "type": "number",
-                    "minimum": 0
+                "contact": {
+                    "type": "string"
                 },
-                "quantity": {
+                "lead_time": {
                     "type": "integer",
-                    "minimum": 1
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "tax_id": {
+                    "type": "string",
+                    "maxLength": 64
                 }
             }
         },
-        "apis.PurchaseResponse": {
+        "apis.SupplierResponse": {
             "type": "object",
             "properties": {
-                "arrived": {
-                    "type": "boolean"
-                },
-                "book": {
-                    "$ref": "#/definitions/apis.BookResponse"
-                },
-                "book_id": {
-                    "type": "integer"
+                "contact": {
+                    "type": "string"
                 },
                 "created_at": {
                     "type": "string"
@@ -1370,120 +7011,94 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
-                "paid": {
-                    "type": "boolean"
+                "lead_time": {
+                    "type": "integer"
                 },
-                "price": {
-                    "type": "number"
+                "name": {
+                    "type": "string"
                 },
-                "quantity": {
-                    "type": "integer"
+                "notes": {
+                    "type": "string"
                 },
-                "returned": {
-                    "type": "boolean"
+                "tax_id": {
+                    "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
-        "apis.RegisterRequest": {
+        "apis.SupplierSummaryResponse": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "avatar": {
-                    "type": "string"
-                },
-                "gender": {
-                    "type": "string"
-                },
-                "is_admin": {
-                    "type": "boolean",
-                    "default": false
+                "average_delivery_days": {
+                    "description": "付款到收货的平均天数, null if no purchase arrived",
+                    "type": "number"
                 },
-                "password": {
-                    "type": "string",
-                    "maxLength": 30,
-                    "minLength": 8
+                "open_unpaid": {
+                    "description": "未付款且未退货、未取消的采购总额",
+                    "type": "number"
                 },
-                "real_name": {
-                    "type": "string"
+                "purchase_count": {
+                    "type": "integer"
                 },
-                "staff_id": {
-                    "type": "string"
+                "supplier_id": {
+                    "type": "integer"
                 },
-                "username": {
-                    "type": "string",
-                    "minLength": 1
+                "total_spend": {
+                    "description": "已付款采购总额, 结单的采购单只计算已收货部分",
+                    "type": "number"
                 }
             }
         },
-        "apis.SaleCreateRequest": {
+        "apis.TagCreateRequest": {
             "type": "object",
             "required": [
-                "book_id",
-                "quantity"
+                "name"
             ],
             "properties": {
-                "book_id": {
-                    "type": "integer",
-                    "minimum": 1
-                },
-                "price": {
-                    "type": "number"
-                },
-                "quantity": {
-                    "type": "integer",
-                    "minimum": 1
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
                 }
             }
         },
-        "apis.SaleListResponse": {
+        "apis.TagListResponse": {
             "type": "object",
             "properties": {
                 "page_total": {
                     "type": "integer"
                 },
-                "sales": {
+                "tags": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/apis.SaleResponse"
+                        "$ref": "#/definitions/apis.TagResponse"
                     }
                 }
             }
         },
-        "apis.SaleResponse": {
+        "apis.TagModifyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                }
+            }
+        },
+        "apis.TagResponse": {
             "type": "object",
             "properties": {
-                "book": {
-                    "$ref": "#/definitions/apis.BookResponse"
-                },
-                "book_id": {
-                    "type": "integer"
-                },
-                "created_at": {
-                    "type": "string"
-                },
                 "id": {
                     "type": "integer"
                 },
-                "price": {
-                    "type": "number"
-                },
-                "quantity": {
-                    "type": "integer"
-                },
-                "updated_at": {
+                "name": {
                     "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
@@ -1505,6 +7120,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "avatar": {
+                    "description": "http(s) url or the url of an uploaded image",
                     "type": "string"
                 },
                 "gender": {
@@ -1535,6 +7151,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "avatar": {
+                    "description": "http(s) url or the url of an uploaded image",
                     "type": "string"
                 },
                 "gender": {
@@ -1568,6 +7185,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "avatar": {
+                    "description": "http(s) url or the url of an uploaded image",
                     "type": "string"
                 },
                 "gender": {
@@ -1595,6 +7213,20 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "utils.ErrorDetailElement": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "tag": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
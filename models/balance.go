@@ -16,6 +16,7 @@ type Balance struct {
 	User          *User         `json:"-"`
 	OperationType OperationType `json:"operation_type" gorm:"not null"`
 	OperationID   int           `json:"operation_id"`
+	RelatedID     *int          `json:"related_id"` // 关联的流水, 如采购退款对应的采购支出
 	Reason        *string       `json:"reason"`
 }
 
@@ -42,13 +43,21 @@ const (
 	OperationTypeSale
 	OperationTypeManual
 	OperationTypeInitialize
+	OperationTypeReceipt
+	OperationTypeRefund
+	OperationTypeSupplierCredit
+	OperationTypePurchaseRefund
 )
 
 var OperationTypeMap = map[OperationType]string{
-	OperationTypePurchase:   "采购支出",
-	OperationTypeSale:       "销售收入",
-	OperationTypeManual:     "手动收支",
-	OperationTypeInitialize: "初始化",
+	OperationTypePurchase:       "采购支出",
+	OperationTypeSale:           "销售收入",
+	OperationTypeManual:         "手动收支",
+	OperationTypeInitialize:     "初始化",
+	OperationTypeReceipt:        "小票收入",
+	OperationTypeRefund:         "销售退款",
+	OperationTypeSupplierCredit: "供应商短缺退款",
+	OperationTypePurchaseRefund: "采购退款",
 }
 
 func (b *Balance) BeforeCreate(tx *gorm.DB) (err error) {
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
 
 type PageRequest struct {
 	PageNum  *int `json:"page_num" query:"page_num" validate:"omitempty,min=1"`
@@ -15,3 +19,19 @@ func (q PageRequest) QuerySet(tx *gorm.DB) *gorm.DB {
 }
 
 type Map map[string]any
+
+// WithDeleted is a preload condition which includes soft deleted records,
+// e.g. history records should still resolve their archived book
+func WithDeleted(tx *gorm.DB) *gorm.DB {
+	return tx.Unscoped()
+}
+
+// IsDuplicatedKey reports whether err is a unique constraint violation.
+// Postgres errors are translated to gorm.ErrDuplicatedKey, the sqlite driver misses sqlite3.Error returned by value
+func IsDuplicatedKey(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
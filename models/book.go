@@ -1,24 +1,39 @@
 package models
 
-import "time"
+import (
+	"book_management_system_backend/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrBookHasStock = utils.BadRequest("书籍仍有库存, 无法归档")
+var ErrBookISBNExists = utils.Conflict("该 ISBN 的书籍已存在")
+var ErrBookHasOpenPurchases = utils.BadRequest("书籍存在未完成的采购单, 无法归档")
 
 type Book struct {
-	ID            int        `json:"id"`
-	CreatedAt     time.Time  `json:"created_at" gorm:"not null"`
-	UpdatedAt     time.Time  `json:"updated_at" gorm:"not null"`
-	DeletedAt     time.Time  `json:"-" gorm:"index"`
-	UserID        int        `json:"user_id" gorm:"not null"` // user who create the book
-	User          *User      `json:"-"`
-	ISBN          string     `json:"isbn" gorm:"not null"`
-	Title         string     `json:"title" gorm:"not null"`
-	Description   *string    `json:"description"`
-	Author        string     `json:"author" gorm:"not null"`
-	Press         string     `json:"press" gorm:"not null"`
-	PublishedDate *time.Time `json:"published_date"`
-	Cover         *string    `json:"cover"` // cover url or base64, null if not set
-	Price         *int       `json:"price"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
-	Stock         int        `json:"stock" gorm:"default:0;not null"`
-	OnSale        bool       `json:"on_sale" gorm:"default:false;not null"`
+	ID              int               `json:"id"`
+	CreatedAt       time.Time         `json:"created_at" gorm:"not null"`
+	UpdatedAt       time.Time         `json:"updated_at" gorm:"not null"`
+	DeletedAt       gorm.DeletedAt    `json:"-" gorm:"index"`          // 归档时间, 归档的书籍不可销售和采购
+	UserID          int               `json:"user_id" gorm:"not null"` // user who create the book
+	User            *User             `json:"-"`
+	ISBN            string            `json:"isbn" gorm:"not null;uniqueIndex"` // normalized ISBN-13, see utils.NormalizeISBN
+	Title           string            `json:"title" gorm:"not null"`
+	Description     *string           `json:"description"`
+	Author          string            `json:"author" gorm:"not null"` // byline of the contributors, see ContributorByline
+	Press           string            `json:"press" gorm:"not null"`
+	PublishedDate   *time.Time        `json:"published_date"`
+	Cover           *string           `json:"cover"`                                             // http(s) url or the url of an uploaded image, null if not set
+	Price           *int              `json:"price"`                                             // 单价, 用 int 表示以分为单位，避免浮点数精度问题
+	Stock           int               `json:"stock" gorm:"default:0;not null"`                   // total stock of all locations, see ChangeStock
+	Stocks          []BookStock       `json:"stocks"`                                            // stock of each location, see PreloadBookStocks
+	ReorderPoint    *int              `json:"reorder_point" gorm:"check:reorder_point>=0"`       // 补货点, 库存与在途数量之和不高于补货点时建议补货, null 表示按近期销量计算, see EvaluateReorders
+	ReorderQuantity *int              `json:"reorder_quantity" gorm:"check:reorder_quantity>=1"` // 补货数量, null 表示按近期销量计算
+	OnSale          bool              `json:"on_sale" gorm:"default:false;not null"`
+	Categories      []Category        `json:"categories" gorm:"many2many:book_category"`
+	Tags            []Tag             `json:"tags" gorm:"many2many:book_tag"`
+	Contributors    []BookContributor `json:"contributors"`            // ordered by position
+	Snippet         *string           `json:"-" gorm:"->;-:migration"` // highlighted search snippet, only selected by SearchBooks
 }
 
 func (b *Book) PriceFloat() float64 {
@@ -27,3 +42,45 @@ func (b *Book) PriceFloat() float64 {
 	}
 	return float64(*b.Price) / 100
 }
+
+// ArchivedAt returns the archive time, null if not archived
+func (b *Book) ArchivedAt() *time.Time {
+	if !b.DeletedAt.Valid {
+		return nil
+	}
+	return &b.DeletedAt.Time
+}
+
+// Archive soft deletes the book, which is blocked while the book has stock or open purchases.
+// The book should be locked in the transaction.
+func (b *Book) Archive(tx *gorm.DB) error {
+	if b.Stock > 0 {
+		return ErrBookHasStock
+	}
+
+	var openPurchases int64
+	err := tx.Model(&PurchaseItem{}).
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase_item.book_id = ? AND purchase.status IN ?", b.ID, []PurchaseStatus{PurchaseStatusDraft, PurchaseStatusPaid}).
+		Count(&openPurchases).Error
+	if err != nil {
+		return err
+	}
+	if openPurchases > 0 {
+		return ErrBookHasOpenPurchases
+	}
+
+	return tx.Delete(b).Error
+}
+
+// CheckBooksExist returns ErrBookNotFound if any of the books does not exist or is archived
+func CheckBooksExist(tx *gorm.DB, bookIDs []int) error {
+	var count int64
+	if err := tx.Model(&Book{}).Where("id IN ?", bookIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(bookIDs) {
+		return ErrBookNotFound
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrBookPriceApplied = utils.BadRequest("价格变更已生效, 无法取消")
+
+// BookPrice 书籍价格变更记录, effective_at 在未来的为计划变更, 由 ApplyScheduledPrices 定时生效.
+// 某一时刻的价格为该时刻之前最近一次生效的变更
+type BookPrice struct {
+	ID          int        `json:"id"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	BookID      int        `json:"book_id" gorm:"not null;index:idx_book_price_effective,priority:1"`
+	Book        *Book      `json:"-"`
+	UserID      int        `json:"user_id" gorm:"not null"` // user who change the price
+	User        *User      `json:"-"`
+	Price       int        `json:"price" gorm:"not null;check:price>=0"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
+	EffectiveAt time.Time  `json:"effective_at" gorm:"not null;index:idx_book_price_effective,priority:2"`
+	AppliedAt   *time.Time `json:"applied_at" gorm:"index"` // time when Book.Price is updated, null if pending
+}
+
+func (p *BookPrice) PriceFloat() float64 {
+	return float64(p.Price) / 100
+}
+
+// ChangePrice records a price change of the book, it takes effect immediately if effectiveAt is not in the future.
+func (b *Book) ChangePrice(tx *gorm.DB, userID int, price int, effectiveAt time.Time) (*BookPrice, error) {
+	record := BookPrice{BookID: b.ID, UserID: userID, Price: price, EffectiveAt: effectiveAt}
+	now := time.Now()
+	if effectiveAt.After(now) {
+		return &record, tx.Create(&record).Error
+	}
+
+	record.AppliedAt = &now
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	b.Price = &price
+	return &record, tx.Unscoped().Model(&Book{}).Where("id = ?", b.ID).Update("price", price).Error
+}
+
+// EffectivePrice returns the price of the book at the time, null if the price was not set
+func (b *Book) EffectivePrice(tx *gorm.DB, at time.Time) (*int, error) {
+	var record BookPrice
+	err := tx.Where("book_id = ? AND effective_at <= ?", b.ID, at).
+		Order("effective_at DESC, id DESC").Limit(1).Find(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	if record.ID == 0 {
+		return nil, nil
+	}
+	return &record.Price, nil
+}
+
+// ApplyScheduledPrices updates the price of the books whose scheduled changes are due, returns the number of changes applied
+func ApplyScheduledPrices(db *gorm.DB, now time.Time) (int, error) {
+	var records []BookPrice
+	err := db.Where("applied_at IS NULL AND effective_at <= ?", now).Order("effective_at, id").Find(&records).Error
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, record := range records {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// claim the change, it may be applied by another instance or cancelled
+			result := tx.Model(&BookPrice{}).Where("id = ? AND applied_at IS NULL", record.ID).Update("applied_at", now)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			applied++
+
+			// a later change may have taken effect already
+			book := Book{ID: record.BookID}
+			price, err := book.EffectivePrice(tx, now)
+			if err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&Book{}).Where("id = ?", record.BookID).Update("price", price).Error
+		})
+		if err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// StartPriceScheduler applies the scheduled price changes periodically in background
+func StartPriceScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			applied, err := ApplyScheduledPrices(DB, now)
+			if err != nil {
+				utils.Logger.Error("apply scheduled prices error", zap.Error(err))
+			} else if applied > 0 {
+				utils.Logger.Info("scheduled prices applied", zap.Int("count", applied))
+			}
+		}
+	}()
+}
+
+// migrateBookPrices 为没有价格记录的书籍 (旧版书籍) 创建一条初始价格记录, 更早的价格历史已无法恢复
+func migrateBookPrices(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO book_price (created_at, book_id, user_id, price, effective_at, applied_at)
+		SELECT ?, id, user_id, price, created_at, created_at FROM book
+		WHERE price IS NOT NULL AND id NOT IN (SELECT book_id FROM book_price)
+	`, time.Now()).Error
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"book_management_system_backend/config"
+	"book_management_system_backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	BookSearchModePostgres = "postgres" // tsvector + pg_trgm
+	BookSearchModeFTS5     = "fts5"     // sqlite fts5, requires building with -tags sqlite_fts5 as in the Dockerfile and CI
+	BookSearchModeLike     = "like"     // fallback when fts5 is not available
+)
+
+// BookSearchMode is decided by initBookSearch according to the database
+var BookSearchMode = BookSearchModeLike
+
+const (
+	bookSearchMaxTerms = 10
+	bookSnippetRunes   = 64 // length of snippets highlighted by HighlightBookSnippets
+)
+
+// initBookSearch creates the full text index of books, it is safe to be called on every start
+func initBookSearch(db *gorm.DB) error {
+	if config.Config.Mode == config.ModeProduction {
+		BookSearchMode = BookSearchModePostgres
+		for _, sql := range []string{
+			`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+			`ALTER TABLE book ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(author, '') || ' ' || coalesce(isbn, '')), 'B') ||
+				setweight(to_tsvector('simple', coalesce(press, '')), 'C') ||
+				setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+			) STORED`,
+			`CREATE INDEX IF NOT EXISTS idx_book_search_vector ON book USING gin (search_vector)`,
+			`CREATE INDEX IF NOT EXISTS idx_book_search_trgm ON book USING gin ((title || ' ' || author || ' ' || press || ' ' || isbn) gin_trgm_ops)`,
+		} {
+			if err := db.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// creating the table succeeds without the module if it was created by a build with fts5
+	var fts5 bool
+	err := db.Raw(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5).Error
+	if err == nil && fts5 {
+		// trigram tokenizer supports substring and CJK search, terms shorter than 3 characters fall back to LIKE
+		err = db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS book_fts USING fts5(
+			title, author, press, description, isbn,
+			content='book', content_rowid='id', tokenize='trigram'
+		)`).Error
+	}
+	if err != nil || !fts5 {
+		// the triggers would break writes to book once the fts5 module is missing
+		utils.Logger.Warn("fts5 is not available, book search falls back to LIKE", zap.Error(err))
+		BookSearchMode = BookSearchModeLike
+		for _, trigger := range []string{"book_fts_insert", "book_fts_delete", "book_fts_update"} {
+			if err = db.Exec(`DROP TRIGGER IF EXISTS ` + trigger).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	BookSearchMode = BookSearchModeFTS5
+	for _, sql := range []string{
+		`CREATE TRIGGER IF NOT EXISTS book_fts_insert AFTER INSERT ON book BEGIN
+			INSERT INTO book_fts (rowid, title, author, press, description, isbn)
+			VALUES (new.id, new.title, new.author, new.press, new.description, new.isbn);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS book_fts_delete AFTER DELETE ON book BEGIN
+			INSERT INTO book_fts (book_fts, rowid, title, author, press, description, isbn)
+			VALUES ('delete', old.id, old.title, old.author, old.press, old.description, old.isbn);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS book_fts_update AFTER UPDATE ON book BEGIN
+			INSERT INTO book_fts (book_fts, rowid, title, author, press, description, isbn)
+			VALUES ('delete', old.id, old.title, old.author, old.press, old.description, old.isbn);
+			INSERT INTO book_fts (rowid, title, author, press, description, isbn)
+			VALUES (new.id, new.title, new.author, new.press, new.description, new.isbn);
+		END`,
+		// books may be written while the triggers were dropped
+		`INSERT INTO book_fts (book_fts) VALUES ('rebuild')`,
+	} {
+		if err = db.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookSearchTerms splits the search query by white spaces
+func bookSearchTerms(q string) []string {
+	terms := strings.Fields(q)
+	if len(terms) > bookSearchMaxTerms {
+		terms = terms[:bookSearchMaxTerms]
+	}
+	return terms
+}
+
+// SearchBooks filters the books matching all the terms of q in title, author, press, description or isbn,
+// selects a highlighted snippet and orders the books by relevance.
+// Other orders should be appended after it.
+func SearchBooks(tx *gorm.DB, q string) *gorm.DB {
+	terms := bookSearchTerms(q)
+	if len(terms) == 0 {
+		return tx
+	}
+
+	if BookSearchMode == BookSearchModePostgres {
+		const document = `(book.title || ' ' || book.author || ' ' || book.press || ' ' || book.isbn)`
+		return tx.
+			Where(`book.search_vector @@ plainto_tsquery('simple', ?) OR ? <% `+document, q, q).
+			Select(`book.*,
+				ts_headline('simple', concat_ws(' / ', book.title, book.author, book.press, book.description),
+					plainto_tsquery('simple', ?), 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5') AS snippet,
+				ts_rank(book.search_vector, plainto_tsquery('simple', ?)) + word_similarity(?, `+document+`) AS search_rank`,
+				q, q, q).
+			Order("search_rank DESC")
+	}
+
+	// the trigram tokenizer needs at least 3 characters
+	var ftsTerms, likeTerms []string
+	for _, term := range terms {
+		if BookSearchMode == BookSearchModeFTS5 && utf8.RuneCountInString(term) >= 3 {
+			ftsTerms = append(ftsTerms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		} else {
+			likeTerms = append(likeTerms, term)
+		}
+	}
+
+	// weights: title 8, author 4, isbn 4, press 2, description 1
+	var rank []string
+	var rankVars []any
+	for _, term := range likeTerms {
+		pattern := "%" + term + "%"
+		tx = tx.Where(`book.title LIKE ? OR book.author LIKE ? OR book.press LIKE ? OR book.description LIKE ? OR book.isbn LIKE ?`,
+			pattern, pattern, pattern, pattern, pattern)
+		rank = append(rank, `(CASE WHEN book.title LIKE ? THEN 8 ELSE 0 END + CASE WHEN book.author LIKE ? THEN 4 ELSE 0 END +
+			CASE WHEN book.isbn LIKE ? THEN 4 ELSE 0 END + CASE WHEN book.press LIKE ? THEN 2 ELSE 0 END +
+			CASE WHEN book.description LIKE ? THEN 1 ELSE 0 END)`)
+		rankVars = append(rankVars, pattern, pattern, pattern, pattern, pattern)
+	}
+
+	snippet := `NULL` // filled by HighlightBookSnippets
+	var snippetVars []any
+	if len(ftsTerms) > 0 {
+		match := strings.Join(ftsTerms, " ")
+		tx = tx.Where(`book.id IN (SELECT rowid FROM book_fts WHERE book_fts MATCH ?)`, match)
+		snippet = `(SELECT snippet(book_fts, -1, '<mark>', '</mark>', '…', 16) FROM book_fts WHERE book_fts MATCH ? AND rowid = book.id)`
+		snippetVars = append(snippetVars, match)
+		// bm25 is negative, the smaller the better
+		rank = append(rank, `(SELECT -bm25(book_fts, 8.0, 4.0, 2.0, 1.0, 4.0) FROM book_fts WHERE book_fts MATCH ? AND rowid = book.id)`)
+		rankVars = append(rankVars, match)
+	}
+
+	return tx.
+		Select(`book.*, `+snippet+` AS snippet, `+strings.Join(rank, " + ")+` AS search_rank`, append(snippetVars, rankVars...)...).
+		Order("search_rank DESC")
+}
+
+// HighlightBookSnippets fills the snippets which are not generated by the database,
+// the first field containing any term of q is cut around the match and the terms are wrapped in <mark>
+func HighlightBookSnippets(books []Book, q string) {
+	terms := bookSearchTerms(q)
+	if len(terms) == 0 {
+		return
+	}
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = regexp.QuoteMeta(term)
+	}
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(patterns, "|"))
+
+	for i := range books {
+		book := &books[i]
+		if book.Snippet != nil {
+			continue
+		}
+		fields := []string{book.Title, book.Author, book.Press, book.ISBN}
+		if book.Description != nil {
+			fields = append(fields, *book.Description)
+		}
+		for _, field := range fields {
+			location := pattern.FindStringIndex(field)
+			if location == nil {
+				continue
+			}
+			snippet := pattern.ReplaceAllString(cutAround(field, location[0], bookSnippetRunes), "<mark>$0</mark>")
+			book.Snippet = &snippet
+			break
+		}
+	}
+}
+
+// cutAround cuts a window of at most n runes around the byte offset, marking the cut ends with …
+func cutAround(s string, offset int, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	center := utf8.RuneCountInString(s[:offset])
+	start := center - n/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + n
+	if end > len(runes) {
+		end, start = len(runes), len(runes)-n
+	}
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
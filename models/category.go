@@ -0,0 +1,143 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"time"
+)
+
+var ErrCategoryNotFound = utils.NotFound("分类不存在")
+var ErrCategoryHasChildren = utils.BadRequest("分类存在子分类, 无法删除")
+var ErrCategoryCycle = utils.BadRequest("不能将分类移动到其自身或其子分类下")
+
+// Category 书籍分类, 树形结构, 如中图法 (CLC) 或 BISAC 分类
+type Category struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+	ParentID  *int      `json:"parent_id" gorm:"index"` // null for top level categories
+	Parent    *Category `json:"-"`
+	Code      *string   `json:"code" gorm:"size:32;uniqueIndex"` // e.g. CLC I247 or BISAC FIC000000
+	Name      string    `json:"name" gorm:"size:128;not null"`
+	// materialized path of ancestor ids and itself, e.g. /1/4/9/, descendants share the prefix
+	Path string `json:"path" gorm:"size:512;not null;index"`
+}
+
+// AfterCreate sets the path, which requires the id
+func (c *Category) AfterCreate(tx *gorm.DB) error {
+	parentPath := "/"
+	if c.ParentID != nil {
+		var parent Category
+		if err := tx.Select("path").Take(&parent, *c.ParentID).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+		parentPath = parent.Path
+	}
+	c.Path = parentPath + strconv.Itoa(c.ID) + "/"
+	return tx.Model(c).Omit(clause.Associations).Update("path", c.Path).Error
+}
+
+// MoveTo changes the parent of the category and the paths of all its descendants, parentID nil moves it to the top level.
+// The category should be locked in the transaction.
+func (c *Category) MoveTo(tx *gorm.DB, parentID *int) error {
+	newPath := "/" + strconv.Itoa(c.ID) + "/"
+	if parentID != nil {
+		var parent Category
+		if err := tx.Take(&parent, *parentID).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+		if len(parent.Path) >= len(c.Path) && parent.Path[:len(c.Path)] == c.Path {
+			return ErrCategoryCycle
+		}
+		newPath = parent.Path + strconv.Itoa(c.ID) + "/"
+	}
+
+	err := tx.Model(&Category{}).
+		Where("path LIKE ?", c.Path+"%").
+		Update("path", gorm.Expr("? || SUBSTR(path, ?)", newPath, len(c.Path)+1)).Error
+	if err != nil {
+		return err
+	}
+	c.ParentID, c.Path = parentID, newPath
+	return tx.Model(c).Omit(clause.Associations).Update("parent_id", parentID).Error
+}
+
+// Delete deletes a category without children and unlinks its books
+func (c *Category) Delete(tx *gorm.DB) error {
+	var children int64
+	if err := tx.Model(&Category{}).Where("parent_id = ?", c.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryHasChildren
+	}
+	if err := tx.Exec("DELETE FROM book_category WHERE category_id = ?", c.ID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(c).Error
+}
+
+// BookIDsInCategory is a subquery of the ids of books in the category or any of its descendants
+func BookIDsInCategory(tx *gorm.DB, categoryID int) *gorm.DB {
+	return tx.Table("book_category").
+		Select("book_category.book_id").
+		Joins("JOIN category ON category.id = book_category.category_id").
+		Where("category.path LIKE (SELECT path FROM category WHERE id = ?) || '%'", categoryID)
+}
+
+// Tag 书籍标签, 自由填写, 按名称去重
+type Tag struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	Name      string    `json:"name" gorm:"size:64;not null;uniqueIndex"`
+}
+
+// Delete deletes a tag and unlinks its books
+func (t *Tag) Delete(tx *gorm.DB) error {
+	if err := tx.Exec("DELETE FROM book_tag WHERE tag_id = ?", t.ID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(t).Error
+}
+
+// SetCategories replaces the categories of the book
+func (b *Book) SetCategories(tx *gorm.DB, categoryIDs []int) error {
+	categories := make([]Category, 0, len(categoryIDs))
+	if len(categoryIDs) > 0 {
+		if err := tx.Where("id IN ?", categoryIDs).Order("path").Find(&categories).Error; err != nil {
+			return err
+		}
+		if len(categories) != len(categoryIDs) {
+			return ErrCategoryNotFound
+		}
+	}
+	return tx.Model(b).Omit("Categories.*").Association("Categories").Replace(categories)
+}
+
+// SetTags replaces the tags of the book, tags are created if not exist
+func (b *Book) SetTags(tx *gorm.DB, names []string) error {
+	tags := make([]Tag, len(names))
+	for i, name := range names {
+		if err := tx.Where(Tag{Name: name}).FirstOrCreate(&tags[i]).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(b).Omit("Tags.*").Association("Tags").Replace(tags)
+}
+
+// PreloadBookTaxonomy is a scope which preloads the categories and tags of books
+func PreloadBookTaxonomy(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Categories", func(tx *gorm.DB) *gorm.DB { return tx.Order("path") }).
+		Preload("Tags", func(tx *gorm.DB) *gorm.DB { return tx.Order("name") })
+}
+
+// BookIDsWithTag is a subquery of the ids of books with the tag
+func BookIDsWithTag(tx *gorm.DB, name string) *gorm.DB {
+	return tx.Table("book_tag").
+		Select("book_tag.book_id").
+		Joins("JOIN tag ON tag.id = book_tag.tag_id").
+		Where("tag.name = ?", name)
+}
@@ -0,0 +1,293 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrContributorNotFound = utils.NotFound("作者不存在")
+var ErrContributorHasBooks = utils.BadRequest("作者仍关联书籍, 无法删除")
+
+type ContributorRole = string
+
+const (
+	ContributorRoleAuthor      ContributorRole = "author"      // 著
+	ContributorRoleTranslator  ContributorRole = "translator"  // 译
+	ContributorRoleEditor      ContributorRole = "editor"      // 编
+	ContributorRoleIllustrator ContributorRole = "illustrator" // 绘
+)
+
+// Contributor 作者, 译者, 编者或绘者, 同名的不同人需要手动区分
+type Contributor struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+	Name      string    `json:"name" gorm:"size:128;not null;index"`
+}
+
+// BookContributor 书籍与作者的关联, 同一人可以有多个角色, 按 position 排序
+type BookContributor struct {
+	BookID        int             `json:"book_id" gorm:"primaryKey"`
+	ContributorID int             `json:"contributor_id" gorm:"primaryKey;index"`
+	Contributor   *Contributor    `json:"contributor"`
+	Role          ContributorRole `json:"role" gorm:"primaryKey;size:16"`
+	Position      int             `json:"position" gorm:"not null"`
+}
+
+// Name returns the name of the contributor, which should be loaded
+func (bc BookContributor) Name() string {
+	if bc.Contributor == nil {
+		return ""
+	}
+	return bc.Contributor.Name
+}
+
+// ResolveContributors loads the contributors given by id, and finds the contributors given by name,
+// contributors not found by name are created
+func ResolveContributors(tx *gorm.DB, contributors []BookContributor) error {
+	for i := range contributors {
+		bc := &contributors[i]
+		if bc.ContributorID != 0 {
+			var contributor Contributor
+			if err := tx.Take(&contributor, bc.ContributorID).Error; err != nil {
+				return ErrContributorNotFound
+			}
+			bc.Contributor = &contributor
+			continue
+		}
+
+		var contributor Contributor
+		err := tx.Where(Contributor{Name: bc.Contributor.Name}).Order("id").FirstOrCreate(&contributor).Error
+		if err != nil {
+			return err
+		}
+		bc.ContributorID, bc.Contributor = contributor.ID, &contributor
+	}
+	return nil
+}
+
+// SetContributors replaces the contributors of the book in the given order, the contributors should be resolved.
+// Repeated contributors with the same role are ignored.
+func (b *Book) SetContributors(tx *gorm.DB, contributors []BookContributor) error {
+	if err := tx.Where("book_id = ?", b.ID).Delete(&BookContributor{}).Error; err != nil {
+		return err
+	}
+
+	type key struct {
+		contributorID int
+		role          ContributorRole
+	}
+	seen := make(map[key]bool, len(contributors))
+	b.Contributors = make([]BookContributor, 0, len(contributors))
+	for _, bc := range contributors {
+		if seen[key{bc.ContributorID, bc.Role}] {
+			continue
+		}
+		seen[key{bc.ContributorID, bc.Role}] = true
+		bc.BookID, bc.Position = b.ID, len(b.Contributors)
+		b.Contributors = append(b.Contributors, bc)
+	}
+	if len(b.Contributors) == 0 {
+		return nil
+	}
+	return tx.Omit("Contributor").Create(&b.Contributors).Error
+}
+
+// ContributorByline formats the author field of a book, the names of authors are joined,
+// names of all the contributors are joined if there is no author
+func ContributorByline(contributors []BookContributor) string {
+	var authors, others []string
+	for _, bc := range contributors {
+		if bc.Role == ContributorRoleAuthor {
+			authors = append(authors, bc.Name())
+		} else {
+			others = append(others, bc.Name())
+		}
+	}
+	if len(authors) == 0 {
+		authors = others
+	}
+	return strings.Join(authors, ", ")
+}
+
+// PreloadBookContributors is a scope which preloads the contributors of books in order
+func PreloadBookContributors(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Contributors", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Preload("Contributors.Contributor")
+}
+
+// BookIDsOfContributor is a subquery of the ids of books of the contributor, of any role if role is empty
+func BookIDsOfContributor(tx *gorm.DB, contributorID int, role ContributorRole) *gorm.DB {
+	tx = tx.Model(&BookContributor{}).Select("book_id").Where("contributor_id = ?", contributorID)
+	if role != "" {
+		tx = tx.Where("role = ?", role)
+	}
+	return tx
+}
+
+// Rename renames the contributor and updates the author field of its books
+func (c *Contributor) Rename(tx *gorm.DB, name string) error {
+	if err := tx.Model(c).Update("name", name).Error; err != nil {
+		return err
+	}
+
+	var books []Book
+	err := tx.Unscoped().Scopes(PreloadBookContributors).
+		Where("id IN (?)", BookIDsOfContributor(tx, c.ID, "")).
+		Find(&books).Error
+	if err != nil {
+		return err
+	}
+	for _, book := range books {
+		err = tx.Unscoped().Model(&Book{}).Where("id = ?", book.ID).Update("author", ContributorByline(book.Contributors)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes a contributor without books
+func (c *Contributor) Delete(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&BookContributor{}).Where("contributor_id = ?", c.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrContributorHasBooks
+	}
+	return tx.Delete(c).Error
+}
+
+var (
+	// 分隔不同角色的作者组, 如 "刘慈欣 著; 张三 译"
+	contributorGroupSeparator = regexp.MustCompile(`[;；/|]`)
+	// 分隔同一组的作者, 如 "刘慈欣、王晋康" 或 "Neil Gaiman & Terry Pratchett"
+	contributorSeparator = regexp.MustCompile(`[,，、&]|\s+(?i:and)\s+`)
+	// 以空格分隔的角色后缀之后的部分是下一组作者, 如 "刘慈欣 著 张三 译"
+	contributorRoleBoundary = regexp.MustCompile(`\s(著|编著|原著|译|翻译|主编|编|绘|绘图)\s+`)
+	// 国籍或朝代前缀, 如 "[美]" "（清）"
+	contributorNationality = regexp.MustCompile(`^[\[(（【〔][^\])）】〕]{1,4}[\])）】〕]\s*`)
+	// 括号包围的角色, 如 "(译)" "[ed.]"
+	contributorBracket = regexp.MustCompile(`\s*[\[(（【〔]\s*([^\])）】〕]+?)\s*[\])）】〕]$`)
+	// 英文角色前缀, 如 "translated by"
+	contributorRolePrefix = regexp.MustCompile(`^(?i)(written|translated|edited|illustrated)\s+by\s+`)
+)
+
+// contributorRoleMarkers role markers at the end of a name, longer markers first.
+// Chinese markers apply to all the preceding names of the group without a role, e.g. "潘振华、张三 译",
+// while English markers only apply to the name they follow.
+var contributorRoleMarkers = []struct {
+	marker string
+	role   ContributorRole
+}{
+	{"illustrator", ContributorRoleIllustrator},
+	{"translator", ContributorRoleTranslator},
+	{"editor", ContributorRoleEditor},
+	{"illus.", ContributorRoleIllustrator},
+	{"trans.", ContributorRoleTranslator},
+	{"eds.", ContributorRoleEditor},
+	{"ed.", ContributorRoleEditor},
+	{"编著", ContributorRoleAuthor},
+	{"原著", ContributorRoleAuthor},
+	{"翻译", ContributorRoleTranslator},
+	{"主编", ContributorRoleEditor},
+	{"绘图", ContributorRoleIllustrator},
+	{"插图", ContributorRoleIllustrator},
+	{"著", ContributorRoleAuthor},
+	{"撰", ContributorRoleAuthor},
+	{"译", ContributorRoleTranslator},
+	{"编", ContributorRoleEditor},
+	{"绘", ContributorRoleIllustrator},
+}
+
+var contributorRolePrefixes = map[string]ContributorRole{
+	"written":     ContributorRoleAuthor,
+	"translated":  ContributorRoleTranslator,
+	"edited":      ContributorRoleEditor,
+	"illustrated": ContributorRoleIllustrator,
+}
+
+// ParseContributors splits a free text author field into contributors given by name, e.g.
+// "[美] 弗兰克·赫伯特 著; 潘振华、张三 译" or "Terry Pratchett, Neil Gaiman, Paul Kidby (illus.)".
+// Names without a role are authors.
+func ParseContributors(author string) []BookContributor {
+	author = contributorRoleBoundary.ReplaceAllString(author, " $1;")
+
+	var contributors []BookContributor
+	for _, group := range contributorGroupSeparator.Split(author, -1) {
+		pending := len(contributors) // contributors[pending:] of the group have no role yet
+		for _, part := range contributorSeparator.Split(group, -1) {
+			name, role, marker := parseContributorName(part)
+			if name != "" {
+				contributors = append(contributors, BookContributor{Contributor: &Contributor{Name: name}, Role: role})
+			}
+			if role == "" {
+				continue
+			}
+			if name == "" || marker[0] >= utf8.RuneSelf {
+				// a role alone such as "Jane Doe, editor", or a Chinese marker
+				for i := pending; i < len(contributors); i++ {
+					if contributors[i].Role == "" {
+						contributors[i].Role = role
+					}
+				}
+			}
+			pending = len(contributors)
+		}
+	}
+	for i := range contributors {
+		if contributors[i].Role == "" {
+			contributors[i].Role = ContributorRoleAuthor
+		}
+	}
+	return contributors
+}
+
+// parseContributorName strips the nationality and the role marker of a name,
+// the name is empty if the part is a role marker alone
+func parseContributorName(part string) (name string, role ContributorRole, marker string) {
+	name = strings.TrimSpace(part)
+	if matches := contributorRolePrefix.FindStringSubmatch(name); matches != nil {
+		role, marker = contributorRolePrefixes[strings.ToLower(matches[1])], matches[1]
+		name = name[len(matches[0]):]
+	}
+	name = contributorNationality.ReplaceAllString(name, "")
+
+	if role == "" {
+		if matches := contributorBracket.FindStringSubmatchIndex(name); matches != nil {
+			if role = contributorRoleOf(name[matches[2]:matches[3]]); role != "" {
+				marker, name = name[matches[2]:matches[3]], name[:matches[0]]
+			}
+		}
+	}
+	if role == "" {
+		if role = contributorRoleOf(name); role != "" {
+			return "", role, name
+		}
+		for _, m := range contributorRoleMarkers {
+			n := len(name) - len(m.marker)
+			if n > 0 && strings.EqualFold(name[n:], m.marker) {
+				role, marker, name = m.role, m.marker, name[:n]
+				break
+			}
+		}
+	}
+	return strings.TrimSpace(name), role, marker
+}
+
+// contributorRoleOf returns the role of a whole marker, empty if it is not a marker
+func contributorRoleOf(marker string) ContributorRole {
+	marker = strings.TrimSpace(marker)
+	for _, m := range contributorRoleMarkers {
+		if strings.EqualFold(marker, m.marker) {
+			return m.role
+		}
+	}
+	return ""
+}
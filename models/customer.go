@@ -0,0 +1,151 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"errors"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+var ErrCustomerNotFound = utils.NotFound("顾客不存在")
+var ErrCustomerPhoneExists = utils.Conflict("该手机号的顾客已存在")
+var ErrCustomerMemberNumberExists = utils.Conflict("该会员号的顾客已存在")
+
+// Customer 顾客, 与员工账号 User 无关. 销售记录可以关联顾客, 用于查询顾客的购买记录
+type Customer struct {
+	ID           int             `json:"id"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"not null"`
+	UpdatedAt    time.Time       `json:"updated_at" gorm:"not null"`
+	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"` // soft deleted, still referenced by sales
+	Name         string          `json:"name" gorm:"size:128;not null;index"`
+	Phone        *string         `json:"phone" gorm:"size:32;index"` // unique among customers not deleted
+	Email        *string         `json:"email" gorm:"size:128;index"`
+	MemberNumber *string         `json:"member_number" gorm:"size:32;index"` // 会员号, unique among customers not deleted
+	Notes        *string         `json:"notes"`
+	TierID       *int            `json:"tier_id" gorm:"index"` // 会员等级, see UpdateTier
+	Tier         *MembershipTier `json:"-"`
+	Points       int             `json:"points" gorm:"default:0;not null"` // 当前积分, total of the last PointRecord
+}
+
+func (c *Customer) AfterCreate(tx *gorm.DB) error {
+	_, err := c.UpdateTier(tx, time.Now())
+	return err
+}
+
+// CheckUnique returns a conflict error if another customer not deleted has the same phone or member number
+func (c *Customer) CheckUnique(tx *gorm.DB) error {
+	var count int64
+	if c.Phone != nil {
+		if err := tx.Model(&Customer{}).Where("phone = ? AND id <> ?", *c.Phone, c.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCustomerPhoneExists
+		}
+	}
+	if c.MemberNumber != nil {
+		if err := tx.Model(&Customer{}).Where("member_number = ? AND id <> ?", *c.MemberNumber, c.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCustomerMemberNumberExists
+		}
+	}
+	return nil
+}
+
+// CheckCustomerExists returns ErrCustomerNotFound if the customer does not exist or is deleted
+func CheckCustomerExists(tx *gorm.DB, customerID int) error {
+	err := tx.Select("id").Take(&Customer{}, customerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCustomerNotFound
+	}
+	return err
+}
+
+// CustomerBook 顾客购买的一本书的汇总
+type CustomerBook struct {
+	BookID       int
+	Book         *Book
+	Quantity     int // refunds excluded
+	Spent        int // 以分为单位, refunds excluded
+	LastBoughtAt time.Time
+}
+
+func (b *CustomerBook) SpentFloat() float64 {
+	return float64(b.Spent) / 100
+}
+
+// CustomerHistory 顾客的购买记录汇总
+type CustomerHistory struct {
+	SaleCount  int
+	VisitCount int // a receipt or a sale not in a receipt is one visit
+	Quantity   int // refunds excluded
+	Spent      int // 以分为单位, refunds excluded
+	FirstVisit *time.Time
+	LastVisit  *time.Time
+	Books      []CustomerBook // ordered by the last time bought, latest first
+}
+
+func (h *CustomerHistory) SpentFloat() float64 {
+	return float64(h.Spent) / 100
+}
+
+// History summarizes the sales of the customer,
+// calculated in go to be database independent as a customer has a few sales
+func (c *Customer) History(tx *gorm.DB) (history CustomerHistory, err error) {
+	var sales []Sale
+	err = tx.Select("id", "created_at", "book_id", "receipt_id", "quantity", "refunded_quantity", "price").
+		Where("customer_id = ?", c.ID).
+		Order("created_at, id").
+		Find(&sales).Error
+	if err != nil {
+		return
+	}
+
+	receipts := make(map[int]bool)
+	bookIndex := make(map[int]int) // book id -> index of history.Books
+	bookIDs := make([]int, 0)
+	for _, sale := range sales {
+		history.SaleCount++
+		if sale.ReceiptID == nil || !receipts[*sale.ReceiptID] {
+			history.VisitCount++
+		}
+		if sale.ReceiptID != nil {
+			receipts[*sale.ReceiptID] = true
+		}
+		createdAt := sale.CreatedAt
+		if history.FirstVisit == nil {
+			history.FirstVisit = &createdAt
+		}
+		history.LastVisit = &createdAt
+
+		quantity := sale.Quantity - sale.RefundedQuantity
+		history.Quantity += quantity
+		history.Spent += sale.Price * quantity
+
+		i, ok := bookIndex[sale.BookID]
+		if !ok {
+			i = len(history.Books)
+			bookIndex[sale.BookID] = i
+			history.Books = append(history.Books, CustomerBook{BookID: sale.BookID})
+			bookIDs = append(bookIDs, sale.BookID)
+		}
+		history.Books[i].Quantity += quantity
+		history.Books[i].Spent += sale.Price * quantity
+		history.Books[i].LastBoughtAt = sale.CreatedAt
+	}
+
+	var books []Book
+	if err = tx.Unscoped().Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		return
+	}
+	for i := range books {
+		history.Books[bookIndex[books[i].ID]].Book = &books[i]
+	}
+	sort.SliceStable(history.Books, func(i, j int) bool {
+		return history.Books[i].LastBoughtAt.After(history.Books[j].LastBoughtAt)
+	})
+	return
+}
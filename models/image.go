@@ -0,0 +1,193 @@
+package models
+
+import (
+	"book_management_system_backend/config"
+	"book_management_system_backend/utils"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImageStorage stores the uploaded images, see InitStorage
+var ImageStorage utils.Storage
+
+// ImageURLPrefix the url of an uploaded image is ImageURLPrefix + id, which is stored as Book.Cover or User.Avatar
+const ImageURLPrefix = "/api/images/"
+
+var ErrImageNotFound = utils.NotFound("图片不存在")
+var ErrImageReference = utils.BadRequest("图片必须是 http(s) 链接或已上传图片的链接")
+
+// Image 上传的图片, 以内容的 sha256 作为 id, 内容不可修改, 相同的图片只保存一次
+type Image struct {
+	ID                   string    `json:"id" gorm:"size:64;primaryKey"`
+	CreatedAt            time.Time `json:"created_at" gorm:"not null"`
+	UserID               int       `json:"user_id" gorm:"not null"` // user who upload the image first
+	ContentType          string    `json:"content_type" gorm:"size:32;not null"`
+	Size                 int       `json:"size" gorm:"not null"` // bytes
+	Width                int       `json:"width" gorm:"not null"`
+	Height               int       `json:"height" gorm:"not null"`
+	ThumbnailContentType string    `json:"thumbnail_content_type" gorm:"size:32;not null"`
+}
+
+func (i *Image) URL() string {
+	return ImageURLPrefix + i.ID
+}
+
+func (i *Image) ThumbnailURL() string {
+	return i.URL() + "?thumbnail=true"
+}
+
+// ThumbnailKey the storage key of the thumbnail, the original image is stored with the id as key
+func (i *Image) ThumbnailKey() string {
+	return i.ID + "_thumbnail"
+}
+
+// InitStorage creates the image storage according to the config, images are kept in memory in test mode
+func InitStorage() {
+	var err error
+	switch {
+	case config.Config.Mode == config.ModeTest || config.Config.Mode == config.ModeBench:
+		ImageStorage = &utils.MemoryStorage{}
+	case config.Config.ImageStorage == config.ImageStorageS3:
+		ImageStorage, err = utils.NewS3Storage(config.Config.S3Endpoint, config.Config.S3Region, config.Config.S3Bucket,
+			config.Config.S3AccessKey, config.Config.S3SecretKey)
+	case config.Config.ImageStorage == config.ImageStorageLocal:
+		ImageStorage, err = utils.NewLocalStorage(config.Config.ImageDir)
+	default:
+		err = errors.New("unknown image storage: " + config.Config.ImageStorage)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// SaveImage validates the type and size of an image, stores it with its thumbnail,
+// the existing image is returned if the same image has been uploaded
+func SaveImage(tx *gorm.DB, userID int, data []byte) (*Image, error) {
+	if len(data) > config.Config.ImageMaxSize {
+		return nil, utils.BadRequest("图片大小不能超过 " + strconv.Itoa(config.Config.ImageMaxSize/1024) + "KB")
+	}
+	img, contentType, err := utils.DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	image := Image{ID: hex.EncodeToString(sum[:])}
+	if err = tx.Limit(1).Find(&image).Error; err != nil {
+		return nil, err
+	}
+	if image.UserID != 0 {
+		return &image, nil
+	}
+
+	thumbnail, thumbnailContentType, err := utils.EncodeThumbnail(utils.Thumbnail(img, config.Config.ImageThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+	image.UserID = userID
+	image.ContentType = contentType
+	image.Size = len(data)
+	image.Width, image.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	image.ThumbnailContentType = thumbnailContentType
+
+	// objects are written before the record, an orphan object is harmless and overwritten by the next upload
+	if err = ImageStorage.Put(image.ID, image.ContentType, data); err != nil {
+		return nil, err
+	}
+	if err = ImageStorage.Put(image.ThumbnailKey(), image.ThumbnailContentType, thumbnail); err != nil {
+		return nil, err
+	}
+	return &image, tx.Create(&image).Error
+}
+
+// ImageIDFromURL returns the image id of the url of an uploaded image
+func ImageIDFromURL(url string) (string, bool) {
+	id, ok := strings.CutPrefix(url, ImageURLPrefix)
+	return id, ok && id != "" && !strings.ContainsAny(id, "/?#")
+}
+
+// CheckImageReference checks a cover or an avatar, which is either an http(s) url or the url of an uploaded image
+func CheckImageReference(tx *gorm.DB, reference *string) error {
+	if reference == nil {
+		return nil
+	}
+	if strings.HasPrefix(*reference, "http://") || strings.HasPrefix(*reference, "https://") {
+		return nil
+	}
+	id, ok := ImageIDFromURL(*reference)
+	if !ok {
+		return ErrImageReference
+	}
+	var count int64
+	if err := tx.Model(&Image{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrImageNotFound
+	}
+	return nil
+}
+
+// 旧版的封面和头像可以是 base64, 如 data:image/png;base64,...
+var inlineImagePattern = regexp.MustCompile(`^(?:data:image/[a-z+.-]+;base64,)?([A-Za-z0-9+/=\s]+)$`)
+
+// migrateInlineImages 将旧版以 base64 保存的封面和头像上传到图片存储, 替换为图片链接.
+// 无法解析为图片的值保持不变, 只执行一次
+func migrateInlineImages(db *gorm.DB) error {
+	return runMigrationOnce(db, "inline_images", func(tx *gorm.DB) error {
+		migrate := func(model any, column string, owner string) error {
+			var rows []struct {
+				ID     int
+				Owner  int
+				Inline string
+			}
+			err := tx.Unscoped().Model(model).
+				Select("id", owner+" AS owner", column+" AS inline").
+				Where(column+" IS NOT NULL AND "+column+" NOT LIKE ? AND "+column+" NOT LIKE ? AND "+column+" NOT LIKE ?",
+					"http://%", "https://%", ImageURLPrefix+"%").
+				Scan(&rows).Error
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				matches := inlineImagePattern.FindStringSubmatch(row.Inline)
+				var data []byte
+				if matches != nil {
+					data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(matches[1]), ""))
+				}
+				if matches == nil || err != nil {
+					utils.Logger.Warn("invalid inline image", zap.String("column", column), zap.Int("id", row.ID))
+					continue
+				}
+
+				err = tx.Transaction(func(tx *gorm.DB) error {
+					image, err := SaveImage(tx, row.Owner, data)
+					if err != nil {
+						return err
+					}
+					return tx.Unscoped().Model(model).Where("id = ?", row.ID).UpdateColumn(column, image.URL()).Error
+				})
+				var httpError *utils.HttpError
+				if errors.As(err, &httpError) {
+					utils.Logger.Warn("invalid inline image", zap.String("column", column), zap.Int("id", row.ID), zap.Error(err))
+				} else if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := migrate(&Book{}, "cover", "user_id"); err != nil {
+			return err
+		}
+		return migrate(&User{}, "avatar", "id")
+	})
+}
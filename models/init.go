@@ -3,12 +3,14 @@ package models
 import (
 	"book_management_system_backend/config"
 	"book_management_system_backend/utils"
+	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
+	"strings"
 	"time"
 )
 
@@ -20,6 +22,7 @@ var gormConfig = &gorm.Config{
 	NamingStrategy: schema.NamingStrategy{
 		SingularTable: true, // use singular table name, table for `User` would be `user` with this option enabled
 	},
+	TranslateError: true, // unique constraint violations are returned as gorm.ErrDuplicatedKey
 	Logger: logger.New(
 		utils.StdOutLogger,
 		logger.Config{
@@ -49,7 +52,68 @@ func InitDB() {
 		panic(err)
 	}
 
-	err = DB.AutoMigrate(User{}, Book{}, UserJwtSecret{}, Balance{}, Purchase{}, Sale{})
+	// must run before the unique index on book.isbn is created
+	err = migrateBookISBN(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = DB.AutoMigrate(Migration{}, User{}, Book{}, UserJwtSecret{}, Balance{}, Supplier{}, Purchase{}, PurchaseItem{}, PurchaseArrival{}, PurchaseArrivalItem{}, PurchaseTransition{}, Receipt{}, Sale{}, SaleRefund{}, Category{}, Tag{}, Contributor{}, BookContributor{}, Image{}, BookPrice{}, Location{}, BookStock{}, StockTransfer{}, StockMovement{}, Stocktake{}, StocktakeItem{}, StocktakeCount{}, ReorderSuggestion{}, Customer{}, MembershipTier{}, PointRecord{}, Promotion{}, PromotionPress{}, Coupon{}, SalePromotion{}, PriceOverride{})
+	if err != nil {
+		panic(err)
+	}
+
+	err = migratePurchaseItems(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migratePurchaseStatus(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migratePurchaseReceivedQuantity(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateBookDeletedAt(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateBookContributors(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateInlineImages(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateBookPrices(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateLocations(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateStockMovements(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = migrateMembershipTiers(DB)
+	if err != nil {
+		panic(err)
+	}
+
+	err = initBookSearch(DB)
 	if err != nil {
 		panic(err)
 	}
@@ -81,3 +145,159 @@ func InitDB() {
 		panic(err)
 	}
 }
+
+// migratePurchaseItems 将旧版单行采购记录的 book_id, quantity, price 迁移到采购明细表
+func migratePurchaseItems(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Purchase{}, "book_id") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO purchase_item (purchase_id, book_id, quantity, price)
+			SELECT id, book_id, quantity, price FROM purchase
+		`).Error
+		if err != nil {
+			return err
+		}
+		for _, constraint := range []string{"fk_purchase_book", "chk_purchase_quantity", "chk_purchase_price"} {
+			if tx.Migrator().HasConstraint(&Purchase{}, constraint) {
+				if err = tx.Migrator().DropConstraint(&Purchase{}, constraint); err != nil {
+					return err
+				}
+			}
+		}
+		for _, column := range []string{"book_id", "quantity", "price"} {
+			if err = tx.Migrator().DropColumn(&Purchase{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migratePurchaseStatus 将旧版 paid, arrived, returned 三个布尔字段迁移到 status 字段
+func migratePurchaseStatus(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Purchase{}, "paid") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE purchase SET status = CASE
+				WHEN returned THEN 'returned'
+				WHEN arrived THEN 'arrived'
+				WHEN paid THEN 'paid'
+				ELSE 'draft'
+			END
+		`).Error
+		if err != nil {
+			return err
+		}
+		for _, column := range []string{"paid", "arrived", "returned"} {
+			if err = tx.Migrator().DropColumn(&Purchase{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migratePurchaseReceivedQuantity 旧版已收货的采购单一次性全部收货, 没有收货记录, 将其明细的已收货数量置为采购数量
+func migratePurchaseReceivedQuantity(db *gorm.DB) error {
+	return db.Model(&PurchaseItem{}).
+		Where("received_quantity < quantity").
+		Where("purchase_id IN (?)", db.Model(&Purchase{}).Select("id").Where("status = ?", PurchaseStatusArrived)).
+		UpdateColumn("received_quantity", gorm.Expr("quantity")).Error
+}
+
+// migrateBookDeletedAt 旧版 book.deleted_at 为 time.Time, 未删除的记录为零值, 需要置为 NULL 以支持软删除
+func migrateBookDeletedAt(db *gorm.DB) error {
+	return db.Unscoped().Model(&Book{}).
+		Where("deleted_at < ?", time.Unix(0, 0)).
+		Update("deleted_at", nil).Error
+}
+
+// migrateBookISBN 将旧版书籍的合法 ISBN 规范化为不含连字符的 ISBN-13, 非法 ISBN 保持不变.
+// 规范化后若存在重复的 ISBN 则无法创建唯一索引, 需要手动处理, see Upgrading in README.md
+func migrateBookISBN(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Book{}) || db.Migrator().HasIndex(&Book{}, "ISBN") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var books []Book
+		if err := tx.Unscoped().Select("id", "isbn").Find(&books).Error; err != nil {
+			return err
+		}
+
+		ids := make(map[string][]int, len(books)) // normalized isbn -> book ids
+		for _, book := range books {
+			isbn, ok := utils.NormalizeISBN(book.ISBN)
+			if !ok {
+				isbn = book.ISBN
+			}
+			ids[isbn] = append(ids[isbn], book.ID)
+			if isbn == book.ISBN {
+				continue
+			}
+			err := tx.Unscoped().Model(&Book{}).Where("id = ?", book.ID).UpdateColumn("isbn", isbn).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var duplicates []string
+		for isbn, bookIDs := range ids {
+			if len(bookIDs) > 1 {
+				duplicates = append(duplicates, fmt.Sprintf("%s: %v", isbn, bookIDs))
+			}
+		}
+		if len(duplicates) > 0 {
+			return fmt.Errorf("duplicate book isbn, merge or fix them before upgrading, see Upgrading in README.md: %s", strings.Join(duplicates, "; "))
+		}
+		return nil
+	})
+}
+
+// Migration 记录已执行的一次性数据迁移, see runMigrationOnce
+type Migration struct {
+	Name      string    `gorm:"primaryKey;size:64"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// runMigrationOnce runs a data migration in a transaction and records it by name, it is skipped once recorded
+func runMigrationOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Migration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&Migration{Name: name}).Error
+	})
+}
+
+// migrateBookContributors 将没有关联作者的书籍 (旧版书籍) 的 author 字段拆分为作者, 译者等, author 字段保持不变.
+// 只执行一次, 之后创建的书籍在创建时设置作者
+func migrateBookContributors(db *gorm.DB) error {
+	return runMigrationOnce(db, "book_contributors", func(tx *gorm.DB) error {
+		var books []Book
+		return tx.Unscoped().Select("id", "author").
+			Where("id NOT IN (?)", tx.Model(&BookContributor{}).Select("book_id")).
+			FindInBatches(&books, 500, func(_ *gorm.DB, _ int) error {
+				for i := range books {
+					contributors := ParseContributors(books[i].Author)
+					if err := ResolveContributors(tx, contributors); err != nil {
+						return err
+					}
+					if err := books[i].SetContributors(tx, contributors); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	})
+}
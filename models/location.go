@@ -0,0 +1,177 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrLocationNotFound = utils.NotFound("库存位置不存在")
+var ErrLocationHasStock = utils.BadRequest("库存位置仍有库存, 无法删除")
+var ErrLocationIsDefault = utils.BadRequest("默认库存位置无法删除")
+var ErrTransferSameLocation = utils.BadRequest("调出和调入位置不能相同")
+
+// DefaultLocationName 默认库存位置的名称, 旧版的库存全部迁移到默认位置
+const DefaultLocationName = "门店"
+
+// Location 库存位置, 如门店、仓库、分店. 未指定位置的收货和销售使用默认位置
+type Location struct {
+	ID          int            `json:"id"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`                     // soft deleted, still referenced by sales, arrivals and transfers
+	Name        string         `json:"name" gorm:"size:64;not null;index"` // unique among locations not deleted
+	Description *string        `json:"description"`
+	IsDefault   bool           `json:"is_default" gorm:"default:false;not null"` // exactly one location is the default
+}
+
+// BookStock 书籍在某一位置的库存, Book.Stock 为所有位置库存之和
+type BookStock struct {
+	BookID     int       `json:"book_id" gorm:"primaryKey"`
+	LocationID int       `json:"location_id" gorm:"primaryKey;index"`
+	Location   *Location `json:"-"`
+	Stock      int       `json:"stock" gorm:"default:0;not null;check:stock>=0"`
+}
+
+// StockTransfer 库存调拨记录, 从一个位置调出到另一个位置, 书籍总库存不变
+type StockTransfer struct {
+	ID             int       `json:"id"`
+	CreatedAt      time.Time `json:"created_at" gorm:"not null"`
+	UserID         int       `json:"user_id" gorm:"not null"`
+	User           *User     `json:"-"`
+	BookID         int       `json:"book_id" gorm:"not null;index"`
+	Book           *Book     `json:"-"`
+	FromLocationID int       `json:"from_location_id" gorm:"not null"`
+	FromLocation   *Location `json:"-"`
+	ToLocationID   int       `json:"to_location_id" gorm:"not null"`
+	ToLocation     *Location `json:"-"`
+	Quantity       int       `json:"quantity" gorm:"not null;check:quantity>=1"`
+	Reason         *string   `json:"reason"`
+}
+
+func (t *StockTransfer) BeforeCreate(tx *gorm.DB) error {
+	if t.FromLocationID == t.ToLocationID {
+		return ErrTransferSameLocation
+	}
+	if err := CheckBooksExist(tx, []int{t.BookID}); err != nil {
+		return err
+	}
+	for _, id := range []int{t.FromLocationID, t.ToLocationID} {
+		if err := tx.Take(&Location{}, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *StockTransfer) AfterCreate(tx *gorm.DB) error {
+	err := ChangeStock(tx, &StockMovement{
+		BookID:      t.BookID,
+		LocationID:  t.FromLocationID,
+		UserID:      t.UserID,
+		Type:        StockMovementTypeTransfer,
+		Change:      -t.Quantity,
+		OperationID: &t.ID,
+		Reason:      t.Reason,
+	})
+	if err != nil {
+		return err
+	}
+	return ChangeStock(tx, &StockMovement{
+		BookID:      t.BookID,
+		LocationID:  t.ToLocationID,
+		UserID:      t.UserID,
+		Type:        StockMovementTypeTransfer,
+		Change:      t.Quantity,
+		OperationID: &t.ID,
+		Reason:      t.Reason,
+	})
+}
+
+// ResolveLocation checks the location exists and returns its id, or the id of the default location if id is 0
+func ResolveLocation(tx *gorm.DB, id int) (int, error) {
+	var location Location
+	var err error
+	if id == 0 {
+		err = tx.Where("is_default = ?", true).Take(&location).Error
+	} else {
+		err = tx.Take(&location, id).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrLocationNotFound
+	}
+	return location.ID, err
+}
+
+// StockAt returns the stock of the book at the location
+func StockAt(tx *gorm.DB, bookID, locationID int) (int, error) {
+	var stock BookStock
+	err := tx.Where("book_id = ? AND location_id = ?", bookID, locationID).Limit(1).Find(&stock).Error
+	return stock.Stock, err
+}
+
+// SetDefault makes the location the default one, the location should be locked in the transaction
+func (l *Location) SetDefault(tx *gorm.DB) error {
+	if err := tx.Model(&Location{}).Where("is_default = ? AND id <> ?", true, l.ID).Update("is_default", false).Error; err != nil {
+		return err
+	}
+	l.IsDefault = true
+	return tx.Model(l).Update("is_default", true).Error
+}
+
+// Delete soft deletes the location, which is blocked while it is the default one or has stock.
+// The location should be locked in the transaction.
+func (l *Location) Delete(tx *gorm.DB) error {
+	if l.IsDefault {
+		return ErrLocationIsDefault
+	}
+	var count int64
+	if err := tx.Model(&BookStock{}).Where("location_id = ? AND stock > 0", l.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrLocationHasStock
+	}
+	if err := tx.Where("location_id = ?", l.ID).Delete(&BookStock{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(l).Error
+}
+
+// BookIDsAtLocation is a subquery of the ids of books in stock at the location
+func BookIDsAtLocation(tx *gorm.DB, locationID int) *gorm.DB {
+	return tx.Model(&BookStock{}).Select("book_id").Where("location_id = ? AND stock > 0", locationID)
+}
+
+func PreloadBookStocks(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Stocks", func(tx *gorm.DB) *gorm.DB { return tx.Where("stock > 0").Order("location_id") })
+}
+
+// migrateLocations 创建默认库存位置, 并将旧版书籍的库存迁移到默认位置; 旧版销售记录和收货记录的位置为默认位置
+func migrateLocations(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var location Location
+		err := tx.Where("is_default = ?", true).Attrs(Location{Name: DefaultLocationName, IsDefault: true}).FirstOrCreate(&location).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`
+			INSERT INTO book_stock (book_id, location_id, stock)
+			SELECT id, ?, stock FROM book
+			WHERE stock > 0 AND id NOT IN (SELECT book_id FROM book_stock)
+		`, location.ID).Error
+		if err != nil {
+			return err
+		}
+		for _, model := range []any{&Sale{}, &SaleRefund{}, &PurchaseArrival{}} {
+			if err = tx.Model(model).Where("location_id IS NULL").Update("location_id", location.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
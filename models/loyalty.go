@@ -0,0 +1,245 @@
+package models
+
+import (
+	"book_management_system_backend/config"
+	"book_management_system_backend/utils"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrPointsNotEnough = utils.BadRequest("积分不足")
+var ErrPointsExceedAmount = utils.BadRequest("积分抵扣金额超过应付金额")
+var ErrPointsWithoutCustomer = utils.BadRequest("使用积分需要指定顾客")
+var ErrMembershipTierMinSpendExists = utils.Conflict("该消费金额的会员等级已存在")
+
+// DefaultMembershipTierName 默认会员等级的名称, 没有会员等级时创建
+const DefaultMembershipTierName = "普通会员"
+
+// MembershipTier 会员等级, 顾客近 12 个月的消费金额达到 MinSpend 即为该等级, 取满足条件的最高等级.
+// 等级决定每消费 1 元累积的积分
+type MembershipTier struct {
+	ID            int       `json:"id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"not null"`
+	Name          string    `json:"name" gorm:"size:64;not null"`
+	MinSpend      int       `json:"min_spend" gorm:"not null;uniqueIndex;check:min_spend>=0"` // 近 12 个月消费金额下限, 以分为单位
+	PointsPerYuan int       `json:"points_per_yuan" gorm:"not null;check:points_per_yuan>=0"` // 每消费 1 元累积的积分, 积分抵扣的部分不累积
+}
+
+func (t *MembershipTier) MinSpendFloat() float64 {
+	return float64(t.MinSpend) / 100
+}
+
+// CheckUnique returns a conflict error if another tier has the same min spend
+func (t *MembershipTier) CheckUnique(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&MembershipTier{}).Where("min_spend = ? AND id <> ?", t.MinSpend, t.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMembershipTierMinSpendExists
+	}
+	return nil
+}
+
+// PointRecord 积分流水, 与 Balance 类似, Total 为变更后该顾客的积分
+type PointRecord struct {
+	ID            int                `json:"id"`
+	CreatedAt     time.Time          `json:"created_at" gorm:"not null"`
+	CustomerID    int                `json:"customer_id" gorm:"not null;index"`
+	Customer      *Customer          `json:"-"`
+	Change        int                `json:"change" gorm:"not null"`
+	Total         int                `json:"total" gorm:"not null"` // negative if earned points are reversed after redeemed
+	UserID        int                `json:"user_id" gorm:"not null"`
+	User          *User              `json:"-"`
+	OperationType PointOperationType `json:"operation_type" gorm:"not null"`
+	OperationID   int                `json:"operation_id"` // id of the sale, receipt or refund, 0 for manual changes
+	Reason        *string            `json:"reason"`
+}
+
+type PointOperationType = int
+
+const (
+	PointOperationTypeSale PointOperationType = iota + 1
+	PointOperationTypeReceipt
+	PointOperationTypeSaleRedeem
+	PointOperationTypeReceiptRedeem
+	PointOperationTypeRefundReverse
+	PointOperationTypeRefundReturn
+	PointOperationTypeManual
+)
+
+var PointOperationTypeMap = map[PointOperationType]string{
+	PointOperationTypeSale:          "销售累积",
+	PointOperationTypeReceipt:       "小票累积",
+	PointOperationTypeSaleRedeem:    "销售抵扣",
+	PointOperationTypeReceiptRedeem: "小票抵扣",
+	PointOperationTypeRefundReverse: "退款扣回",
+	PointOperationTypeRefundReturn:  "退款返还",
+	PointOperationTypeManual:        "手动调整",
+}
+
+func (r *PointRecord) Info() string {
+	return fmt.Sprintf("顾客 %d %s %d 积分", r.CustomerID, PointOperationTypeMap[r.OperationType], r.Change)
+}
+
+// BeforeCreate locks the customer and updates the points of the customer.
+// Redeeming and manual changes cannot make the points negative.
+func (r *PointRecord) BeforeCreate(tx *gorm.DB) error {
+	var customer Customer
+	if err := tx.Clauses(LockClause).Select("id", "points").Take(&customer, r.CustomerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCustomerNotFound
+		}
+		return err
+	}
+
+	r.Total = customer.Points + r.Change
+	if r.Total < 0 && r.OperationType != PointOperationTypeRefundReverse {
+		return ErrPointsNotEnough
+	}
+	return tx.Model(&customer).UpdateColumn("points", r.Total).Error
+}
+
+// PointsValue returns the amount in cents the points are worth when redeemed
+func PointsValue(points int) int {
+	return points * config.Config.LoyaltyPointValue
+}
+
+// PreparePayment checks the customer has enough points to redeem for a payment of the amount in cents,
+// returns the amount paid by points and the points earned by the rest
+func (c *Customer) PreparePayment(tx *gorm.DB, amount, redeemed int) (pointsPaid, earned int, err error) {
+	if err = tx.Preload("Tier").Take(c, c.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, 0, ErrCustomerNotFound
+		}
+		return
+	}
+	if redeemed > c.Points {
+		return 0, 0, ErrPointsNotEnough
+	}
+	pointsPaid = PointsValue(redeemed)
+	if pointsPaid > amount {
+		return 0, 0, ErrPointsExceedAmount
+	}
+	if c.Tier != nil {
+		earned = (amount - pointsPaid) * c.Tier.PointsPerYuan / 100
+	}
+	return
+}
+
+// SettlePayment records the points redeemed and earned by a payment of the customer, then updates the tier of the customer.
+// Refunds return the redeemed points and reverse the earned points with negative values.
+func (c *Customer) SettlePayment(tx *gorm.DB, userID, redeemed, earned int, redeemType, earnType PointOperationType, operationID int) error {
+	records := []PointRecord{
+		{CustomerID: c.ID, UserID: userID, Change: -redeemed, OperationType: redeemType, OperationID: operationID},
+		{CustomerID: c.ID, UserID: userID, Change: earned, OperationType: earnType, OperationID: operationID},
+	}
+	for i := range records {
+		if records[i].Change == 0 {
+			continue
+		}
+		if err := tx.Create(&records[i]).Error; err != nil {
+			return err
+		}
+	}
+	_, err := c.UpdateTier(tx, time.Now())
+	return err
+}
+
+// RollingSpend 近 12 个月的消费金额, 以分为单位, 已扣除退款
+func (c *Customer) RollingSpend(tx *gorm.DB, now time.Time) (spend int, err error) {
+	err = tx.Model(&Sale{}).
+		Select("COALESCE(SUM(price * (quantity - refunded_quantity)), 0)").
+		Where("customer_id = ? AND created_at > ?", c.ID, now.AddDate(-1, 0, 0)).
+		Scan(&spend).Error
+	return
+}
+
+// UpdateTier upgrades or downgrades the customer to the highest tier the rolling 12-month spend reaches,
+// returns whether the tier is changed
+func (c *Customer) UpdateTier(tx *gorm.DB, now time.Time) (bool, error) {
+	spend, err := c.RollingSpend(tx, now)
+	if err != nil {
+		return false, err
+	}
+
+	var tiers []MembershipTier
+	if err = tx.Where("min_spend <= ?", spend).Order("min_spend desc").Limit(1).Find(&tiers).Error; err != nil {
+		return false, err
+	}
+	var tierID *int
+	if len(tiers) > 0 {
+		tierID = &tiers[0].ID
+	}
+
+	if err = tx.Select("id", "tier_id").Take(c, c.ID).Error; err != nil {
+		return false, err
+	}
+	if tierID == nil && c.TierID == nil || tierID != nil && c.TierID != nil && *tierID == *c.TierID {
+		return false, nil
+	}
+	c.TierID = tierID
+	return true, tx.Model(c).UpdateColumn("tier_id", tierID).Error
+}
+
+// UpdateTiers updates the tiers of all customers, returns the number of customers whose tier is changed
+func UpdateTiers(db *gorm.DB, now time.Time) (updated int, err error) {
+	var customers []Customer
+	conn := db.Session(&gorm.Session{NewDB: true}) // without the conditions of the batches
+	err = db.Select("id").FindInBatches(&customers, 500, func(_ *gorm.DB, _ int) error {
+		for i := range customers {
+			changed, err := customers[i].UpdateTier(conn, now)
+			if err != nil {
+				return err
+			}
+			if changed {
+				updated++
+			}
+		}
+		return nil
+	}).Error
+	return
+}
+
+// StartTierScheduler updates the tiers of all customers at startup and then periodically in background,
+// so that customers are downgraded once the spend is out of the rolling 12 months
+func StartTierScheduler(interval time.Duration) {
+	update := func(now time.Time) {
+		updated, err := UpdateTiers(DB, now)
+		if err != nil {
+			utils.Logger.Error("update membership tiers error", zap.Error(err))
+		} else if updated > 0 {
+			utils.Logger.Info("membership tiers updated", zap.Int("count", updated))
+		}
+	}
+	go func() {
+		update(time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			update(now)
+		}
+	}()
+}
+
+// migrateMembershipTiers 创建默认会员等级, 并为旧版顾客计算一次等级.
+// 只执行一次, 管理员删除全部等级后不再重新创建
+func migrateMembershipTiers(db *gorm.DB) error {
+	return runMigrationOnce(db, "membership_tiers", func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&MembershipTier{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Create(&MembershipTier{Name: DefaultMembershipTierName, PointsPerYuan: 1}).Error; err != nil {
+				return err
+			}
+		}
+		_, err := UpdateTiers(tx.Where("tier_id IS NULL"), time.Now())
+		return err
+	})
+}
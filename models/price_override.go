@@ -0,0 +1,139 @@
+package models
+
+import (
+	"book_management_system_backend/config"
+	"book_management_system_backend/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var ErrPriceOverrideReasonRequired = utils.BadRequest("手动修改价格需要填写原因")
+var ErrPriceOverrideNeedsApproval = utils.Forbidden("折扣超过权限或低于成本价, 需要管理员授权")
+var ErrPriceOverrideApproverInvalid = utils.Forbidden("授权人无权批准该折扣")
+
+// PriceOverride 销售时手动改价的审计记录. 折扣不超过销售员角色的上限且不低于成本价时直接生效,
+// 否则需要另一位管理员授权, 且不超过授权后的上限
+type PriceOverride struct {
+	ID         int       `json:"id"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+	SaleID     int       `json:"sale_id" gorm:"not null;uniqueIndex"`
+	BookID     int       `json:"book_id" gorm:"not null;index"`
+	UserID     int       `json:"user_id" gorm:"not null;index"` // user who sold the book
+	User       *User     `json:"-"`
+	ApproverID *int      `json:"approver_id" gorm:"index"` // null if approval is not required
+	Approver   *User     `json:"-"`
+	ListPrice  *int      `json:"list_price"` // 以分为单位, the price of the book at the time, null if not set
+	Cost       *int      `json:"cost"`       // 以分为单位, price of the latest received purchase, null if never purchased
+	Price      int       `json:"price" gorm:"not null"`
+	Quantity   int       `json:"quantity" gorm:"not null"`
+	Reason     string    `json:"reason" gorm:"not null"`
+}
+
+func (o *PriceOverride) ListPriceFloat() *float64 {
+	if o.ListPrice == nil {
+		return nil
+	}
+	price := float64(*o.ListPrice) / 100
+	return &price
+}
+
+func (o *PriceOverride) CostFloat() *float64 {
+	if o.Cost == nil {
+		return nil
+	}
+	cost := float64(*o.Cost) / 100
+	return &cost
+}
+
+// BelowCost reports whether the book is sold below the price of the latest received purchase
+func (o *PriceOverride) BelowCost() bool {
+	return o.Cost != nil && o.Price < *o.Cost
+}
+
+func (o *PriceOverride) PriceFloat() float64 {
+	return float64(o.Price) / 100
+}
+
+// DiscountPercent returns the percent off the list price, 0 if the price is raised or the list price is not set
+func (o *PriceOverride) DiscountPercent() float64 {
+	if o.ListPrice == nil || *o.ListPrice == 0 || o.Price >= *o.ListPrice {
+		return 0
+	}
+	return float64(*o.ListPrice-o.Price) * 100 / float64(*o.ListPrice)
+}
+
+// MaxPriceDiscount returns the max percent the user can discount a sale by without approval
+func (user *User) MaxPriceDiscount() int {
+	if user.IsAdmin {
+		return config.Config.PriceOverrideAdminMaxDiscount
+	}
+	return config.Config.PriceOverrideStaffMaxDiscount
+}
+
+// BookCost returns the price of the latest received purchase of the book, nil if never received
+func BookCost(tx *gorm.DB, bookID int) (*int, error) {
+	var costs []int
+	err := tx.Model(&PurchaseItem{}).
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase_item.book_id = ? AND purchase_item.received_quantity > 0", bookID).
+		Where("purchase.status IN ?", []PurchaseStatus{PurchaseStatusArrived, PurchaseStatusClosed}).
+		Order("purchase.id DESC").Limit(1).
+		Pluck("purchase_item.price", &costs).Error
+	if err != nil || len(costs) == 0 {
+		return nil, err
+	}
+	return &costs[0], nil
+}
+
+// checkPriceOverride checks the price given manually against the list price and the cost, and fills s.PriceOverride to be created with the sale.
+// A reason is always required, and the approver is checked if the discount is above the limit of the user or the price is below cost.
+func (s *Sale) checkPriceOverride(tx *gorm.DB, listPrice *int) error {
+	if listPrice != nil && s.Price == *listPrice {
+		s.PriceOverride = nil // not overridden
+		return nil
+	}
+	override := s.PriceOverride
+	if override == nil || strings.TrimSpace(override.Reason) == "" {
+		return ErrPriceOverrideReasonRequired
+	}
+	override.BookID = s.BookID
+	override.UserID = s.UserID
+	override.ListPrice = listPrice
+	override.Price = s.Price
+	override.Quantity = s.Quantity
+	cost, err := BookCost(tx, s.BookID)
+	if err != nil {
+		return err
+	}
+	override.Cost = cost
+
+	var user User
+	if err := tx.Take(&user, s.UserID).Error; err != nil {
+		return err
+	}
+	discount := override.DiscountPercent()
+	if discount <= float64(user.MaxPriceDiscount()) && !override.BelowCost() {
+		override.ApproverID = nil
+		return nil
+	}
+
+	if override.ApproverID == nil {
+		return ErrPriceOverrideNeedsApproval
+	}
+	if *override.ApproverID == s.UserID {
+		return ErrPriceOverrideApproverInvalid // a second user is required
+	}
+	var approver User
+	if err := tx.Take(&approver, *override.ApproverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPriceOverrideApproverInvalid
+		}
+		return err
+	}
+	if !approver.IsAdmin || discount > float64(config.Config.PriceOverrideApprovedMaxDiscount) {
+		return ErrPriceOverrideApproverInvalid
+	}
+	return nil
+}
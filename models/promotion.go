@@ -0,0 +1,415 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var ErrPromotionNotFound = utils.NotFound("促销活动不存在")
+var ErrCouponNotFound = utils.NotFound("优惠券不存在")
+var ErrCouponCodeExists = utils.Conflict("该优惠码已存在")
+var ErrCouponUsedUp = utils.BadRequest("优惠券已达到使用次数上限")
+var ErrCouponNotApplicable = utils.BadRequest("优惠券不适用于该销售")
+var ErrCouponHasBeenUsed = utils.BadRequest("优惠券已被使用, 无法删除")
+var ErrPromotionPercentInvalid = utils.BadRequest("折扣比例应在 1 到 100 之间")
+var ErrPromotionAmountInvalid = utils.BadRequest("立减金额应大于 0")
+var ErrPromotionQuantityInvalid = utils.BadRequest("买赠数量应大于 0")
+var ErrPromotionDateRangeInvalid = utils.BadRequest("结束时间应晚于开始时间")
+
+type PromotionType = int
+
+const (
+	PromotionTypePercentage PromotionType = iota + 1 // Value percent off the price
+	PromotionTypeFixed                               // Value cents off the price of each book
+	PromotionTypeBuyGet                              // buy BuyQuantity get FreeQuantity free
+)
+
+var PromotionTypeMap = map[PromotionType]string{
+	PromotionTypePercentage: "折扣",
+	PromotionTypeFixed:      "立减",
+	PromotionTypeBuyGet:     "买赠",
+}
+
+// Promotion 促销活动, 在创建销售时自动计算, 不指定价格的销售才参与促销.
+// 适用范围为指定的书籍、分类 (含子分类) 和出版社之一, 都不指定则适用于所有书籍.
+// CouponOnly 的促销只在使用其优惠券时生效
+type Promotion struct {
+	ID           int              `json:"id"`
+	CreatedAt    time.Time        `json:"created_at" gorm:"not null"`
+	UpdatedAt    time.Time        `json:"updated_at" gorm:"not null"`
+	DeletedAt    gorm.DeletedAt   `json:"-" gorm:"index"` // soft deleted, still referenced by sales
+	Name         string           `json:"name" gorm:"size:128;not null"`
+	Type         PromotionType    `json:"type" gorm:"not null"`
+	Value        int              `json:"value" gorm:"not null;default:0"`         // percent for PromotionTypePercentage, cents for PromotionTypeFixed
+	BuyQuantity  int              `json:"buy_quantity" gorm:"not null;default:0"`  // N of buy N get M
+	FreeQuantity int              `json:"free_quantity" gorm:"not null;default:0"` // M of buy N get M
+	StartAt      *time.Time       `json:"start_at"`                                // null if started
+	EndAt        *time.Time       `json:"end_at"`                                  // exclusive, null if never ends
+	Enabled      bool             `json:"enabled" gorm:"not null"`
+	CouponOnly   bool             `json:"coupon_only" gorm:"not null"`
+	Books        []Book           `json:"-" gorm:"many2many:promotion_book"`
+	Categories   []Category       `json:"-" gorm:"many2many:promotion_category"`
+	Presses      []PromotionPress `json:"-"`
+}
+
+// PromotionPress 促销活动适用的出版社
+type PromotionPress struct {
+	PromotionID int    `json:"promotion_id" gorm:"primaryKey"`
+	Press       string `json:"press" gorm:"size:255;primaryKey"`
+}
+
+// ValueFloat returns the percent, or the amount in yuan of a fixed discount
+func (p *Promotion) ValueFloat() float64 {
+	if p.Type == PromotionTypeFixed {
+		return float64(p.Value) / 100
+	}
+	return float64(p.Value)
+}
+
+// Check checks the value and the date range of the promotion according to the type
+func (p *Promotion) Check() error {
+	switch p.Type {
+	case PromotionTypePercentage:
+		if p.Value < 1 || p.Value > 100 {
+			return ErrPromotionPercentInvalid
+		}
+	case PromotionTypeFixed:
+		if p.Value < 1 {
+			return ErrPromotionAmountInvalid
+		}
+	case PromotionTypeBuyGet:
+		if p.BuyQuantity < 1 || p.FreeQuantity < 1 {
+			return ErrPromotionQuantityInvalid
+		}
+	}
+	if p.StartAt != nil && p.EndAt != nil && !p.EndAt.After(*p.StartAt) {
+		return ErrPromotionDateRangeInvalid
+	}
+	return nil
+}
+
+func (p *Promotion) BookIDs() []int {
+	ids := make([]int, 0, len(p.Books))
+	for _, book := range p.Books {
+		ids = append(ids, book.ID)
+	}
+	return ids
+}
+
+func (p *Promotion) CategoryIDs() []int {
+	ids := make([]int, 0, len(p.Categories))
+	for _, category := range p.Categories {
+		ids = append(ids, category.ID)
+	}
+	return ids
+}
+
+func (p *Promotion) PressNames() []string {
+	names := make([]string, 0, len(p.Presses))
+	for _, press := range p.Presses {
+		names = append(names, press.Press)
+	}
+	return names
+}
+
+// SetScope replaces the books, categories and presses the promotion applies to
+func (p *Promotion) SetScope(tx *gorm.DB, bookIDs, categoryIDs []int, presses []string) error {
+	books := make([]Book, 0, len(bookIDs))
+	if len(bookIDs) > 0 {
+		if err := CheckBooksExist(tx, bookIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", bookIDs).Order("id").Find(&books).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(p).Omit("Books.*").Association("Books").Replace(books); err != nil {
+		return err
+	}
+
+	categories := make([]Category, 0, len(categoryIDs))
+	if len(categoryIDs) > 0 {
+		if err := tx.Where("id IN ?", categoryIDs).Order("path").Find(&categories).Error; err != nil {
+			return err
+		}
+		if len(categories) != len(categoryIDs) {
+			return ErrCategoryNotFound
+		}
+	}
+	if err := tx.Model(p).Omit("Categories.*").Association("Categories").Replace(categories); err != nil {
+		return err
+	}
+
+	if err := tx.Where("promotion_id = ?", p.ID).Delete(&PromotionPress{}).Error; err != nil {
+		return err
+	}
+	p.Presses = make([]PromotionPress, 0, len(presses))
+	for _, press := range presses {
+		p.Presses = append(p.Presses, PromotionPress{PromotionID: p.ID, Press: press})
+	}
+	if len(p.Presses) == 0 {
+		return nil
+	}
+	return tx.Create(&p.Presses).Error
+}
+
+// PreloadPromotionScope is a scope which preloads the books, categories and presses of promotions
+func PreloadPromotionScope(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Books", func(tx *gorm.DB) *gorm.DB { return tx.Select("id").Order("id") }).
+		Preload("Categories", func(tx *gorm.DB) *gorm.DB { return tx.Order("path") }).
+		Preload("Presses", func(tx *gorm.DB) *gorm.DB { return tx.Order("press") })
+}
+
+// ActiveAt reports whether the promotion is enabled and in its date range
+func (p *Promotion) ActiveAt(now time.Time) bool {
+	return p.Enabled && (p.StartAt == nil || !p.StartAt.After(now)) && (p.EndAt == nil || p.EndAt.After(now))
+}
+
+// Applies reports whether the book is in the scope of the promotion, the categories of the book should be loaded.
+// The books, categories and presses of the promotion should be preloaded, see PreloadPromotionScope.
+func (p *Promotion) Applies(book *Book) bool {
+	if len(p.Books) == 0 && len(p.Categories) == 0 && len(p.Presses) == 0 {
+		return true
+	}
+	for _, b := range p.Books {
+		if b.ID == book.ID {
+			return true
+		}
+	}
+	for _, category := range p.Categories {
+		for _, c := range book.Categories {
+			if strings.HasPrefix(c.Path, category.Path) {
+				return true
+			}
+		}
+	}
+	for _, press := range p.Presses {
+		if press.Press == book.Press {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount returns the discount in cents of quantity books at the unit price.
+// It is rounded down to whole cents per book, as a sale has one unit price.
+func (p *Promotion) Discount(price, quantity int) int {
+	var discount int
+	switch p.Type {
+	case PromotionTypePercentage:
+		discount = price * p.Value / 100 * quantity
+	case PromotionTypeFixed:
+		discount = p.Value * quantity
+	case PromotionTypeBuyGet:
+		if group := p.BuyQuantity + p.FreeQuantity; group > 0 {
+			discount = quantity / group * p.FreeQuantity * price
+		}
+	}
+	discount = discount / quantity * quantity
+	if discount > price*quantity {
+		return price * quantity
+	}
+	return discount
+}
+
+// CheckPromotionExists returns ErrPromotionNotFound if the promotion does not exist or is deleted
+func CheckPromotionExists(tx *gorm.DB, promotionID int) error {
+	err := tx.Select("id").Take(&Promotion{}, promotionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPromotionNotFound
+	}
+	return err
+}
+
+// ActivePromotions returns the promotions applied automatically at the time, with the scopes preloaded
+func ActivePromotions(tx *gorm.DB, now time.Time) (promotions []Promotion, err error) {
+	err = tx.Scopes(PreloadPromotionScope).
+		Where("enabled = ? AND coupon_only = ?", true, false).
+		Where("start_at IS NULL OR start_at <= ?", now).
+		Where("end_at IS NULL OR end_at > ?", now).
+		Order("id").Find(&promotions).Error
+	return
+}
+
+// Coupon 优惠码, 使用时应用其促销活动, 达到使用次数上限后不可使用. 一张小票使用一次
+type Coupon struct {
+	ID          int        `json:"id"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+	Code        string     `json:"code" gorm:"size:64;not null;uniqueIndex"` // upper case, see NormalizeCouponCode
+	PromotionID int        `json:"promotion_id" gorm:"not null;index"`
+	Promotion   *Promotion `json:"-"`
+	UsageLimit  *int       `json:"usage_limit" gorm:"check:usage_limit>=0"` // null if unlimited
+	UsedCount   int        `json:"used_count" gorm:"not null;default:0"`
+}
+
+// NormalizeCouponCode trims the code and converts it to upper case, so that codes are case-insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckUnique returns a conflict error if another coupon has the same code
+func (c *Coupon) CheckUnique(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&Coupon{}).Where("code = ? AND id <> ?", c.Code, c.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCouponCodeExists
+	}
+	return nil
+}
+
+// UseCoupon locks the coupon of the code and counts one use, the promotion of the coupon is preloaded.
+// The coupon should be applied to at least one sale, or the transaction should be rolled back.
+func UseCoupon(tx *gorm.DB, code string, now time.Time) (*Coupon, error) {
+	var coupon Coupon
+	err := tx.Clauses(LockClause).Where("code = ?", NormalizeCouponCode(code)).Take(&coupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	if coupon.UsageLimit != nil && coupon.UsedCount >= *coupon.UsageLimit {
+		return nil, ErrCouponUsedUp
+	}
+
+	var promotion Promotion
+	if err = tx.Scopes(PreloadPromotionScope).Take(&promotion, coupon.PromotionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotApplicable // promotion deleted
+		}
+		return nil, err
+	}
+	if !promotion.ActiveAt(now) {
+		return nil, ErrCouponNotApplicable
+	}
+	coupon.Promotion = &promotion
+
+	coupon.UsedCount++
+	return &coupon, tx.Model(&coupon).UpdateColumn("used_count", coupon.UsedCount).Error
+}
+
+// SalePromotion 销售应用的促销活动, 用于统计促销效果
+type SalePromotion struct {
+	ID          int        `json:"id"`
+	SaleID      int        `json:"sale_id" gorm:"not null;index"`
+	PromotionID int        `json:"promotion_id" gorm:"not null;index"`
+	Promotion   *Promotion `json:"-"`
+	CouponID    *int       `json:"coupon_id" gorm:"index"`   // null if applied automatically
+	Discount    int        `json:"discount" gorm:"not null"` // 以分为单位, of the whole sale
+}
+
+func (p *SalePromotion) DiscountFloat() float64 {
+	return float64(p.Discount) / 100
+}
+
+// applyPromotions applies the best automatic promotion of the book to the sale, then the coupon on the discounted price.
+// The unit price of the sale is the discounted one, and the discounts are recorded in s.Promotions.
+func (s *Sale) applyPromotions(tx *gorm.DB, book *Book, coupon *Coupon, now time.Time) error {
+	promotions, err := ActivePromotions(tx, now)
+	if err != nil {
+		return err
+	}
+	if err = tx.Model(book).Association("Categories").Find(&book.Categories); err != nil {
+		return err
+	}
+
+	var best *Promotion
+	var bestDiscount int
+	for i := range promotions {
+		if !promotions[i].Applies(book) {
+			continue
+		}
+		if discount := promotions[i].Discount(s.Price, s.Quantity); discount > bestDiscount {
+			best, bestDiscount = &promotions[i], discount
+		}
+	}
+	if best != nil {
+		s.applyDiscount(SalePromotion{PromotionID: best.ID, Discount: bestDiscount})
+	}
+
+	if coupon == nil {
+		return nil
+	}
+	if coupon.Promotion.Applies(book) {
+		if discount := coupon.Promotion.Discount(s.Price, s.Quantity); discount > 0 {
+			couponID := coupon.ID
+			s.applyDiscount(SalePromotion{PromotionID: coupon.PromotionID, CouponID: &couponID, Discount: discount})
+			return nil
+		}
+	}
+	if s.ReceiptID == nil {
+		return ErrCouponNotApplicable
+	}
+	return nil
+}
+
+func (s *Sale) applyDiscount(promotion SalePromotion) {
+	s.Price -= promotion.Discount / s.Quantity
+	s.Discount += promotion.Discount
+	s.Promotions = append(s.Promotions, promotion)
+}
+
+// PromotionSummary 促销活动的销售统计
+type PromotionSummary struct {
+	PromotionID      int
+	Promotion        *Promotion
+	SaleCount        int
+	Quantity         int
+	RefundedQuantity int
+	Discount         int // 以分为单位, of the whole sales including refunded books
+	Revenue          int // 以分为单位, refunds excluded
+}
+
+func (s *PromotionSummary) DiscountFloat() float64 {
+	return float64(s.Discount) / 100
+}
+
+func (s *PromotionSummary) RevenueFloat() float64 {
+	return float64(s.Revenue) / 100
+}
+
+// SummarizePromotions summarizes the sales of each promotion applied, ordered by promotion id
+func SummarizePromotions(tx *gorm.DB, startTime, endTime *time.Time) (summaries []PromotionSummary, err error) {
+	querySet := tx.Model(&SalePromotion{}).
+		Select("sale_promotion.promotion_id, COUNT(*) AS sale_count, " +
+			"SUM(sale.quantity) AS quantity, SUM(sale.refunded_quantity) AS refunded_quantity, " +
+			"SUM(sale_promotion.discount) AS discount, SUM(sale.price * (sale.quantity - sale.refunded_quantity)) AS revenue").
+		Joins("JOIN sale ON sale.id = sale_promotion.sale_id").
+		Group("sale_promotion.promotion_id").
+		Order("sale_promotion.promotion_id")
+	if startTime != nil {
+		querySet = querySet.Where("sale.created_at >= ?", *startTime)
+	}
+	if endTime != nil {
+		querySet = querySet.Where("sale.created_at <= ?", *endTime)
+	}
+	if err = querySet.Scan(&summaries).Error; err != nil {
+		return
+	}
+
+	promotionIDs := make([]int, 0, len(summaries))
+	for _, summary := range summaries {
+		promotionIDs = append(promotionIDs, summary.PromotionID)
+	}
+	var promotions []Promotion
+	if err = tx.Unscoped().Where("id IN ?", promotionIDs).Find(&promotions).Error; err != nil {
+		return
+	}
+	promotionIndex := make(map[int]int, len(promotions)) // promotion id -> index of promotions
+	for i, promotion := range promotions {
+		promotionIndex[promotion.ID] = i
+	}
+	for i := range summaries {
+		if j, ok := promotionIndex[summaries[i].PromotionID]; ok {
+			summaries[i].Promotion = &promotions[j]
+		}
+	}
+	return
+}
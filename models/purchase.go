@@ -1,22 +1,347 @@
 package models
 
-import "time"
+import (
+	"book_management_system_backend/utils"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
 
+type PurchaseStatus = string
+
+const (
+	PurchaseStatusDraft     PurchaseStatus = "draft"     // 已下单, 未付款
+	PurchaseStatusPaid      PurchaseStatus = "paid"      // 已付款, 未收货
+	PurchaseStatusArrived   PurchaseStatus = "arrived"   // 已收货
+	PurchaseStatusReturned  PurchaseStatus = "returned"  // 未付款退货
+	PurchaseStatusCancelled PurchaseStatus = "cancelled" // 未付款取消
+	PurchaseStatusRefunded  PurchaseStatus = "refunded"  // 已付款未收货, 供应商退款
+	PurchaseStatusClosed    PurchaseStatus = "closed"    // 部分收货后结单, 短缺部分由供应商退款
+)
+
+// PurchaseTransitions 采购单状态转移表, key 为当前状态, value 为可转移到的状态
+var PurchaseTransitions = map[PurchaseStatus][]PurchaseStatus{
+	PurchaseStatusDraft: {PurchaseStatusPaid, PurchaseStatusReturned, PurchaseStatusCancelled},
+	PurchaseStatusPaid:  {PurchaseStatusArrived, PurchaseStatusRefunded, PurchaseStatusClosed},
+}
+
+// Purchase 采购单, 一张采购单包含多条采购明细, 整单付款、退货、收货
 type Purchase struct {
-	ID        int       `json:"id"`
-	CreatedAt time.Time `json:"created_at" gorm:"not null"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
-	BookID    int       `json:"book_id" gorm:"not null"`
-	UserID    int       `json:"user_id" gorm:"not null"`
-	Book      *Book     `json:"-"`
-	User      *User     `json:"-"`
-	Quantity  int       `json:"quantity" gorm:"not null;check:quantity>=1"`
-	Price     int       `json:"price" gorm:"not null;check:price>=0"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
-	Paid      bool      `json:"paid" gorm:"default:false;not null"`
-	Arrived   bool      `json:"arrived" gorm:"default:false;not null"`  // 已付款状态下可收货
-	Returned  bool      `json:"returned" gorm:"default:false;not null"` // 未付款状态下可退货
-}
-
-func (p *Purchase) PriceFloat() float64 {
-	return float64(p.Price) / 100
+	ID          int                  `json:"id"`
+	CreatedAt   time.Time            `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time            `json:"updated_at" gorm:"not null"`
+	UserID      int                  `json:"user_id" gorm:"not null"`
+	User        *User                `json:"-"`
+	SupplierID  *int                 `json:"supplier_id" gorm:"index"`
+	Supplier    *Supplier            `json:"-"`
+	Items       []PurchaseItem       `json:"items"`
+	Arrivals    []PurchaseArrival    `json:"arrivals"`
+	Transitions []PurchaseTransition `json:"transitions"`
+	Status      PurchaseStatus       `json:"status" gorm:"size:16;default:draft;not null;index"`
+	PaidAt      *time.Time           `json:"paid_at"`
+	PaidBy      *int                 `json:"paid_by"`    // user id
+	ArrivedAt   *time.Time           `json:"arrived_at"` // 全部收货或结单的时间
+	ArrivedBy   *int                 `json:"arrived_by"`
+	ReturnedAt  *time.Time           `json:"returned_at"`
+	ReturnedBy  *int                 `json:"returned_by"`
+	CancelledAt *time.Time           `json:"cancelled_at"`
+	CancelledBy *int                 `json:"cancelled_by"`
+}
+
+// PurchaseTransition 采购单状态变更记录
+type PurchaseTransition struct {
+	ID         int            `json:"id"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"not null"`
+	PurchaseID int            `json:"purchase_id" gorm:"not null;index"`
+	UserID     int            `json:"user_id" gorm:"not null"`
+	User       *User          `json:"-"`
+	From       PurchaseStatus `json:"from" gorm:"size:16;not null"` // empty when created
+	To         PurchaseStatus `json:"to" gorm:"size:16;not null"`
+}
+
+func (p *Purchase) BeforeCreate(_ *gorm.DB) error {
+	if p.Status == "" {
+		p.Status = PurchaseStatusDraft
+	}
+	return nil
+}
+
+func (p *Purchase) AfterCreate(tx *gorm.DB) error {
+	return tx.Create(&PurchaseTransition{
+		PurchaseID: p.ID,
+		UserID:     p.UserID,
+		To:         p.Status,
+	}).Error
+}
+
+func (p *Purchase) CanTransitTo(status PurchaseStatus) bool {
+	for _, next := range PurchaseTransitions[p.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitTo changes the status of the purchase according to PurchaseTransitions,
+// records the time, the operator and the history. The purchase should be locked in the transaction.
+func (p *Purchase) TransitTo(tx *gorm.DB, status PurchaseStatus, userID int) error {
+	if !p.CanTransitTo(status) {
+		return utils.BadRequest(fmt.Sprintf("Cannot change purchase status from %s to %s", p.Status, status))
+	}
+
+	from := p.Status
+	now := time.Now()
+	updates := map[string]any{"status": status}
+	switch status {
+	case PurchaseStatusPaid:
+		p.PaidAt, p.PaidBy = &now, &userID
+		updates["paid_at"], updates["paid_by"] = now, userID
+	case PurchaseStatusArrived, PurchaseStatusClosed:
+		p.ArrivedAt, p.ArrivedBy = &now, &userID
+		updates["arrived_at"], updates["arrived_by"] = now, userID
+	case PurchaseStatusReturned:
+		p.ReturnedAt, p.ReturnedBy = &now, &userID
+		updates["returned_at"], updates["returned_by"] = now, userID
+	case PurchaseStatusCancelled:
+		p.CancelledAt, p.CancelledBy = &now, &userID
+		updates["cancelled_at"], updates["cancelled_by"] = now, userID
+	}
+	if err := tx.Model(p).Omit(clause.Associations).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	transition := PurchaseTransition{
+		PurchaseID: p.ID,
+		UserID:     userID,
+		From:       from,
+		To:         status,
+	}
+	if err := tx.Create(&transition).Error; err != nil {
+		return err
+	}
+	p.Status = status
+	p.Transitions = append(p.Transitions, transition)
+	return nil
+}
+
+// Total 采购单总价, 以分为单位
+func (p *Purchase) Total() int {
+	var total int
+	for _, item := range p.Items {
+		total += item.Price * item.Quantity
+	}
+	return total
+}
+
+func (p *Purchase) TotalFloat() float64 {
+	return float64(p.Total()) / 100
+}
+
+// PurchaseItem 采购明细, 一本书一行
+type PurchaseItem struct {
+	ID         int   `json:"id"`
+	PurchaseID int   `json:"purchase_id" gorm:"not null;index"`
+	BookID     int   `json:"book_id" gorm:"not null;index"`
+	Book       *Book `json:"-"`
+	Quantity   int   `json:"quantity" gorm:"not null;check:quantity>=1"`
+	Price      int   `json:"price" gorm:"not null;check:price>=0"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
+	// 已收货数量, 可分多次收货
+	ReceivedQuantity int `json:"received_quantity" gorm:"default:0;not null"`
+}
+
+func (i *PurchaseItem) PriceFloat() float64 {
+	return float64(i.Price) / 100
+}
+
+// OutstandingQuantity 未收货数量
+func (i *PurchaseItem) OutstandingQuantity() int {
+	return i.Quantity - i.ReceivedQuantity
+}
+
+// PurchaseArrival 一次收货记录, 一张采购单可以分多次收货
+type PurchaseArrival struct {
+	ID         int                   `json:"id"`
+	CreatedAt  time.Time             `json:"created_at" gorm:"not null"`
+	PurchaseID int                   `json:"purchase_id" gorm:"not null;index"`
+	UserID     int                   `json:"user_id" gorm:"not null"`
+	User       *User                 `json:"-"`
+	LocationID int                   `json:"location_id"` // location the items are received into
+	Location   *Location             `json:"-"`
+	Items      []PurchaseArrivalItem `json:"items" gorm:"foreignKey:ArrivalID"`
+}
+
+type PurchaseArrivalItem struct {
+	ID             int `json:"id"`
+	ArrivalID      int `json:"arrival_id" gorm:"not null;index"`
+	PurchaseItemID int `json:"purchase_item_id" gorm:"not null"`
+	BookID         int `json:"book_id" gorm:"not null"`
+	Quantity       int `json:"quantity" gorm:"not null;check:quantity>=1"`
+}
+
+// OutstandingQuantity 未收货总数量
+func (p *Purchase) OutstandingQuantity() int {
+	var quantity int
+	for _, item := range p.Items {
+		quantity += item.OutstandingQuantity()
+	}
+	return quantity
+}
+
+// OutstandingTotal 未收货部分的总价, 以分为单位
+func (p *Purchase) OutstandingTotal() int {
+	var total int
+	for _, item := range p.Items {
+		total += item.Price * item.OutstandingQuantity()
+	}
+	return total
+}
+
+func (p *Purchase) OutstandingTotalFloat() float64 {
+	return float64(p.OutstandingTotal()) / 100
+}
+
+// Receive records an arrival of a paid purchase and adds the received quantities to book stock at the location.
+// quantities maps book id to received quantity, nil means all outstanding items. locationID 0 means the default location.
+// The purchase will be marked as arrived once all items are received.
+// The purchase and its items should be loaded and locked in the transaction.
+func (p *Purchase) Receive(tx *gorm.DB, quantities map[int]int, locationID int, userID int) (err error) {
+	if p.Status != PurchaseStatusPaid {
+		return utils.BadRequest("Cannot receive a " + p.Status + " purchase")
+	}
+	if locationID, err = ResolveLocation(tx, locationID); err != nil {
+		return err
+	}
+
+	if quantities == nil {
+		quantities = make(map[int]int, len(p.Items))
+		for _, item := range p.Items {
+			if item.OutstandingQuantity() > 0 {
+				quantities[item.BookID] = item.OutstandingQuantity()
+			}
+		}
+	}
+
+	itemIndex := make(map[int]int, len(p.Items)) // book id -> index of item
+	for i, item := range p.Items {
+		itemIndex[item.BookID] = i
+	}
+	for bookID := range quantities {
+		if _, ok := itemIndex[bookID]; !ok {
+			return utils.BadRequest(fmt.Sprintf("Book %d is not in the purchase", bookID))
+		}
+	}
+
+	arrival := PurchaseArrival{PurchaseID: p.ID, UserID: userID, LocationID: locationID}
+	for i := range p.Items {
+		item := &p.Items[i]
+		quantity, ok := quantities[item.BookID]
+		if !ok || quantity == 0 {
+			continue
+		}
+		if quantity > item.OutstandingQuantity() {
+			return utils.BadRequest(fmt.Sprintf("Received quantity of book %d exceeds outstanding quantity %d", item.BookID, item.OutstandingQuantity()))
+		}
+
+		item.ReceivedQuantity += quantity
+		err = tx.Model(item).Update("received_quantity", item.ReceivedQuantity).Error
+		if err != nil {
+			return err
+		}
+
+		arrival.Items = append(arrival.Items, PurchaseArrivalItem{
+			PurchaseItemID: item.ID,
+			BookID:         item.BookID,
+			Quantity:       quantity,
+		})
+	}
+	if len(arrival.Items) == 0 {
+		return utils.BadRequest("Nothing to receive")
+	}
+
+	if err = tx.Create(&arrival).Error; err != nil {
+		return err
+	}
+	p.Arrivals = append(p.Arrivals, arrival)
+
+	// update book stock
+	for _, item := range arrival.Items {
+		err = ChangeStock(tx, &StockMovement{
+			BookID:      item.BookID,
+			LocationID:  locationID,
+			UserID:      userID,
+			Type:        StockMovementTypeArrival,
+			Change:      item.Quantity,
+			OperationID: &arrival.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.OutstandingQuantity() == 0 {
+		return p.TransitTo(tx, PurchaseStatusArrived, userID)
+	}
+	return nil
+}
+
+// CloseShort closes a partially received purchase, the supplier credits the missing units.
+// The purchase and its items should be loaded and locked in the transaction.
+func (p *Purchase) CloseShort(tx *gorm.DB, userID int) (err error) {
+	credit := p.OutstandingTotal()
+	if err = p.TransitTo(tx, PurchaseStatusClosed, userID); err != nil {
+		return err
+	}
+	if credit == 0 {
+		return nil
+	}
+
+	payment, err := p.payment(tx)
+	if err != nil {
+		return err
+	}
+
+	balance := Balance{
+		UserID:        userID,
+		Change:        credit,
+		OperationType: OperationTypeSupplierCredit,
+		OperationID:   p.ID,
+		RelatedID:     &payment.ID,
+	}
+	return tx.Create(&balance).Error
+}
+
+// Refund cancels a paid purchase before any item arrives, the supplier refunds the whole payment.
+// The purchase and its items should be loaded and locked in the transaction.
+func (p *Purchase) Refund(tx *gorm.DB, userID int) (err error) {
+	for _, item := range p.Items {
+		if item.ReceivedQuantity > 0 {
+			return utils.BadRequest("Cannot refund a partially arrived purchase, close it instead")
+		}
+	}
+
+	if err = p.TransitTo(tx, PurchaseStatusRefunded, userID); err != nil {
+		return err
+	}
+
+	payment, err := p.payment(tx)
+	if err != nil {
+		return err
+	}
+
+	balance := Balance{
+		UserID:        userID,
+		Change:        -payment.Change,
+		OperationType: OperationTypePurchaseRefund,
+		OperationID:   p.ID,
+		RelatedID:     &payment.ID,
+	}
+	return tx.Create(&balance).Error
+}
+
+// payment finds the balance created when the purchase was paid
+func (p *Purchase) payment(tx *gorm.DB) (balance Balance, err error) {
+	err = tx.Where("operation_type = ? AND operation_id = ?", OperationTypePurchase, p.ID).Last(&balance).Error
+	return
 }
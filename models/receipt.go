@@ -0,0 +1,115 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Receipt 销售小票, 一次结账包含多条销售记录, 整单只产生一条流水
+type Receipt struct {
+	ID         int       `json:"id"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"not null"`
+	UserID     int       `json:"user_id" gorm:"not null"`
+	User       *User     `json:"-"`
+	CustomerID *int      `json:"customer_id" gorm:"index"` // customer of all the sales, null if unknown
+	Customer   *Customer `json:"-"`
+	Sales      []Sale    `json:"sales"`
+	Total      int       `json:"total" gorm:"default:0;not null"` // 总价, 用 int 表示以分为单位，避免浮点数精度问题
+	// 积分抵扣和累积
+	PointsRedeemed int `json:"points_redeemed" gorm:"default:0;not null"` // 抵扣的积分
+	PointsPaid     int `json:"points_paid" gorm:"default:0;not null"`     // 积分抵扣的金额, 以分为单位
+	PointsEarned   int `json:"points_earned" gorm:"default:0;not null"`   // 累积的积分
+	// coupon to use for all the sales, counted as one use
+	CouponCode *string `json:"-" gorm:"-"`
+	// user who approves the price overrides of the sales
+	ApproverID *int `json:"-" gorm:"-"`
+}
+
+func (r *Receipt) TotalFloat() float64 {
+	return float64(r.Total) / 100
+}
+
+func (r *Receipt) PointsPaidFloat() float64 {
+	return float64(r.PointsPaid) / 100
+}
+
+// Checkout creates the receipt and all of its sales, should be called in a transaction.
+// Stock of each book is checked and updated in Sale hooks, and one balance is created for the whole receipt.
+// Points of the customer are redeemed and earned for the whole receipt.
+// The coupon is applied to each sale it applies to, and it should apply to at least one.
+func (r *Receipt) Checkout(tx *gorm.DB) (err error) {
+	if r.CustomerID != nil {
+		if err = CheckCustomerExists(tx, *r.CustomerID); err != nil {
+			return
+		}
+	} else if r.PointsRedeemed > 0 {
+		return ErrPointsWithoutCustomer
+	}
+	var coupon *Coupon
+	if r.CouponCode != nil {
+		if coupon, err = UseCoupon(tx, *r.CouponCode, time.Now()); err != nil {
+			return
+		}
+	}
+	if err = tx.Omit("Sales").Create(r).Error; err != nil {
+		return
+	}
+
+	r.Total = 0
+	couponApplied := false
+	for i := range r.Sales {
+		r.Sales[i].UserID = r.UserID
+		r.Sales[i].ReceiptID = &r.ID
+		r.Sales[i].CustomerID = r.CustomerID
+		r.Sales[i].PointsRedeemed = 0
+		r.Sales[i].CouponCode = nil
+		r.Sales[i].coupon = coupon
+		if r.Sales[i].PriceOverride != nil {
+			r.Sales[i].PriceOverride.ApproverID = r.ApproverID
+		}
+		if err = tx.Create(&r.Sales[i]).Error; err != nil {
+			return
+		}
+		r.Total += r.Sales[i].Price * r.Sales[i].Quantity
+		for _, promotion := range r.Sales[i].Promotions {
+			couponApplied = couponApplied || promotion.CouponID != nil
+		}
+	}
+	if coupon != nil && !couponApplied {
+		return ErrCouponNotApplicable
+	}
+
+	var customer Customer
+	if r.CustomerID != nil {
+		customer.ID = *r.CustomerID
+		if r.PointsPaid, r.PointsEarned, err = customer.PreparePayment(tx, r.Total, r.PointsRedeemed); err != nil {
+			return
+		}
+	}
+	err = tx.Model(r).Omit(clause.Associations).Updates(map[string]any{
+		"total":         r.Total,
+		"points_paid":   r.PointsPaid,
+		"points_earned": r.PointsEarned,
+	}).Error
+	if err != nil {
+		return
+	}
+
+	// the amount paid by points is not received
+	var balance = &Balance{
+		UserID:        r.UserID,
+		Change:        r.Total - r.PointsPaid,
+		OperationType: OperationTypeReceipt,
+		OperationID:   r.ID,
+	}
+	if err = tx.Create(balance).Error; err != nil {
+		return
+	}
+
+	if r.CustomerID == nil {
+		return
+	}
+	return customer.SettlePayment(tx, r.UserID, r.PointsRedeemed, r.PointsEarned, PointOperationTypeReceiptRedeem, PointOperationTypeReceipt, r.ID)
+}
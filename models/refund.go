@@ -0,0 +1,182 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrSaleNotFound = utils.NotFound("销售记录不存在")
+var ErrRefundExceeded = utils.BadRequest("退款数量超过可退数量")
+var ErrRefundBookArchived = utils.BadRequest("书籍已归档, 恢复后才能退款")
+
+// SaleRefund 销售退款, 一条销售记录可以分多次部分退款
+type SaleRefund struct {
+	ID         int       `json:"id"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+	SaleID     int       `json:"sale_id" gorm:"not null;index"`
+	BookID     int       `json:"book_id" gorm:"not null"`
+	UserID     int       `json:"user_id" gorm:"not null"`
+	LocationID int       `json:"location_id"` // location the books are returned to, the location of the sale if 0 when created
+	Sale       *Sale     `json:"-"`
+	Book       *Book     `json:"-"`
+	User       *User     `json:"-"`
+	Location   *Location `json:"-"`
+	Quantity   int       `json:"quantity" gorm:"not null;check:quantity>=1"`
+	Price      int       `json:"price" gorm:"not null;check:price>=0"` // 退款单价, 与销售单价一致, 用 int 表示以分为单位
+	Reason     *string   `json:"reason"`
+	// 积分抵扣的部分退还为积分, 其余部分退款, 并按退款金额扣回累积的积分
+	PointsPaid     int `json:"points_paid" gorm:"default:0;not null"`     // 退还为积分的金额, 以分为单位
+	PointsReturned int `json:"points_returned" gorm:"default:0;not null"` // 退还的积分
+	PointsReversed int `json:"points_reversed" gorm:"default:0;not null"` // 扣回的积分
+}
+
+func (r *SaleRefund) PriceFloat() float64 {
+	return float64(r.Price) / 100
+}
+
+func (r *SaleRefund) AmountFloat() float64 {
+	return float64(r.Price*r.Quantity) / 100
+}
+
+func (r *SaleRefund) PointsPaidFloat() float64 {
+	return float64(r.PointsPaid) / 100
+}
+
+// CashFloat 退款金额中以现金退还的部分
+func (r *SaleRefund) CashFloat() float64 {
+	return float64(r.Price*r.Quantity-r.PointsPaid) / 100
+}
+
+func (r *SaleRefund) BeforeCreate(tx *gorm.DB) (err error) {
+	var sale Sale
+	// lock the sale to prevent concurrent refunds exceeding the sold quantity
+	if err = tx.Clauses(LockClause).Take(&sale, r.SaleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSaleNotFound
+		} else {
+			return
+		}
+	}
+
+	// refund all remaining quantity if not set
+	if r.Quantity == 0 {
+		r.Quantity = sale.Quantity - sale.RefundedQuantity
+	}
+	if r.Quantity <= 0 || sale.RefundedQuantity+r.Quantity > sale.Quantity {
+		return ErrRefundExceeded
+	}
+	// refunded books return to stock, which archived books must not have, see Book.Archive
+	var book Book
+	if err = tx.Unscoped().Select("id", "deleted_at").Take(&book, sale.BookID).Error; err != nil {
+		return
+	}
+	if book.DeletedAt.Valid {
+		return ErrRefundBookArchived
+	}
+	r.Price = sale.Price
+	r.BookID = sale.BookID
+	if r.LocationID == 0 {
+		r.LocationID = sale.LocationID
+	}
+	if r.LocationID, err = ResolveLocation(tx, r.LocationID); err != nil {
+		return
+	}
+	if sale.CustomerID == nil {
+		return
+	}
+	// points of deleted customers are not returned
+	if err = CheckCustomerExists(tx, *sale.CustomerID); errors.Is(err, ErrCustomerNotFound) {
+		return nil
+	} else if err != nil {
+		return
+	}
+	return r.preparePoints(tx, &sale)
+}
+
+// preparePoints returns the amount paid by points first, and reverses the points earned by the rest in proportion.
+// Points of a sale in a receipt are settled with the receipt.
+func (r *SaleRefund) preparePoints(tx *gorm.DB, sale *Sale) error {
+	payment := struct {
+		Total          int
+		PointsRedeemed int
+		PointsPaid     int
+		PointsEarned   int
+	}{sale.Price * sale.Quantity, sale.PointsRedeemed, sale.PointsPaid, sale.PointsEarned}
+	refunded := tx.Model(&SaleRefund{})
+	if sale.ReceiptID != nil {
+		var receipt Receipt
+		if err := tx.Clauses(LockClause).Take(&receipt, *sale.ReceiptID).Error; err != nil {
+			return err
+		}
+		payment.Total, payment.PointsRedeemed, payment.PointsPaid, payment.PointsEarned = receipt.Total, receipt.PointsRedeemed, receipt.PointsPaid, receipt.PointsEarned
+		refunded = refunded.Where("sale_id IN (?)", tx.Model(&Sale{}).Select("id").Where("receipt_id = ?", receipt.ID))
+	} else {
+		refunded = refunded.Where("sale_id = ?", sale.ID)
+	}
+	if payment.PointsPaid == 0 && payment.PointsEarned == 0 {
+		return nil
+	}
+
+	var pointsPaidReturned int
+	if err := refunded.Select("COALESCE(SUM(points_paid), 0)").Scan(&pointsPaidReturned).Error; err != nil {
+		return err
+	}
+	amount := r.Price * r.Quantity
+	r.PointsPaid = payment.PointsPaid - pointsPaidReturned
+	if r.PointsPaid > amount {
+		r.PointsPaid = amount
+	}
+	if payment.PointsPaid > 0 {
+		r.PointsReturned = r.PointsPaid * payment.PointsRedeemed / payment.PointsPaid
+	}
+	if cash := payment.Total - payment.PointsPaid; cash > 0 {
+		r.PointsReversed = (amount - r.PointsPaid) * payment.PointsEarned / cash
+	}
+	return nil
+}
+
+func (r *SaleRefund) AfterCreate(tx *gorm.DB) (err error) {
+	// Update refunded quantity of the sale
+	err = tx.Model(&Sale{ID: r.SaleID}).Update("refunded_quantity", gorm.Expr("refunded_quantity + ?", r.Quantity)).Error
+	if err != nil {
+		return
+	}
+	// Restore book stock
+	err = ChangeStock(tx, &StockMovement{
+		BookID:      r.BookID,
+		LocationID:  r.LocationID,
+		UserID:      r.UserID,
+		Type:        StockMovementTypeRefund,
+		Change:      r.Quantity,
+		OperationID: &r.ID,
+		Reason:      r.Reason,
+	})
+	if err != nil {
+		return
+	}
+	// Create balance, the amount paid by points is returned as points
+	var balance = &Balance{
+		UserID:        r.UserID,
+		Change:        -(r.Price*r.Quantity - r.PointsPaid),
+		OperationType: OperationTypeRefund,
+		OperationID:   r.ID,
+		Reason:        r.Reason,
+	}
+	if err = tx.Create(balance).Error; err != nil {
+		return
+	}
+
+	var sale Sale
+	if err = tx.Select("id", "customer_id").Take(&sale, r.SaleID).Error; err != nil || sale.CustomerID == nil {
+		return
+	}
+	if err = CheckCustomerExists(tx, *sale.CustomerID); errors.Is(err, ErrCustomerNotFound) {
+		return nil
+	} else if err != nil {
+		return
+	}
+	customer := Customer{ID: *sale.CustomerID}
+	return customer.SettlePayment(tx, r.UserID, -r.PointsReturned, -r.PointsReversed, PointOperationTypeRefundReturn, PointOperationTypeRefundReverse, r.ID)
+}
@@ -0,0 +1,306 @@
+package models
+
+import (
+	"book_management_system_backend/config"
+	"book_management_system_backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"time"
+)
+
+var ErrNothingToReorder = utils.BadRequest("没有需要补货的书籍")
+var ErrReorderPriceUnknown = utils.BadRequest("书籍从未采购过, 没有采购价格, 请手动创建采购单")
+
+// ReorderSuggestion 补货建议, 库存与在途数量之和不高于补货点的在售书籍. 由 EvaluateReorders 定期重新计算
+type ReorderSuggestion struct {
+	BookID       int       `json:"book_id" gorm:"primaryKey"`
+	Book         *Book     `json:"-"`
+	SupplierID   *int      `json:"supplier_id" gorm:"index"` // supplier of the latest purchase of the book, null if unknown
+	Supplier     *Supplier `json:"-"`
+	Stock        int       `json:"stock" gorm:"not null"`         // total stock when evaluated
+	OnOrder      int       `json:"on_order" gorm:"not null"`      // outstanding quantity of draft and paid purchases
+	DailySales   float64   `json:"daily_sales" gorm:"not null"`   // average daily sales in recent days, refunds excluded
+	ReorderPoint int       `json:"reorder_point" gorm:"not null"` // reorder point of the book, or sales during the lead time if not set
+	Quantity     int       `json:"quantity" gorm:"not null"`      // suggested quantity to purchase
+	Price        *int      `json:"price"`                         // price of the latest purchase, null if never purchased
+	EvaluatedAt  time.Time `json:"evaluated_at" gorm:"not null"`
+}
+
+func (s *ReorderSuggestion) PriceFloat() *float64 {
+	if s.Price == nil {
+		return nil
+	}
+	price := float64(*s.Price) / 100
+	return &price
+}
+
+// EvaluateReorders evaluates the stock of books on sale against their reorder points and recent sales velocity,
+// and replaces all reorder suggestions. Returns the number of suggestions and the books newly need reordering.
+//
+// The reorder point of a book defaults to the sales during the lead time of its supplier,
+// and the reorder quantity defaults to the sales in config.Config.ReorderSalesDays days.
+// Books without reorder point and without recent sales are never suggested.
+func EvaluateReorders(db *gorm.DB, now time.Time) (total int, added []int, err error) {
+	salesDays := config.Config.ReorderSalesDays
+
+	var sold []struct {
+		BookID   int
+		Quantity int
+	}
+	err = db.Model(&Sale{}).Select("book_id, SUM(quantity - refunded_quantity) AS quantity").
+		Where("created_at >= ?", now.AddDate(0, 0, -salesDays)).
+		Group("book_id").Having("SUM(quantity - refunded_quantity) > 0").
+		Scan(&sold).Error
+	if err != nil {
+		return
+	}
+	dailySales := make(map[int]float64, len(sold)) // book id -> average daily sales
+	for _, row := range sold {
+		dailySales[row.BookID] = float64(row.Quantity) / float64(salesDays)
+	}
+
+	var onOrder []struct {
+		BookID   int
+		Quantity int
+	}
+	err = db.Model(&PurchaseItem{}).Select("purchase_item.book_id, SUM(purchase_item.quantity - purchase_item.received_quantity) AS quantity").
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase.status IN ?", []PurchaseStatus{PurchaseStatusDraft, PurchaseStatusPaid}).
+		Group("purchase_item.book_id").
+		Scan(&onOrder).Error
+	if err != nil {
+		return
+	}
+	outstanding := make(map[int]int, len(onOrder)) // book id -> quantity on order
+	for _, row := range onOrder {
+		outstanding[row.BookID] = row.Quantity
+	}
+
+	bookIDs := make([]int, 0, len(dailySales))
+	for bookID := range dailySales {
+		bookIDs = append(bookIDs, bookID)
+	}
+	var books []Book
+	err = db.Select("id", "stock", "reorder_point", "reorder_quantity").
+		Where("on_sale = ? AND (reorder_point IS NOT NULL OR id IN ?)", true, bookIDs).
+		Order("id").Find(&books).Error
+	if err != nil {
+		return
+	}
+
+	latest, err := latestPurchases(db, books)
+	if err != nil {
+		return
+	}
+
+	suggestions := make([]ReorderSuggestion, 0)
+	for _, book := range books {
+		daily := dailySales[book.ID]
+		suggestion := latest[book.ID]
+		suggestion.BookID = book.ID
+		suggestion.Stock = book.Stock
+		suggestion.OnOrder = outstanding[book.ID]
+		suggestion.DailySales = daily
+		suggestion.EvaluatedAt = now
+
+		leadTime := config.Config.ReorderLeadTime
+		if suggestion.Supplier != nil && suggestion.Supplier.LeadTime > 0 {
+			leadTime = suggestion.Supplier.LeadTime
+		}
+		if book.ReorderPoint != nil {
+			suggestion.ReorderPoint = *book.ReorderPoint
+		} else {
+			suggestion.ReorderPoint = int(math.Ceil(daily * float64(leadTime)))
+		}
+		available := suggestion.Stock + suggestion.OnOrder
+		if available > suggestion.ReorderPoint {
+			continue
+		}
+
+		if book.ReorderQuantity != nil {
+			suggestion.Quantity = *book.ReorderQuantity
+		} else {
+			suggestion.Quantity = int(math.Ceil(daily * float64(salesDays)))
+		}
+		// at least enough to bring the stock above the reorder point
+		if suggestion.Quantity <= suggestion.ReorderPoint-available {
+			suggestion.Quantity = suggestion.ReorderPoint - available + 1
+		}
+		suggestion.Supplier = nil
+		suggestions = append(suggestions, suggestion)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var previous []int
+		if err := tx.Model(&ReorderSuggestion{}).Pluck("book_id", &previous).Error; err != nil {
+			return err
+		}
+		suggested := make(map[int]bool, len(previous))
+		for _, bookID := range previous {
+			suggested[bookID] = true
+		}
+		for _, suggestion := range suggestions {
+			if !suggested[suggestion.BookID] {
+				added = append(added, suggestion.BookID)
+			}
+		}
+
+		if err := tx.Where("1 = 1").Delete(&ReorderSuggestion{}).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&suggestions, 500).Error
+	})
+	return len(suggestions), added, err
+}
+
+// latestPurchases returns the supplier and the price of the latest purchases of the books, keyed by book id.
+// The supplier is the one of the latest purchase with a supplier, null if it is deleted.
+func latestPurchases(db *gorm.DB, books []Book) (map[int]ReorderSuggestion, error) {
+	bookIDs := make([]int, 0, len(books))
+	for _, book := range books {
+		bookIDs = append(bookIDs, book.ID)
+	}
+
+	var rows []struct {
+		BookID     int
+		SupplierID *int
+		Price      int
+	}
+	err := db.Model(&PurchaseItem{}).Select("purchase_item.book_id, purchase.supplier_id, purchase_item.price").
+		Joins("JOIN purchase ON purchase.id = purchase_item.purchase_id").
+		Where("purchase_item.book_id IN ?", bookIDs).
+		Order("purchase.id DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[int]ReorderSuggestion, len(books))
+	supplierIDs := make([]int, 0)
+	for _, row := range rows {
+		suggestion, ok := latest[row.BookID]
+		if !ok {
+			price := row.Price
+			suggestion.Price = &price
+		}
+		if suggestion.SupplierID == nil && row.SupplierID != nil {
+			suggestion.SupplierID = row.SupplierID
+			supplierIDs = append(supplierIDs, *row.SupplierID)
+		}
+		latest[row.BookID] = suggestion
+	}
+
+	var suppliers []Supplier
+	if err = db.Where("id IN ?", supplierIDs).Find(&suppliers).Error; err != nil {
+		return nil, err
+	}
+	supplierIndex := make(map[int]int, len(suppliers)) // supplier id -> index of supplier
+	for i, supplier := range suppliers {
+		supplierIndex[supplier.ID] = i
+	}
+	for bookID, suggestion := range latest {
+		if suggestion.SupplierID == nil {
+			continue
+		}
+		if i, ok := supplierIndex[*suggestion.SupplierID]; ok {
+			suggestion.Supplier = &suppliers[i]
+		} else {
+			suggestion.SupplierID = nil // deleted supplier is unknown
+		}
+		latest[bookID] = suggestion
+	}
+	return latest, nil
+}
+
+// DraftReorderPurchases creates draft purchases from the reorder suggestions of the books, one purchase for each supplier
+// ordered by supplier id, and the last one for the books whose supplier is unknown. bookIDs nil means all suggestions.
+// Drafted suggestions are removed as the quantities are on order now.
+// Books never purchased are skipped and kept in the suggestions, as there is no price to draft with.
+func DraftReorderPurchases(tx *gorm.DB, bookIDs []int, userID int) ([]Purchase, error) {
+	querySet := tx.Clauses(LockClause).
+		Where("book_id IN (?)", tx.Model(&Book{}).Select("id")). // books archived after evaluated
+		Order("book_id")
+	if bookIDs != nil {
+		querySet = querySet.Where("book_id IN ?", bookIDs)
+	}
+	var suggestions []ReorderSuggestion
+	if err := querySet.Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, ErrNothingToReorder
+	}
+
+	groups := make(map[int][]PurchaseItem) // supplier id, 0 if unknown -> items
+	drafted := make([]int, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if suggestion.Price == nil {
+			continue
+		}
+		var supplierID int
+		if suggestion.SupplierID != nil {
+			supplierID = *suggestion.SupplierID
+		}
+		groups[supplierID] = append(groups[supplierID], PurchaseItem{
+			BookID:   suggestion.BookID,
+			Quantity: suggestion.Quantity,
+			Price:    *suggestion.Price,
+		})
+		drafted = append(drafted, suggestion.BookID)
+	}
+	if len(drafted) == 0 {
+		return nil, ErrReorderPriceUnknown
+	}
+	supplierIDs := make([]int, 0, len(groups))
+	for supplierID := range groups {
+		supplierIDs = append(supplierIDs, supplierID)
+	}
+	sort.Slice(supplierIDs, func(i, j int) bool {
+		if supplierIDs[i] == 0 || supplierIDs[j] == 0 {
+			return supplierIDs[j] == 0 // unknown supplier last
+		}
+		return supplierIDs[i] < supplierIDs[j]
+	})
+
+	purchases := make([]Purchase, 0, len(groups))
+	for _, supplierID := range supplierIDs {
+		purchase := Purchase{UserID: userID, Items: groups[supplierID]}
+		if supplierID != 0 {
+			supplierID := supplierID
+			purchase.SupplierID = &supplierID
+		}
+		if err := tx.Create(&purchase).Error; err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, purchase)
+	}
+
+	err := tx.Where("book_id IN ?", drafted).Delete(&ReorderSuggestion{}).Error
+	return purchases, err
+}
+
+// StartReorderScheduler evaluates the reorder suggestions at startup and then periodically in background,
+// and warns of the books newly need reordering
+func StartReorderScheduler(interval time.Duration) {
+	evaluate := func(now time.Time) {
+		total, added, err := EvaluateReorders(DB, now)
+		if err != nil {
+			utils.Logger.Error("evaluate reorders error", zap.Error(err))
+		} else if len(added) > 0 {
+			utils.Logger.Warn("books need reordering", zap.Ints("book_ids", added), zap.Int("total", total))
+		}
+	}
+	go func() {
+		evaluate(time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			evaluate(now)
+		}
+	}()
+}
@@ -13,21 +13,47 @@ var ErrBookNotFound = utils.NotFound("书籍不存在")
 var ErrBookPriceNotSet = utils.BadRequest("书籍价格未设置")
 
 type Sale struct {
-	ID        int       `json:"id"`
-	CreatedAt time.Time `json:"created_at" gorm:"not null"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
-	BookID    int       `json:"book_id" gorm:"not null"`
-	UserID    int       `json:"user_id" gorm:"not null"`
-	Book      *Book     `json:"-"`
-	User      *User     `json:"-"`
-	Quantity  int       `json:"quantity" gorm:"not null;check:quantity>=1"`
-	Price     int       `json:"price" gorm:"not null;check:price>=0"` // 单价, 用 int 表示以分为单位，避免浮点数精度问题
+	ID               int       `json:"id"`
+	CreatedAt        time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"not null"`
+	BookID           int       `json:"book_id" gorm:"not null"`
+	UserID           int       `json:"user_id" gorm:"not null"`
+	ReceiptID        *int      `json:"receipt_id" gorm:"index"`  // null if not sold in a receipt
+	LocationID       int       `json:"location_id" gorm:"index"` // location sold from, 0 for the default location when created
+	CustomerID       *int      `json:"customer_id" gorm:"index"` // null if the customer is unknown
+	Book             *Book     `json:"-"`
+	User             *User     `json:"-"`
+	Receipt          *Receipt  `json:"-"`
+	Location         *Location `json:"-"`
+	Customer         *Customer `json:"-"`
+	Quantity         int       `json:"quantity" gorm:"not null;check:quantity>=1"`
+	Price            int       `json:"price" gorm:"not null;check:price>=0"`        // 单价, 用 int 表示以分为单位，避免浮点数精度问题, 已扣除促销折扣
+	RefundedQuantity int       `json:"refunded_quantity" gorm:"default:0;not null"` // 已退款数量
+	// 促销折扣, 以分为单位, of the whole sale. the list price is Price + Discount / Quantity
+	Discount   int             `json:"discount" gorm:"default:0;not null"`
+	Promotions []SalePromotion `json:"promotions"`
+	CouponCode *string         `json:"-" gorm:"-"` // coupon to use, ignored in a receipt
+	coupon     *Coupon         // coupon of the receipt
+	// audit record if the price is given manually, set the reason and the approver to create the sale
+	PriceOverride *PriceOverride `json:"-"`
+	// 积分抵扣和累积, 只用于不属于小票的销售, 小票的积分见 Receipt
+	PointsRedeemed int `json:"points_redeemed" gorm:"default:0;not null"` // 抵扣的积分
+	PointsPaid     int `json:"points_paid" gorm:"default:0;not null"`     // 积分抵扣的金额, 以分为单位
+	PointsEarned   int `json:"points_earned" gorm:"default:0;not null"`   // 累积的积分
 }
 
 func (s *Sale) PriceFloat() float64 {
 	return float64(s.Price) / 100
 }
 
+func (s *Sale) DiscountFloat() float64 {
+	return float64(s.Discount) / 100
+}
+
+func (s *Sale) PointsPaidFloat() float64 {
+	return float64(s.PointsPaid) / 100
+}
+
 func (s *Sale) BeforeCreate(tx *gorm.DB) (err error) {
 	var book Book
 	// Get the book
@@ -39,18 +65,70 @@ func (s *Sale) BeforeCreate(tx *gorm.DB) (err error) {
 		}
 	}
 
-	// Check stock
-	if book.Stock < s.Quantity {
+	if s.CustomerID != nil {
+		if err = CheckCustomerExists(tx, *s.CustomerID); err != nil {
+			return
+		}
+	}
+
+	// Check stock at the location
+	if s.LocationID, err = ResolveLocation(tx, s.LocationID); err != nil {
+		return
+	}
+	stock, err := StockAt(tx, s.BookID, s.LocationID)
+	if err != nil {
+		return
+	}
+	if stock < s.Quantity {
 		return ErrStockNotEnough
 	}
 	if !book.OnSale {
 		return ErrNotOnSale
 	}
+	now := time.Now()
+	// scheduled price changes take effect at the time even if not applied to the book yet
+	listPrice, err := book.EffectivePrice(tx, now)
+	if err != nil {
+		return
+	}
+	if listPrice == nil {
+		listPrice = book.Price // no history, e.g. books inserted directly into the database
+	}
 	if s.Price == 0 {
-		if book.Price == nil {
+		if listPrice == nil {
 			return ErrBookPriceNotSet
 		}
-		s.Price = *book.Price
+		s.Price = *listPrice
+		s.PriceOverride = nil
+
+		// promotions apply to the book price only, not to a price given manually
+		coupon := s.coupon
+		if s.ReceiptID == nil && s.CouponCode != nil {
+			if coupon, err = UseCoupon(tx, *s.CouponCode, now); err != nil {
+				return err
+			}
+		}
+		if err = s.applyPromotions(tx, &book, coupon, now); err != nil {
+			return err
+		}
+	} else {
+		if s.ReceiptID == nil && s.CouponCode != nil {
+			return ErrCouponNotApplicable
+		}
+		if err = s.checkPriceOverride(tx, listPrice); err != nil {
+			return
+		}
+	}
+
+	// points of the sales in a receipt are settled by the receipt
+	if s.ReceiptID == nil && s.CustomerID != nil {
+		customer := Customer{ID: *s.CustomerID}
+		s.PointsPaid, s.PointsEarned, err = customer.PreparePayment(tx, s.Price*s.Quantity, s.PointsRedeemed)
+		if err != nil {
+			return
+		}
+	} else if s.ReceiptID == nil && s.PointsRedeemed > 0 {
+		return ErrPointsWithoutCustomer
 	}
 
 	s.Book = &book
@@ -59,16 +137,35 @@ func (s *Sale) BeforeCreate(tx *gorm.DB) (err error) {
 
 func (s *Sale) AfterCreate(tx *gorm.DB) (err error) {
 	// Update book stock
-	if err = tx.Model(s.Book).Update("stock", s.Book.Stock-s.Quantity).Error; err != nil {
+	err = ChangeStock(tx, &StockMovement{
+		BookID:      s.BookID,
+		LocationID:  s.LocationID,
+		UserID:      s.UserID,
+		Type:        StockMovementTypeSale,
+		Change:      -s.Quantity,
+		OperationID: &s.ID,
+	})
+	if err != nil {
+		return
+	}
+	// Balance of a receipt is created in Receipt.Checkout
+	if s.ReceiptID != nil {
 		return
 	}
-	// Create balance
+	// Create balance, the amount paid by points is not received
 	var balance = &Balance{
 		UserID:        s.UserID,
-		Change:        s.Price * s.Quantity,
+		Change:        s.Price*s.Quantity - s.PointsPaid,
 		OperationType: OperationTypeSale,
 		OperationID:   s.ID,
 	}
+	if err = tx.Create(balance).Error; err != nil {
+		return
+	}
 
-	return tx.Create(balance).Error
+	if s.CustomerID == nil {
+		return
+	}
+	customer := Customer{ID: *s.CustomerID}
+	return customer.SettlePayment(tx, s.UserID, s.PointsRedeemed, s.PointsEarned, PointOperationTypeSaleRedeem, PointOperationTypeSale, s.ID)
 }
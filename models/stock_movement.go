@@ -0,0 +1,91 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrStockMovementChange = utils.BadRequest("报损和丢失的数量变化必须为负数")
+
+type StockMovementType = string
+
+const (
+	StockMovementTypeInitialize StockMovementType = "initialize" // 流水记录之前已有的库存
+	StockMovementTypeSale       StockMovementType = "sale"
+	StockMovementTypeRefund     StockMovementType = "refund"    // 销售退款退回的库存
+	StockMovementTypeArrival    StockMovementType = "arrival"   // 采购收货
+	StockMovementTypeTransfer   StockMovementType = "transfer"  // 调拨, 调出和调入各一条
+	StockMovementTypeStocktake  StockMovementType = "stocktake" // 盘点过账调整
+	StockMovementTypeAdjustment StockMovementType = "adjustment"
+	StockMovementTypeDamage     StockMovementType = "damage"
+	StockMovementTypeLoss       StockMovementType = "loss"
+)
+
+// StockMovementManualTypes 可以手动调整库存的类型, 必须填写原因
+var StockMovementManualTypes = []StockMovementType{StockMovementTypeAdjustment, StockMovementTypeDamage, StockMovementTypeLoss}
+
+// StockMovement 库存流水, 只增不改, 每次库存变动记录一条, 见 ChangeStock
+type StockMovement struct {
+	ID          int               `json:"id"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"not null"`
+	BookID      int               `json:"book_id" gorm:"not null;index"`
+	Book        *Book             `json:"-"`
+	LocationID  int               `json:"location_id" gorm:"not null;index"`
+	Location    *Location         `json:"-"`
+	UserID      int               `json:"user_id" gorm:"not null"`
+	User        *User             `json:"-"`
+	Type        StockMovementType `json:"type" gorm:"size:16;not null;index"`
+	Change      int               `json:"change" gorm:"not null"` // 数量变化, 出库为负
+	Stock       int               `json:"stock" gorm:"not null"`  // 变动后该位置的库存
+	OperationID *int              `json:"operation_id"`           // id of the sale, refund, purchase arrival, transfer or stocktake, null for manual adjustments
+	Reason      *string           `json:"reason"`
+}
+
+// ChangeStock adds movement.Change to the stock of the book at the location, as well as the total stock of the book,
+// and records the movement with the resulting stock.
+// ErrStockNotEnough is returned if the stock at the location is less than -movement.Change.
+func ChangeStock(tx *gorm.DB, movement *StockMovement) error {
+	if movement.Change >= 0 {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "book_id"}, {Name: "location_id"}},
+			DoUpdates: clause.Assignments(map[string]any{"stock": gorm.Expr("book_stock.stock + ?", movement.Change)}),
+		}).Create(&BookStock{BookID: movement.BookID, LocationID: movement.LocationID, Stock: movement.Change}).Error
+		if err != nil {
+			return err
+		}
+	} else {
+		result := tx.Model(&BookStock{}).
+			Where("book_id = ? AND location_id = ? AND stock >= ?", movement.BookID, movement.LocationID, -movement.Change).
+			Update("stock", gorm.Expr("stock + ?", movement.Change))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStockNotEnough
+		}
+	}
+	err := tx.Unscoped().Model(&Book{}).Where("id = ?", movement.BookID).Update("stock", gorm.Expr("stock + ?", movement.Change)).Error
+	if err != nil {
+		return err
+	}
+
+	if movement.Stock, err = StockAt(tx, movement.BookID, movement.LocationID); err != nil {
+		return err
+	}
+	return tx.Omit(clause.Associations).Create(movement).Error
+}
+
+// migrateStockMovements 为没有流水的库存 (旧版库存) 创建一条初始流水, 使流水之和等于库存
+func migrateStockMovements(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO stock_movement (created_at, book_id, location_id, user_id, type, change, stock)
+		SELECT ?, book_stock.book_id, book_stock.location_id, book.user_id, ?, book_stock.stock, book_stock.stock
+		FROM book_stock JOIN book ON book.id = book_stock.book_id
+		WHERE book_stock.stock > 0 AND NOT EXISTS (
+			SELECT 1 FROM stock_movement
+			WHERE stock_movement.book_id = book_stock.book_id AND stock_movement.location_id = book_stock.location_id
+		)
+	`, time.Now(), StockMovementTypeInitialize).Error
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"book_management_system_backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+var ErrStocktakeNotOpen = utils.BadRequest("盘点单已过账或已取消")
+var ErrStocktakeEmpty = utils.BadRequest("盘点单没有盘点记录")
+
+type StocktakeStatus = string
+
+const (
+	StocktakeStatusOpen      StocktakeStatus = "open"      // 盘点中, 可以继续提交盘点数量
+	StocktakeStatusPosted    StocktakeStatus = "posted"    // 已过账, 差异已调整到库存
+	StocktakeStatusCancelled StocktakeStatus = "cancelled" // 已取消, 库存不变
+)
+
+// Stocktake 盘点单, 盘点一个位置的库存. 盘点数量可由多人分批提交, 过账时按盘点数量与系统库存的差异调整库存.
+// 只调整盘点过的书籍, 未盘点的书籍库存不变
+type Stocktake struct {
+	ID         int              `json:"id"`
+	CreatedAt  time.Time        `json:"created_at" gorm:"not null"`
+	UpdatedAt  time.Time        `json:"updated_at" gorm:"not null"`
+	UserID     int              `json:"user_id" gorm:"not null"` // user who open the stocktake
+	User       *User            `json:"-"`
+	LocationID int              `json:"location_id" gorm:"not null;index"`
+	Location   *Location        `json:"-"`
+	Status     StocktakeStatus  `json:"status" gorm:"size:16;default:open;not null;index"`
+	Note       *string          `json:"note"`
+	Items      []StocktakeItem  `json:"items"`
+	Counts     []StocktakeCount `json:"counts"`
+	ClosedAt   *time.Time       `json:"closed_at"` // 过账或取消的时间
+	ClosedBy   *int             `json:"closed_by"`
+}
+
+// StocktakeItem 一本书的盘点结果, 盘点数量为该书所有盘点记录之和
+type StocktakeItem struct {
+	ID          int   `json:"id"`
+	StocktakeID int   `json:"stocktake_id" gorm:"not null;uniqueIndex:idx_stocktake_item_book,priority:1"`
+	BookID      int   `json:"book_id" gorm:"not null;uniqueIndex:idx_stocktake_item_book,priority:2"`
+	Book        *Book `json:"-"`
+	Counted     int   `json:"counted" gorm:"not null;check:counted>=0"`
+	SystemStock *int  `json:"system_stock"` // 过账时该位置的系统库存, null if not posted
+}
+
+// Variance 盘点差异, 盘盈为正, 盘亏为负. 未过账时与当前系统库存比较
+func (i *StocktakeItem) Variance(systemStock int) int {
+	if i.SystemStock != nil {
+		systemStock = *i.SystemStock
+	}
+	return i.Counted - systemStock
+}
+
+// StocktakeCount 一条盘点记录, 同一本书可以由不同的人分多次盘点, 如分区盘点
+type StocktakeCount struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
+	StocktakeID int       `json:"stocktake_id" gorm:"not null;index"`
+	UserID      int       `json:"user_id" gorm:"not null"`
+	User        *User     `json:"-"`
+	BookID      int       `json:"book_id" gorm:"not null"`
+	Quantity    int       `json:"quantity" gorm:"not null;check:quantity>=0"`
+}
+
+func (s *Stocktake) BeforeCreate(_ *gorm.DB) error {
+	if s.Status == "" {
+		s.Status = StocktakeStatusOpen
+	}
+	return nil
+}
+
+// AddCounts records counted quantities of books, which are added to the counted quantities of the items.
+// quantities maps book id to counted quantity. The stocktake should be locked in the transaction.
+func (s *Stocktake) AddCounts(tx *gorm.DB, quantities map[int]int, userID int) error {
+	if s.Status != StocktakeStatusOpen {
+		return ErrStocktakeNotOpen
+	}
+
+	bookIDs := make([]int, 0, len(quantities))
+	for bookID := range quantities {
+		bookIDs = append(bookIDs, bookID)
+	}
+	sort.Ints(bookIDs)
+	if err := CheckBooksExist(tx, bookIDs); err != nil {
+		return err
+	}
+
+	for _, bookID := range bookIDs {
+		count := StocktakeCount{StocktakeID: s.ID, UserID: userID, BookID: bookID, Quantity: quantities[bookID]}
+		if err := tx.Create(&count).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "stocktake_id"}, {Name: "book_id"}},
+			DoUpdates: clause.Assignments(map[string]any{"counted": gorm.Expr("stocktake_item.counted + ?", count.Quantity)}),
+		}).Create(&StocktakeItem{StocktakeID: s.ID, BookID: bookID, Counted: count.Quantity}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Post adjusts the stock of the counted books to the counted quantities, one stock movement for each book with variance.
+// The counted books are locked during posting, so that they cannot be sold until the stocktake is posted.
+// The stocktake should be locked in the transaction.
+func (s *Stocktake) Post(tx *gorm.DB, userID int) error {
+	if s.Status != StocktakeStatusOpen {
+		return ErrStocktakeNotOpen
+	}
+
+	var items []StocktakeItem
+	if err := tx.Where("stocktake_id = ?", s.ID).Order("book_id").Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return ErrStocktakeEmpty
+	}
+
+	// lock books in the same order as checkout to avoid deadlocks, sales of the books wait until posted
+	bookIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		bookIDs = append(bookIDs, item.BookID)
+	}
+	if err := tx.Clauses(LockClause).Unscoped().Select("id").Where("id IN ?", bookIDs).Order("id").Find(&[]Book{}).Error; err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		stock, err := StockAt(tx, item.BookID, s.LocationID)
+		if err != nil {
+			return err
+		}
+		item.SystemStock = &stock
+		if err = tx.Model(item).Update("system_stock", stock).Error; err != nil {
+			return err
+		}
+		if item.Variance(stock) == 0 {
+			continue
+		}
+		err = ChangeStock(tx, &StockMovement{
+			BookID:      item.BookID,
+			LocationID:  s.LocationID,
+			UserID:      userID,
+			Type:        StockMovementTypeStocktake,
+			Change:      item.Variance(stock),
+			OperationID: &s.ID,
+			Reason:      s.Note,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.Items = items
+	return s.close(tx, StocktakeStatusPosted, userID)
+}
+
+// Cancel discards the stocktake, the stock is unchanged. The stocktake should be locked in the transaction.
+func (s *Stocktake) Cancel(tx *gorm.DB, userID int) error {
+	if s.Status != StocktakeStatusOpen {
+		return ErrStocktakeNotOpen
+	}
+	return s.close(tx, StocktakeStatusCancelled, userID)
+}
+
+func (s *Stocktake) close(tx *gorm.DB, status StocktakeStatus, userID int) error {
+	now := time.Now()
+	s.Status, s.ClosedAt, s.ClosedBy = status, &now, &userID
+	return tx.Model(s).Omit(clause.Associations).Updates(map[string]any{
+		"status":    status,
+		"closed_at": now,
+		"closed_by": userID,
+	}).Error
+}
+
+func PreloadStocktakeDetails(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("book_id") }).
+		Preload("Counts", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") })
+}
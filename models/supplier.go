@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Supplier 供应商
+type Supplier struct {
+	ID        int            `json:"id"`
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-"`
+	Name      string         `json:"name" gorm:"size:256;not null"`
+	Contact   *string        `json:"contact"`
+	TaxID     *string        `json:"tax_id" gorm:"size:64"`
+	LeadTime  int            `json:"lead_time" gorm:"default:0;not null"` // 默认交货周期, 以天为单位
+	Notes     *string        `json:"notes"`
+}
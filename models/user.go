@@ -14,7 +14,7 @@ type User struct {
 	Username       string         `json:"username" gorm:"size:256;uniqueIndex;not null"`
 	HashedPassword string         `json:"-" gorm:"size:256;not null"`
 	IsAdmin        bool           `json:"is_admin" gorm:"default:false;not null"`
-	Avatar         *string        `json:"avatar"`
+	Avatar         *string        `json:"avatar"` // http(s) url or the url of an uploaded image
 	RealName       *string        `json:"real_name"`
 	Gender         *string        `json:"gender" gorm:"size:1"`
 	StaffID        *string        `json:"staff_id" gorm:"size:20"`
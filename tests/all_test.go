@@ -12,4 +12,45 @@ func TestAllTests(t *testing.T) {
 	// book
 	t.Run("testCreateABook", testCreateABook)
 	t.Run("testGetABook", testGetABook)
+	t.Run("testCreateABookISBN", testCreateABookISBN)
+	t.Run("testSearchBooks", testSearchBooks)
+
+	// category and tag
+	t.Run("testCategoriesAndTags", testCategoriesAndTags)
+	t.Run("testContributors", testContributors)
+	t.Run("testImages", testImages)
+
+	// purchase
+	t.Run("testCreateAPurchase", testCreateAPurchase)
+	t.Run("testPayAndArriveAPurchase", testPayAndArriveAPurchase)
+	t.Run("testCancelAPurchase", testCancelAPurchase)
+	t.Run("testPartialArrival", testPartialArrival)
+	t.Run("testRefundAPurchase", testRefundAPurchase)
+
+	// location
+	t.Run("testLocations", testLocations)
+	t.Run("testStockMovements", testStockMovements)
+	t.Run("testStocktakes", testStocktakes)
+	t.Run("testReorders", testReorders)
+
+	// supplier
+	t.Run("testCreateASupplier", testCreateASupplier)
+	t.Run("testSupplierSummary", testSupplierSummary)
+
+	// sale
+	t.Run("testCreateAReceipt", testCreateAReceipt)
+	t.Run("testRefundASale", testRefundASale)
+	t.Run("testCustomers", testCustomers)
+	t.Run("testLoyalty", testLoyalty)
+	t.Run("testPromotions", testPromotions)
+	t.Run("testPriceOverrides", testPriceOverrides)
+
+	// book archive, summary and import
+	t.Run("testArchiveABook", testArchiveABook)
+	t.Run("testGetABookSummary", testGetABookSummary)
+	t.Run("testImportBooks", testImportBooks)
+	t.Run("testBookPrices", testBookPrices)
+
+	// export
+	t.Run("testExport", testExport)
 }
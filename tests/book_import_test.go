@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"testing"
+)
+
+const bookImportCSV = `isbn,title,author,press,price,on_sale
+9787111000051,importedBook,testAuthor,testPress,12.5,true
+978-7-111-00001-3,,,,99,
+9787111000052,badBook,testAuthor,testPress,abc,
+9787111000068,noAuthorBook,,testPress,1,
+`
+
+const bookImportONIX = `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+  <Header><Sender><SenderName>testPress</SenderName></Sender></Header>
+  <Product>
+    <RecordReference>test-1</RecordReference>
+    <ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9787111000051</IDValue></ProductIdentifier>
+    <DescriptiveDetail>
+      <TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>onixBook</TitleText></TitleElement></TitleDetail>
+    </DescriptiveDetail>
+  </Product>
+  <Product>
+    <RecordReference>test-2</RecordReference>
+    <ProductIdentifier><ProductIDType>02</ProductIDType><IDValue>0306406152</IDValue></ProductIdentifier>
+    <ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9787111000075</IDValue></ProductIdentifier>
+    <DescriptiveDetail>
+      <TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitlePrefix>The</TitlePrefix><TitleWithoutPrefix>ONIX Book</TitleWithoutPrefix></TitleElement></TitleDetail>
+      <Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonName>Author One</PersonName></Contributor>
+      <Contributor><SequenceNumber>2</SequenceNumber><ContributorRole>A01</ContributorRole><NamesBeforeKey>Author</NamesBeforeKey><KeyNames>Two</KeyNames></Contributor>
+      <Contributor><SequenceNumber>3</SequenceNumber><ContributorRole>B06</ContributorRole><PersonName>Translator</PersonName></Contributor>
+    </DescriptiveDetail>
+    <CollateralDetail><TextContent><TextType>03</TextType><ContentAudience>00</ContentAudience><Text>A book from ONIX</Text></TextContent></CollateralDetail>
+    <PublishingDetail>
+      <Publisher><PublishingRole>01</PublishingRole><PublisherName>onixPress</PublisherName></Publisher>
+      <PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>20230115</Date></PublishingDate>
+    </PublishingDetail>
+    <ProductSupply><SupplyDetail>
+      <Price><PriceType>02</PriceType><PriceAmount>9.99</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>
+      <Price><PriceType>02</PriceType><PriceAmount>59.00</PriceAmount><CurrencyCode>CNY</CurrencyCode></Price>
+    </SupplyDetail></ProductSupply>
+  </Product>
+</ONIXMessage>
+`
+
+func testImportBooks(t *testing.T) {
+	adminTester.testPostRaw(t, "/api/books/_import", 403, "text/csv", []byte(bookImportCSV), nil)
+	superAdminTester.testPostRaw(t, "/api/books/_import", 400, "text/csv", []byte("isbn,unknown\n"), nil)
+
+	// dry run reports the rows but saves nothing
+	var response apis.BookImportResponse
+	superAdminTester.testPostRaw(t, "/api/books/_import?dry_run=true", 200, "text/csv", []byte(bookImportCSV), &response)
+	assert.True(t, response.DryRun)
+	assert.Equal(t, 1, response.Created)
+	assert.Equal(t, 1, response.Updated)
+	assert.Equal(t, 2, response.Failed)
+	assert.Equal(t, 0, response.Rows[0].BookID)
+
+	var bookListResponse apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"isbn": "9787111000051"}, &bookListResponse)
+	assert.Equal(t, 0, bookListResponse.PageTotal)
+
+	response = apis.BookImportResponse{}
+	superAdminTester.testPostRaw(t, "/api/books/_import", 200, "text/csv", []byte(bookImportCSV), &response)
+	assert.Equal(t, 1, response.Created)
+	assert.Equal(t, 1, response.Updated)
+	assert.Equal(t, 2, response.Failed)
+	if assert.Len(t, response.Rows, 4) {
+		assert.Equal(t, apis.BookImportStatusCreated, response.Rows[0].Status)
+		assert.NotZero(t, response.Rows[0].BookID)
+
+		// only the columns present in the row are updated
+		assert.Equal(t, apis.BookImportStatusUpdated, response.Rows[1].Status)
+		assert.Equal(t, "9787111000013", response.Rows[1].ISBN)
+		var book apis.BookResponse
+		superAdminTester.testGet(t, "/api/books/1", 200, nil, &book)
+		assert.Equal(t, "testBook", book.Title)
+		assert.Equal(t, 99.0, *book.PriceFloat)
+
+		assert.Equal(t, apis.BookImportStatusFailed, response.Rows[2].Status)
+		if assert.NotNil(t, response.Rows[2].Detail) {
+			fields := make([]string, 0)
+			for _, detail := range *response.Rows[2].Detail {
+				fields = append(fields, detail.Field)
+			}
+			assert.ElementsMatch(t, []string{"price"}, fields)
+		}
+		assert.Equal(t, apis.BookImportStatusFailed, response.Rows[3].Status)
+		if assert.NotNil(t, response.Rows[3].Detail) {
+			assert.Equal(t, "author", (*response.Rows[3].Detail)[0].Field)
+		}
+	}
+
+	// ONIX uploaded as multipart form
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "catalogue.xml")
+	assert.Nil(t, err)
+	_, _ = part.Write([]byte(bookImportONIX))
+	assert.Nil(t, writer.Close())
+
+	response = apis.BookImportResponse{}
+	superAdminTester.testPostRaw(t, "/api/books/_import?format=onix", 200, writer.FormDataContentType(), form.Bytes(), &response)
+	assert.Equal(t, 1, response.Created)
+	assert.Equal(t, 1, response.Updated)
+	assert.Equal(t, 0, response.Failed)
+
+	superAdminTester.testGet(t, "/api/books", 200, Map{"isbn": "9787111000051"}, &bookListResponse)
+	assert.Equal(t, "onixBook", bookListResponse.Books[0].Title)
+	assert.Equal(t, 12.5, *bookListResponse.Books[0].PriceFloat)
+
+	superAdminTester.testGet(t, "/api/books", 200, Map{"isbn": "9787111000075"}, &bookListResponse)
+	if assert.Equal(t, 1, bookListResponse.PageTotal) {
+		book := bookListResponse.Books[0]
+		assert.Equal(t, "The ONIX Book", book.Title)
+		assert.Equal(t, "Author One, Author Two", book.Author)
+		assert.Equal(t, "onixPress", book.Press)
+		assert.Equal(t, 59.0, *book.PriceFloat)
+		assert.Equal(t, "2023-01-15", book.PublishedDate.Format("2006-01-02"))
+		assert.Equal(t, "A book from ONIX", *book.Description)
+	}
+}
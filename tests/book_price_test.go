@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testBookPrices(t *testing.T) {
+	book := testCreateBook(t, Map{"title": "价格测试", "isbn": "9787111000181", "price": 10}, 10)
+	route := "/api/books/" + strconv.Itoa(book.ID) + "/prices"
+
+	// modifying the price records a change
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book.ID), 200, Map{"price": 12.5}, &book)
+	assert.Equal(t, 12.5, *book.PriceFloat)
+	var prices apis.BookPriceListResponse
+	superAdminTester.testGet(t, route, 200, nil, &prices)
+	if assert.Len(t, prices.Prices, 2) {
+		assert.Equal(t, 12.5, prices.Prices[0].PriceFloat)
+		assert.Equal(t, 10.0, prices.Prices[1].PriceFloat)
+		assert.NotNil(t, prices.Prices[0].AppliedAt)
+	}
+	beforeSchedule := time.Now()
+
+	// scheduled changes do not change the price until they take effect
+	var price apis.BookPriceResponse
+	effectiveAt := time.Now().Add(time.Hour)
+	superAdminTester.testPost(t, route, 201, Map{"price": 15, "effective_at": effectiveAt}, &price)
+	assert.Nil(t, price.AppliedAt)
+	superAdminTester.testGet(t, "/api/books/"+strconv.Itoa(book.ID), 200, nil, &book)
+	assert.Equal(t, 12.5, *book.PriceFloat)
+	superAdminTester.testPost(t, route, 400, Map{"price": 15, "effective_at": time.Now().Add(-time.Hour)}, nil)
+	superAdminTester.testPost(t, route, 400, Map{"price": -1}, nil)
+	superAdminTester.testPost(t, "/api/books/100000/prices", 404, Map{"price": 15}, nil)
+
+	// cancel a scheduled change
+	var cancelled apis.BookPriceResponse
+	superAdminTester.testPost(t, route, 201, Map{"price": 20, "effective_at": effectiveAt.Add(time.Hour)}, &cancelled)
+	superAdminTester.testGet(t, route, 200, Map{"pending": true}, &prices)
+	assert.Len(t, prices.Prices, 2)
+	adminTester.testDelete(t, route+"/"+strconv.Itoa(cancelled.ID), 403, nil, nil)
+	superAdminTester.testDelete(t, route+"/"+strconv.Itoa(cancelled.ID), 204, nil, nil)
+	superAdminTester.testDelete(t, "/api/books/100000/prices/"+strconv.Itoa(price.ID), 404, nil, nil)
+
+	applied, err := ApplyScheduledPrices(DB, effectiveAt.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, applied)
+	superAdminTester.testGet(t, "/api/books/"+strconv.Itoa(book.ID), 200, nil, &book)
+	assert.Equal(t, 15.0, *book.PriceFloat)
+	superAdminTester.testDelete(t, route+"/"+strconv.Itoa(price.ID), 400, nil, nil)
+
+	// the price at a time
+	superAdminTester.testGet(t, route, 200, Map{"at": beforeSchedule.Format(time.RFC3339Nano)}, &prices)
+	if assert.Len(t, prices.Prices, 2) {
+		assert.Equal(t, 12.5, prices.Prices[0].PriceFloat)
+	}
+
+	// sales use the effective price even if the scheduler has not run yet
+	effective := BookPrice{BookID: book.ID, UserID: 1, Price: 1800, EffectiveAt: time.Now()}
+	DB.Create(&effective)
+	var sale apis.SaleResponse
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1}, &sale)
+	assert.Equal(t, 18.0, sale.PriceFloat)
+	// so they can not be cancelled either
+	superAdminTester.testDelete(t, route+"/"+strconv.Itoa(effective.ID), 400, nil, nil)
+}
@@ -0,0 +1,14 @@
+//go:build sqlite_fts5
+
+package tests
+
+import (
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestBookSearchFTS5 makes sure the fts5 index is used when built with -tags sqlite_fts5, instead of falling back to like
+func TestBookSearchFTS5(t *testing.T) {
+	assert.Equal(t, BookSearchModeFTS5, BookSearchMode)
+}
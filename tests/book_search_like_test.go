@@ -0,0 +1,15 @@
+//go:build !sqlite_fts5
+
+package tests
+
+import (
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestBookSearchLike makes sure the search falls back to like without -tags sqlite_fts5,
+// even if the fts5 table was created by a previous build with the tag
+func TestBookSearchLike(t *testing.T) {
+	assert.Equal(t, BookSearchModeLike, BookSearchMode)
+}
@@ -4,6 +4,7 @@ import (
 	"book_management_system_backend/apis"
 	. "book_management_system_backend/models"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -13,11 +14,42 @@ func testCreateABook(t *testing.T) {
 		"title":  "testBook",
 		"author": "testAuthor",
 		"press":  "testPress",
-		"isbn":   "90000000001",
+		"isbn":   "9787111000013",
 		"price":  100,
 	}, &bookCreateResponse)
 
 	assert.Equal(t, "testBook", bookCreateResponse.Title)
+	assert.Equal(t, "9787111000013", bookCreateResponse.ISBN)
+}
+
+func testCreateABookISBN(t *testing.T) {
+	book := Map{
+		"title":  "isbnBook",
+		"author": "testAuthor",
+		"press":  "testPress",
+	}
+
+	// invalid checksum
+	book["isbn"] = "9787111000014"
+	superAdminTester.testPost(t, "/api/books", 400, book, nil)
+	book["isbn"] = "90000000001"
+	superAdminTester.testPost(t, "/api/books", 400, book, nil)
+
+	// ISBN-10 is converted to ISBN-13
+	var bookResponse apis.BookResponse
+	book["isbn"] = "0-306-40615-2"
+	superAdminTester.testPost(t, "/api/books", 201, book, &bookResponse)
+	assert.Equal(t, "9780306406157", bookResponse.ISBN)
+
+	// duplicated after normalization
+	book["isbn"] = "978-0-306-40615-7"
+	superAdminTester.testPost(t, "/api/books", 409, book, nil)
+	book["isbn"] = "978-7-111-00001-3"
+	superAdminTester.testPost(t, "/api/books", 409, book, nil)
+
+	var bookListResponse apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"isbn": "0306406152"}, &bookListResponse)
+	assert.Equal(t, 1, bookListResponse.PageTotal)
 }
 
 func testGetABook(t *testing.T) {
@@ -27,3 +59,166 @@ func testGetABook(t *testing.T) {
 	assert.Equal(t, 1, bookGetResponse.PageTotal)
 	assert.Equal(t, "testBook", bookGetResponse.Books[0].Title)
 }
+
+func testArchiveABook(t *testing.T) {
+	var bookResponse apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title":   "archivedBook",
+		"author":  "testAuthor",
+		"press":   "testPress",
+		"isbn":    "978-7-111-00003-7",
+		"price":   30,
+		"on_sale": true,
+	}, &bookResponse)
+	bookURL := "/api/books/" + strconv.Itoa(bookResponse.ID)
+
+	// books with open purchases or stock cannot be archived
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": bookResponse.ID, "quantity": 1, "price": 10}},
+	}, &purchaseResponse)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+	superAdminTester.testDelete(t, bookURL, 400, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, nil, nil)
+	superAdminTester.testDelete(t, bookURL, 400, nil, nil)
+
+	var saleResponse apis.SaleResponse
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": bookResponse.ID, "quantity": 1}, &saleResponse)
+
+	adminTester.testDelete(t, bookURL, 403, nil, nil)
+	superAdminTester.testDelete(t, bookURL, 204, nil, nil)
+
+	var bookListResponse apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"id": bookResponse.ID}, &bookListResponse)
+	assert.Equal(t, 0, bookListResponse.PageTotal)
+	superAdminTester.testGet(t, "/api/books", 200, Map{"id": bookResponse.ID, "include_deleted": true}, &bookListResponse)
+	assert.Equal(t, 1, bookListResponse.PageTotal)
+	assert.NotNil(t, bookListResponse.Books[0].ArchivedAt)
+	adminTester.testGet(t, "/api/books", 403, Map{"include_deleted": true}, nil)
+
+	// history records still resolve the archived book
+	superAdminTester.testGet(t, "/api/sales/"+strconv.Itoa(saleResponse.ID), 200, nil, &saleResponse)
+	assert.Equal(t, "archivedBook", saleResponse.Book.Title)
+	superAdminTester.testGet(t, purchaseURL, 200, nil, &purchaseResponse)
+	assert.Equal(t, "archivedBook", purchaseResponse.Items[0].Book.Title)
+
+	// archived books cannot be sold or purchased
+	superAdminTester.testPost(t, "/api/sales", 404, Map{"book_id": bookResponse.ID, "quantity": 1}, nil)
+	superAdminTester.testPost(t, "/api/purchases", 404, Map{
+		"items": []Map{{"book_id": bookResponse.ID, "quantity": 1, "price": 10}},
+	}, nil)
+
+	// nor refunded, which would return them to stock
+	saleURL := "/api/sales/" + strconv.Itoa(saleResponse.ID)
+	superAdminTester.testPost(t, saleURL+"/_refund", 400, nil, nil)
+
+	superAdminTester.testPost(t, bookURL+"/_restore", 200, nil, &bookResponse)
+	assert.Nil(t, bookResponse.ArchivedAt)
+	superAdminTester.testPost(t, bookURL+"/_restore", 400, nil, nil)
+	superAdminTester.testPost(t, saleURL+"/_refund", 201, nil, nil)
+	superAdminTester.testGet(t, bookURL, 200, nil, &bookResponse)
+	assert.Equal(t, 1, bookResponse.Stock)
+}
+
+func testGetABookSummary(t *testing.T) {
+	var bookResponse apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title":   "summaryBook",
+		"author":  "testAuthor",
+		"press":   "testPress",
+		"isbn":    "9787111000044",
+		"price":   20,
+		"on_sale": true,
+	}, &bookResponse)
+	bookURL := "/api/books/" + strconv.Itoa(bookResponse.ID)
+
+	var summary apis.BookSummaryResponse
+	superAdminTester.testGet(t, bookURL, 200, nil, &summary)
+	assert.Equal(t, "summaryBook", summary.Title)
+	assert.Equal(t, 0, summary.TotalSold)
+	assert.Nil(t, summary.LastSaleTime)
+	assert.Nil(t, summary.AveragePurchaseCost)
+	assert.Nil(t, summary.Margin)
+	superAdminTester.testGet(t, "/api/books/id=id", 400, nil, nil) // not passed to the database as a condition
+
+	// paid purchase, 3 of 4 arrived
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": bookResponse.ID, "quantity": 4, "price": 10}},
+	}, &purchaseResponse)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, Map{
+		"items": []Map{{"book_id": bookResponse.ID, "quantity": 3}},
+	}, nil)
+
+	// draft purchases are not counted
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": bookResponse.ID, "quantity": 2, "price": 16}},
+	}, nil)
+
+	// sold 2, refunded 1
+	var saleResponse apis.SaleResponse
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": bookResponse.ID, "quantity": 2}, &saleResponse)
+	superAdminTester.testPost(t, "/api/sales/"+strconv.Itoa(saleResponse.ID)+"/_refund", 201, Map{"quantity": 1}, nil)
+
+	superAdminTester.testGet(t, bookURL, 200, nil, &summary)
+	assert.Equal(t, 2, summary.Stock)
+	assert.Equal(t, 1, summary.TotalSold)
+	assert.Equal(t, 4, summary.TotalPurchased)
+	assert.Equal(t, 1, summary.PendingArrivals)
+	assert.NotNil(t, summary.LastSaleTime)
+	if assert.NotNil(t, summary.AveragePurchaseCost) {
+		assert.InDelta(t, 10.0, *summary.AveragePurchaseCost, 1e-9)
+	}
+	if assert.NotNil(t, summary.Margin) {
+		assert.InDelta(t, 0.5, *summary.Margin, 1e-9)
+	}
+
+	superAdminTester.testGet(t, "/api/books/0", 404, nil, nil)
+}
+
+func testSearchBooks(t *testing.T) {
+	for _, book := range []Map{
+		{
+			"title":       "Harry Potter and the Philosopher's Stone",
+			"author":      "J. K. Rowling",
+			"press":       "Bloomsbury",
+			"isbn":        "9787111000082",
+			"description": "Harry discovers that he is a wizard",
+		},
+		{"title": "Rowling: A Biography", "author": "Sean Smith", "press": "Michael O'Mara", "isbn": "9787111000099"},
+		{"title": "The Potter's Field", "author": "Ellis Peters", "press": "Headline", "isbn": "9787111000105"},
+	} {
+		superAdminTester.testPost(t, "/api/books", 201, book, nil)
+	}
+
+	// terms are matched across fields
+	var response apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "harry potter rowling"}, &response)
+	if assert.Equal(t, 1, response.PageTotal) {
+		assert.Equal(t, "9787111000082", response.Books[0].ISBN)
+		if assert.NotNil(t, response.Books[0].Snippet) {
+			assert.Contains(t, *response.Books[0].Snippet, "<mark>")
+		}
+	}
+
+	// title matches rank before author matches
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "rowling"}, &response)
+	if assert.Equal(t, 2, response.PageTotal) {
+		assert.Equal(t, "9787111000099", response.Books[0].ISBN)
+		assert.Equal(t, "9787111000082", response.Books[1].ISBN)
+	}
+
+	// description, isbn and other filters
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "wizard"}, &response)
+	assert.Equal(t, 1, response.PageTotal)
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "7111000105"}, &response)
+	assert.Equal(t, 1, response.PageTotal)
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "potter", "author": "Peters"}, &response)
+	assert.Equal(t, 1, response.PageTotal)
+	superAdminTester.testGet(t, "/api/books", 200, Map{"q": "potter", "page_num": 1, "page_size": 10}, &response)
+	assert.Equal(t, 2, response.PageTotal)
+	assert.Len(t, response.Books, 2)
+}
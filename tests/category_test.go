@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testCategoriesAndTags(t *testing.T) {
+	createCategory := func(data Map) apis.CategoryResponse {
+		var response apis.CategoryResponse
+		superAdminTester.testPost(t, "/api/categories", 201, data, &response)
+		return response
+	}
+	literature := createCategory(Map{"code": "I", "name": "文学"})
+	chinese := createCategory(Map{"code": "I2", "name": "中国文学", "parent_id": literature.ID})
+	novel := createCategory(Map{"code": "I247", "name": "小说", "parent_id": chinese.ID})
+	fiction := createCategory(Map{"code": "FIC000000", "name": "Fiction"})
+	assert.Equal(t, "/"+strconv.Itoa(literature.ID)+"/"+strconv.Itoa(chinese.ID)+"/"+strconv.Itoa(novel.ID)+"/", novel.Path)
+	superAdminTester.testPost(t, "/api/categories", 409, Map{"code": "I2", "name": "duplicated"}, nil)
+	superAdminTester.testPost(t, "/api/categories", 404, Map{"name": "orphan", "parent_id": 100000}, nil)
+
+	var category apis.CategoryResponse
+	superAdminTester.testGet(t, "/api/categories/"+strconv.Itoa(literature.ID), 200, nil, &category)
+	if assert.Len(t, category.Children, 1) {
+		assert.Equal(t, chinese.ID, category.Children[0].ID)
+	}
+	superAdminTester.testGet(t, "/api/categories/id=id", 400, nil, nil)
+	var categoryList apis.CategoryListResponse
+	superAdminTester.testGet(t, "/api/categories", 200, Map{"root": true}, &categoryList)
+	assert.Equal(t, 2, categoryList.PageTotal)
+	superAdminTester.testGet(t, "/api/categories", 200, Map{"code": "I2"}, &categoryList)
+	assert.Equal(t, 2, categoryList.PageTotal)
+
+	// books with categories and free-form tags
+	var book1, book2 apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title": "三体", "author": "刘慈欣", "press": "重庆出版社", "isbn": "9787111000112",
+		"category_ids": []int{novel.ID}, "tags": []string{"经典", "科幻"},
+	}, &book1)
+	assert.Len(t, book1.Categories, 1)
+	assert.Len(t, book1.Tags, 2)
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title": "Dune", "author": "Frank Herbert", "press": "Chilton", "isbn": "9787111000129",
+		"category_ids": []int{fiction.ID}, "tags": []string{"经典"},
+	}, &book2)
+	superAdminTester.testPost(t, "/api/books", 404, Map{
+		"title": "unknown", "author": "unknown", "press": "unknown", "isbn": "9787111000136",
+		"category_ids": []int{100000},
+	}, nil)
+
+	listBooks := func(query Map) []int {
+		var response apis.BookListResponse
+		superAdminTester.testGet(t, "/api/books", 200, query, &response)
+		ids := make([]int, 0, len(response.Books))
+		for _, book := range response.Books {
+			ids = append(ids, book.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int{book1.ID}, listBooks(Map{"category_id": literature.ID}))
+	assert.Equal(t, []int{book1.ID}, listBooks(Map{"category_id": novel.ID}))
+	assert.Equal(t, []int{book1.ID, book2.ID}, listBooks(Map{"tag": "经典"}))
+	assert.Equal(t, []int{book1.ID}, listBooks(Map{"tag": "科幻", "category_id": literature.ID}))
+
+	// moving a category moves its descendants
+	superAdminTester.testPatch(t, "/api/categories/"+strconv.Itoa(literature.ID), 400, Map{"parent_id": novel.ID}, nil)
+	superAdminTester.testPatch(t, "/api/categories/"+strconv.Itoa(chinese.ID), 200, Map{"parent_id": fiction.ID}, &category)
+	assert.Equal(t, "/"+strconv.Itoa(fiction.ID)+"/"+strconv.Itoa(chinese.ID)+"/", category.Path)
+	superAdminTester.testGet(t, "/api/categories/"+strconv.Itoa(novel.ID), 200, nil, &category)
+	assert.Equal(t, "/"+strconv.Itoa(fiction.ID)+"/"+strconv.Itoa(chinese.ID)+"/"+strconv.Itoa(novel.ID)+"/", category.Path)
+	assert.Empty(t, listBooks(Map{"category_id": literature.ID}))
+	assert.Equal(t, []int{book1.ID, book2.ID}, listBooks(Map{"category_id": fiction.ID}))
+	superAdminTester.testPatch(t, "/api/categories/"+strconv.Itoa(chinese.ID), 200, Map{"parent_id": 0}, &category)
+	assert.Nil(t, category.ParentID)
+	assert.Equal(t, "/"+strconv.Itoa(chinese.ID)+"/", category.Path)
+
+	// modify the categories and tags of a book
+	var book apis.BookResponse
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book1.ID), 200, Map{"tags": []string{}, "category_ids": []int{literature.ID, fiction.ID}}, &book)
+	assert.Len(t, book.Categories, 2)
+	assert.Empty(t, book.Tags)
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book1.ID), 200, Map{"title": "三体 I"}, &book)
+	assert.Len(t, book.Categories, 2)
+	assert.Equal(t, []int{book2.ID}, listBooks(Map{"tag": "经典"}))
+
+	// tags
+	var tagList apis.TagListResponse
+	superAdminTester.testGet(t, "/api/tags", 200, Map{"name": "科"}, &tagList)
+	assert.Equal(t, 1, tagList.PageTotal)
+	superAdminTester.testPost(t, "/api/tags", 409, Map{"name": "经典"}, nil)
+	var tag apis.TagResponse
+	superAdminTester.testPost(t, "/api/tags", 201, Map{"name": "名著"}, &tag)
+	superAdminTester.testPatch(t, "/api/tags/"+strconv.Itoa(tag.ID), 409, Map{"name": "经典"}, nil)
+	superAdminTester.testPatch(t, "/api/tags/"+strconv.Itoa(tag.ID), 200, Map{"name": "世界名著"}, &tag)
+	assert.Equal(t, "世界名著", tag.Name)
+	superAdminTester.testGet(t, "/api/tags", 200, Map{"name": "经典"}, &tagList)
+	adminTester.testDelete(t, "/api/tags/"+strconv.Itoa(tagList.Tags[0].ID), 403, nil, nil)
+	superAdminTester.testDelete(t, "/api/tags/"+strconv.Itoa(tagList.Tags[0].ID), 204, nil, nil)
+	assert.Empty(t, listBooks(Map{"tag": "经典"}))
+
+	// categories with children cannot be deleted
+	superAdminTester.testDelete(t, "/api/categories/"+strconv.Itoa(chinese.ID), 400, nil, nil)
+	adminTester.testDelete(t, "/api/categories/"+strconv.Itoa(novel.ID), 403, nil, nil)
+	superAdminTester.testDelete(t, "/api/categories/"+strconv.Itoa(novel.ID), 204, nil, nil)
+	superAdminTester.testDelete(t, "/api/categories/"+strconv.Itoa(chinese.ID), 204, nil, nil)
+	superAdminTester.testGet(t, "/api/categories/"+strconv.Itoa(novel.ID), 404, nil, nil)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testContributors(t *testing.T) {
+	// the author field is split into contributors
+	var book1 apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title": "沙丘", "author": "[美] 弗兰克·赫伯特 著; 潘振华、张三 译", "press": "江苏凤凰文艺出版社", "isbn": "9787111000143",
+	}, &book1)
+	assert.Equal(t, "[美] 弗兰克·赫伯特 著; 潘振华、张三 译", book1.Author)
+	if assert.Len(t, book1.Contributors, 3) {
+		assert.Equal(t, "弗兰克·赫伯特", book1.Contributors[0].Name)
+		assert.Equal(t, ContributorRoleAuthor, book1.Contributors[0].Role)
+		assert.Equal(t, "潘振华", book1.Contributors[1].Name)
+		assert.Equal(t, ContributorRoleTranslator, book1.Contributors[1].Role)
+		assert.Equal(t, "张三", book1.Contributors[2].Name)
+		assert.Equal(t, ContributorRoleTranslator, book1.Contributors[2].Role)
+	}
+	translatorID := book1.Contributors[1].ContributorID
+
+	// the author field defaults to the byline of the contributors
+	var book2 apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title": "Good Omens", "press": "Gollancz", "isbn": "9787111000150",
+		"contributors": []Map{
+			{"name": "Terry Pratchett"},
+			{"name": "Neil Gaiman", "role": "author"},
+			{"contributor_id": translatorID, "role": "translator"},
+		},
+	}, &book2)
+	assert.Equal(t, "Terry Pratchett, Neil Gaiman", book2.Author)
+	if assert.Len(t, book2.Contributors, 3) {
+		assert.Equal(t, ContributorRoleAuthor, book2.Contributors[0].Role)
+		assert.Equal(t, translatorID, book2.Contributors[2].ContributorID)
+	}
+	superAdminTester.testPost(t, "/api/books", 400, Map{"title": "no author", "press": "unknown", "isbn": "9787111000167"}, nil)
+	superAdminTester.testPost(t, "/api/books", 400, Map{"title": "no author", "press": "unknown", "isbn": "9787111000167", "contributors": []Map{}}, nil)
+	superAdminTester.testPost(t, "/api/books", 400, Map{"title": "bad role", "press": "unknown", "isbn": "9787111000167", "contributors": []Map{{"name": "A", "role": "writer"}}}, nil)
+	superAdminTester.testPost(t, "/api/books", 404, Map{"title": "unknown", "press": "unknown", "isbn": "9787111000167", "contributors": []Map{{"contributor_id": 100000}}}, nil)
+
+	listBooks := func(query Map) []int {
+		var response apis.BookListResponse
+		superAdminTester.testGet(t, "/api/books", 200, query, &response)
+		ids := make([]int, 0, len(response.Books))
+		for _, book := range response.Books {
+			ids = append(ids, book.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int{book1.ID, book2.ID}, listBooks(Map{"contributor_id": translatorID}))
+	assert.Empty(t, listBooks(Map{"contributor_id": translatorID, "contributor_role": "author"}))
+	assert.Equal(t, []int{book2.ID}, listBooks(Map{"contributor_id": book2.Contributors[1].ContributorID}))
+
+	// replace the contributors
+	var book apis.BookResponse
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book2.ID), 200, Map{
+		"contributors": []Map{{"contributor_id": book2.Contributors[1].ContributorID}, {"name": "Terry Pratchett"}},
+	}, &book)
+	assert.Equal(t, "Neil Gaiman, Terry Pratchett", book.Author)
+	assert.Len(t, book.Contributors, 2)
+	assert.Empty(t, listBooks(Map{"contributor_id": translatorID, "contributor_role": "translator", "title": "Good Omens"}))
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book1.ID), 200, Map{"author": "弗兰克·赫伯特"}, &book)
+	assert.Len(t, book.Contributors, 1)
+	assert.Empty(t, listBooks(Map{"contributor_id": translatorID}))
+
+	// renaming a contributor updates the author field of its books
+	var contributor apis.ContributorResponse
+	superAdminTester.testPatch(t, "/api/contributors/"+strconv.Itoa(book2.Contributors[1].ContributorID), 200, Map{"name": "尼尔·盖曼"}, &contributor)
+	assert.Equal(t, "尼尔·盖曼", contributor.Name)
+	superAdminTester.testGet(t, "/api/books/"+strconv.Itoa(book2.ID), 200, nil, &book)
+	assert.Equal(t, "尼尔·盖曼, Terry Pratchett", book.Author)
+	assert.Equal(t, []int{book2.ID}, listBooks(Map{"author": "盖曼"}))
+
+	var contributorList apis.ContributorListResponse
+	superAdminTester.testGet(t, "/api/contributors", 200, Map{"name": "Terry"}, &contributorList)
+	assert.Equal(t, 1, contributorList.PageTotal)
+
+	// contributors with books cannot be deleted
+	superAdminTester.testDelete(t, "/api/contributors/"+strconv.Itoa(contributor.ID), 400, nil, nil)
+	adminTester.testDelete(t, "/api/contributors/"+strconv.Itoa(translatorID), 403, nil, nil)
+	superAdminTester.testDelete(t, "/api/contributors/"+strconv.Itoa(translatorID), 204, nil, nil)
+	superAdminTester.testGet(t, "/api/contributors/"+strconv.Itoa(translatorID), 404, nil, nil)
+	superAdminTester.testGet(t, "/api/contributors/id=id", 400, nil, nil)
+	superAdminTester.testPost(t, "/api/contributors", 201, Map{"name": "潘振华"}, &contributor)
+	assert.NotEqual(t, translatorID, contributor.ID)
+}
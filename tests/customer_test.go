@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testCustomers(t *testing.T) {
+	var customer, other apis.CustomerResponse
+	adminTester.testPost(t, "/api/customers", 201, Map{
+		"name": "张三", "phone": "13800000000", "email": "zhangsan@example.com", "member_number": "M001",
+	}, &customer)
+	adminTester.testPost(t, "/api/customers", 409, Map{"name": "张三", "phone": "13800000000"}, nil)
+	adminTester.testPost(t, "/api/customers", 400, Map{"name": "李四", "email": "not an email"}, nil)
+	adminTester.testPost(t, "/api/customers", 201, Map{"name": "李四", "member_number": "M002"}, &other)
+	adminTester.testPatch(t, "/api/customers/"+strconv.Itoa(other.ID), 409, Map{"member_number": "M001"}, nil)
+	adminTester.testPatch(t, "/api/customers/"+strconv.Itoa(other.ID), 200, Map{"phone": "13900000000"}, &other)
+	assert.Equal(t, "13900000000", *other.Phone)
+	customerURL := "/api/customers/" + strconv.Itoa(customer.ID)
+
+	// search and lookup
+	var customers apis.CustomerListResponse
+	adminTester.testGet(t, "/api/customers", 200, Map{"q": "zhangsan"}, &customers)
+	if assert.Len(t, customers.Customers, 1) {
+		assert.Equal(t, customer.ID, customers.Customers[0].ID)
+	}
+	adminTester.testGet(t, "/api/customers", 200, Map{"q": "M00"}, &customers)
+	assert.Equal(t, 2, customers.PageTotal)
+	var found apis.CustomerResponse
+	adminTester.testGet(t, "/api/customers/_lookup", 200, Map{"member_number": "M002"}, &found)
+	assert.Equal(t, other.ID, found.ID)
+	adminTester.testGet(t, "/api/customers/_lookup", 200, Map{"phone": "13800000000"}, &found)
+	assert.Equal(t, customer.ID, found.ID)
+	adminTester.testGet(t, "/api/customers/_lookup", 404, Map{"phone": "13800000000", "member_number": "M002"}, nil)
+	adminTester.testGet(t, "/api/customers/_lookup", 400, nil, nil)
+	adminTester.testGet(t, "/api/customers/id=id", 400, nil, nil)
+	adminTester.testGet(t, "/api/customers/id=id/history", 400, nil, nil)
+
+	// sales of the customer
+	book1 := testCreateBook(t, Map{"title": "顾客", "isbn": "9787111000259", "price": 20}, 10)
+	book2 := testCreateBook(t, Map{"title": "顾客2", "isbn": "9787111000266", "price": 10}, 10)
+
+	var sale apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 404, Map{"book_id": book1.ID, "quantity": 1, "customer_id": 100000}, nil)
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 2, "customer_id": customer.ID}, &sale)
+	assert.Equal(t, customer.ID, *sale.CustomerID)
+	adminTester.testPost(t, "/api/sales/"+strconv.Itoa(sale.ID)+"/_refund", 201, Map{"quantity": 1}, nil)
+	var receipt apis.ReceiptResponse
+	adminTester.testPost(t, "/api/receipts", 201, Map{
+		"customer_id": customer.ID,
+		"sales":       []Map{{"book_id": book1.ID, "quantity": 1}, {"book_id": book2.ID, "quantity": 3, "customer_id": other.ID}},
+	}, &receipt)
+	assert.Equal(t, customer.ID, *receipt.CustomerID)
+	for _, sale := range receipt.Sales {
+		assert.Equal(t, customer.ID, *sale.CustomerID)
+	}
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book2.ID, "quantity": 1}, nil)
+
+	var sales apis.SaleListResponse
+	adminTester.testGet(t, "/api/sales", 200, Map{"customer_id": customer.ID}, &sales)
+	assert.Equal(t, 3, sales.PageTotal)
+	var receipts apis.ReceiptListResponse
+	adminTester.testGet(t, "/api/receipts", 200, Map{"customer_id": customer.ID}, &receipts)
+	assert.Equal(t, 1, receipts.PageTotal)
+
+	var history apis.CustomerHistoryResponse
+	adminTester.testGet(t, customerURL+"/history", 200, nil, &history)
+	assert.Equal(t, 3, history.SaleCount)
+	assert.Equal(t, 2, history.VisitCount)
+	assert.Equal(t, 5, history.Quantity)
+	assert.Equal(t, 70.0, history.SpentFloat)
+	assert.Equal(t, receipt.CreatedAt.Unix(), history.LastVisit.Unix())
+	assert.True(t, history.FirstVisit.Before(*history.LastVisit))
+	if assert.Len(t, history.Books, 2) {
+		books := make(map[int]apis.CustomerBookResponse)
+		for _, book := range history.Books {
+			books[book.BookID] = book
+		}
+		assert.Equal(t, 2, books[book1.ID].Quantity)
+		assert.Equal(t, 40.0, books[book1.ID].SpentFloat)
+		assert.Equal(t, 3, books[book2.ID].Quantity)
+		assert.Equal(t, "顾客2", books[book2.ID].Book.Title)
+	}
+	adminTester.testGet(t, "/api/customers/"+strconv.Itoa(other.ID)+"/history", 200, nil, &history)
+	assert.Equal(t, 0, history.SaleCount)
+	assert.Nil(t, history.LastVisit)
+	assert.Empty(t, history.Books)
+
+	// sales are kept after the customer is deleted
+	adminTester.testDelete(t, customerURL, 403, nil, nil)
+	superAdminTester.testDelete(t, customerURL, 204, nil, nil)
+	adminTester.testGet(t, customerURL, 404, nil, nil)
+	adminTester.testPost(t, "/api/sales", 404, Map{"book_id": book1.ID, "quantity": 1, "customer_id": customer.ID}, nil)
+	adminTester.testGet(t, "/api/sales", 200, Map{"customer_id": customer.ID}, &sales)
+	assert.Equal(t, 3, sales.PageTotal)
+	adminTester.testPost(t, "/api/customers", 201, Map{"name": "王五", "phone": "13800000000"}, nil)
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"archive/zip"
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testExport(t *testing.T) {
+	superAdminTester.testGet(t, "/api/books", 400, Map{"export": "pdf"}, nil)
+
+	// csv ignores pagination and honors the filters
+	var bookListResponse apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"author": "testAuthor", "page_num": 1, "page_size": 100}, &bookListResponse)
+	contentType, body := superAdminTester.testGetRaw(t, "/api/books", 200, Map{"author": "testAuthor", "export": "csv", "page_num": 1, "page_size": 10, "order_by": "title"})
+	assert.True(t, strings.HasPrefix(contentType, "text/csv"))
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.Nil(t, err)
+	if assert.Len(t, records, bookListResponse.PageTotal+1) {
+		assert.Equal(t, []string{"id", "created_at", "updated_at", "user_id", "isbn"}, records[0][:5])
+		for _, record := range records[2:] {
+			assert.LessOrEqual(t, records[1][5], record[5]) // ordered by title
+		}
+	}
+
+	// ndjson contains nested records
+	var saleListResponse apis.SaleListResponse
+	superAdminTester.testGet(t, "/api/sales", 200, Map{"page_num": 1, "page_size": 100}, &saleListResponse)
+	contentType, body = superAdminTester.testGetRaw(t, "/api/sales", 200, Map{"export": "ndjson"})
+	assert.Equal(t, "application/x-ndjson", contentType)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	var lines int
+	for scanner.Scan() {
+		var sale apis.SaleResponse
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &sale))
+		assert.NotEmpty(t, sale.Book.Title)
+		lines++
+	}
+	assert.Equal(t, saleListResponse.PageTotal, lines)
+
+	// xlsx
+	var balanceListResponse apis.BalanceListResponse
+	superAdminTester.testGet(t, "/api/balances", 200, Map{"positive": false, "page_num": 1, "page_size": 100}, &balanceListResponse)
+	_, body = superAdminTester.testGetRaw(t, "/api/balances", 200, Map{"positive": false, "export": "xlsx"})
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if assert.Nil(t, err) {
+		var sheet []byte
+		for _, file := range reader.File {
+			if file.Name == "xl/worksheets/sheet1.xml" {
+				f, err := file.Open()
+				assert.Nil(t, err)
+				sheet, _ = io.ReadAll(f)
+			}
+		}
+		assert.Equal(t, balanceListResponse.PageTotal+1, strings.Count(string(sheet), "<row "))
+	}
+
+	_, body = superAdminTester.testGetRaw(t, "/api/purchases", 200, Map{"status": PurchaseStatusArrived, "export": "csv"})
+	records, err = csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.Nil(t, err)
+	assert.Contains(t, records[0], "total")
+	for _, record := range records[1:] {
+		assert.Contains(t, record, PurchaseStatusArrived)
+	}
+}
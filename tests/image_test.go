@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func testImages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buffer bytes.Buffer
+	assert.Nil(t, png.Encode(&buffer, src))
+	data := buffer.Bytes()
+
+	// upload as multipart form or raw body, the same image is stored once
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "cover.png")
+	_, _ = part.Write(data)
+	_ = writer.Close()
+	var uploaded, image2 apis.ImageResponse
+	adminTester.testPostRaw(t, "/api/images", 201, writer.FormDataContentType(), form.Bytes(), &uploaded)
+	assert.Equal(t, "image/png", uploaded.ContentType)
+	assert.Equal(t, 600, uploaded.Width)
+	assert.Equal(t, 300, uploaded.Height)
+	assert.Equal(t, "/api/images/"+uploaded.ID, uploaded.URL)
+	adminTester.testPostRaw(t, "/api/images", 201, "image/png", data, &image2)
+	assert.Equal(t, uploaded.ID, image2.ID)
+	adminTester.testPostRaw(t, "/api/images", 400, "image/svg+xml", []byte("<svg></svg>"), nil)
+	adminTester.testPostRaw(t, "/api/images", 400, "image/png", data[:100], nil)
+
+	// images are served without authorization and cached by etag
+	get := func(url string, etag string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := App.Test(req, -1)
+		assert.Nil(t, err)
+		return res
+	}
+	res := get(uploaded.URL, "")
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "image/png", res.Header.Get("Content-Type"))
+	assert.Contains(t, res.Header.Get("Cache-Control"), "immutable")
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, data, body)
+	res = get(uploaded.URL, res.Header.Get("ETag"))
+	assert.Equal(t, 304, res.StatusCode)
+	res = get(uploaded.URL, "")
+	assert.Equal(t, 200, res.StatusCode)
+
+	res = get(uploaded.ThumbnailURL, "")
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "image/jpeg", res.Header.Get("Content-Type"))
+	thumbnail, _, err := image.Decode(res.Body)
+	if assert.Nil(t, err) {
+		assert.Equal(t, image.Rect(0, 0, 256, 128), thumbnail.Bounds())
+	}
+	assert.Equal(t, 404, get("/api/images/unknown", "").StatusCode)
+
+	// covers and avatars are urls
+	var book apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title": "cover", "author": "cover", "press": "cover", "isbn": "9787111000167", "cover": uploaded.URL,
+	}, &book)
+	assert.Equal(t, uploaded.URL, *book.Cover)
+	superAdminTester.testPost(t, "/api/books", 400, Map{
+		"title": "cover", "author": "cover", "press": "cover", "isbn": "9787111000174", "cover": "data:image/png;base64,iVBORw0KGgo=",
+	}, nil)
+	superAdminTester.testPost(t, "/api/books", 404, Map{
+		"title": "cover", "author": "cover", "press": "cover", "isbn": "9787111000174", "cover": "/api/images/unknown",
+	}, nil)
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book.ID), 200, Map{"cover": "https://example.com/cover.jpg"}, &book)
+	assert.Equal(t, "https://example.com/cover.jpg", *book.Cover)
+
+	var user apis.UserResponse
+	adminTester.testPatch(t, "/api/users/me", 400, Map{"avatar": uploaded.ThumbnailURL}, nil)
+	adminTester.testPatch(t, "/api/users/me", 200, Map{"avatar": uploaded.URL}, &user)
+	assert.Equal(t, uploaded.URL, *user.Avatar)
+}
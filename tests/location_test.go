@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testLocations(t *testing.T) {
+	var locations []apis.LocationResponse
+	superAdminTester.testGet(t, "/api/locations", 200, nil, &locations)
+	if !assert.Len(t, locations, 1) {
+		return
+	}
+	assert.Equal(t, DefaultLocationName, locations[0].Name)
+	assert.True(t, locations[0].IsDefault)
+	store := locations[0].ID
+
+	var warehouse, branch apis.LocationResponse
+	adminTester.testPost(t, "/api/locations", 403, Map{"name": "仓库"}, nil)
+	superAdminTester.testPost(t, "/api/locations", 201, Map{"name": "仓库"}, &warehouse)
+	superAdminTester.testPost(t, "/api/locations", 409, Map{"name": "仓库"}, nil)
+	superAdminTester.testPost(t, "/api/locations", 201, Map{"name": "分店", "description": "second branch"}, &branch)
+	assert.False(t, branch.IsDefault)
+
+	book := testCreateBook(t, Map{"title": "库存位置", "isbn": "9787111000198", "price": 30}, 0)
+	bookURL := "/api/books/" + strconv.Itoa(book.ID)
+
+	// receive into the warehouse, then the rest into the default location
+	var purchase apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{"items": []Map{{"book_id": book.ID, "quantity": 10, "price": 10}}}, &purchase)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchase.ID)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 404, Map{"location_id": 100000}, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, Map{
+		"items": []Map{{"book_id": book.ID, "quantity": 6}}, "location_id": warehouse.ID,
+	}, &purchase)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, nil, &purchase)
+	assert.Equal(t, PurchaseStatusArrived, purchase.Status)
+	superAdminTester.testGet(t, purchaseURL, 200, nil, &purchase)
+	if assert.Len(t, purchase.Arrivals, 2) {
+		assert.Equal(t, warehouse.ID, purchase.Arrivals[0].LocationID)
+		assert.Equal(t, store, purchase.Arrivals[1].LocationID)
+	}
+	superAdminTester.testGet(t, bookURL, 200, nil, &book)
+	assert.Equal(t, 10, book.Stock)
+	assert.Equal(t, []apis.BookStockResponse{{LocationID: store, Stock: 4}, {LocationID: warehouse.ID, Stock: 6}}, book.Stocks)
+
+	// sell from a location
+	var sale apis.SaleResponse
+	superAdminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 5}, nil)
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 5, "location_id": warehouse.ID}, &sale)
+	assert.Equal(t, warehouse.ID, sale.LocationID)
+
+	// transfer between locations
+	var transfer apis.StockTransferResponse
+	superAdminTester.testPost(t, "/api/transfers", 201, Map{
+		"book_id": book.ID, "from_location_id": warehouse.ID, "to_location_id": branch.ID, "quantity": 1,
+	}, &transfer)
+	assert.Equal(t, 1, transfer.Quantity)
+	superAdminTester.testPost(t, "/api/transfers", 400, Map{
+		"book_id": book.ID, "from_location_id": warehouse.ID, "to_location_id": branch.ID, "quantity": 1,
+	}, nil)
+	superAdminTester.testPost(t, "/api/transfers", 400, Map{
+		"book_id": book.ID, "from_location_id": store, "to_location_id": store, "quantity": 1,
+	}, nil)
+	superAdminTester.testPost(t, "/api/transfers", 404, Map{
+		"book_id": book.ID, "from_location_id": store, "to_location_id": 100000, "quantity": 1,
+	}, nil)
+	var transfers apis.StockTransferListResponse
+	superAdminTester.testGet(t, "/api/transfers", 200, Map{"location_id": branch.ID}, &transfers)
+	assert.Equal(t, 1, transfers.PageTotal)
+	var movements apis.StockMovementListResponse
+	superAdminTester.testGet(t, "/api/stock_movements", 200, Map{"book_id": book.ID, "type": "transfer"}, &movements)
+	if assert.Len(t, movements.Movements, 2) {
+		assert.Equal(t, -1, movements.Movements[0].Change)
+		assert.Equal(t, branch.ID, movements.Movements[1].LocationID)
+		assert.Equal(t, transfer.ID, *movements.Movements[1].OperationID)
+	}
+
+	var books apis.BookListResponse
+	superAdminTester.testGet(t, "/api/books", 200, Map{"location_id": branch.ID}, &books)
+	if assert.Len(t, books.Books, 1) {
+		assert.Equal(t, book.ID, books.Books[0].ID)
+	}
+
+	// refunded books are returned to the given location
+	var refund apis.SaleRefundResponse
+	superAdminTester.testPost(t, "/api/sales/"+strconv.Itoa(sale.ID)+"/_refund", 201, Map{"location_id": store}, &refund)
+	assert.Equal(t, store, refund.LocationID)
+	superAdminTester.testGet(t, bookURL, 200, nil, &book)
+	assert.Equal(t, 10, book.Stock)
+	assert.Equal(t, []apis.BookStockResponse{{LocationID: store, Stock: 9}, {LocationID: branch.ID, Stock: 1}}, book.Stocks)
+
+	// editing a book leaves its stock alone
+	superAdminTester.testPatch(t, bookURL, 200, Map{"title": "库存位置2", "stock": 0}, nil)
+	superAdminTester.testGet(t, bookURL, 200, nil, &book)
+	assert.Equal(t, "库存位置2", book.Title)
+	assert.Equal(t, 10, book.Stock)
+	assert.Equal(t, []apis.BookStockResponse{{LocationID: store, Stock: 9}, {LocationID: branch.ID, Stock: 1}}, book.Stocks)
+
+	// only locations out of stock can be deleted, except the default one
+	superAdminTester.testDelete(t, "/api/locations/"+strconv.Itoa(branch.ID), 400, nil, nil)
+	superAdminTester.testDelete(t, "/api/locations/"+strconv.Itoa(store), 400, nil, nil)
+	adminTester.testDelete(t, "/api/locations/"+strconv.Itoa(warehouse.ID), 403, nil, nil)
+	superAdminTester.testDelete(t, "/api/locations/"+strconv.Itoa(warehouse.ID), 204, nil, nil)
+
+	// change the default location
+	var location apis.LocationResponse
+	superAdminTester.testPatch(t, "/api/locations/"+strconv.Itoa(branch.ID), 200, Map{"is_default": true}, &location)
+	assert.True(t, location.IsDefault)
+	superAdminTester.testGet(t, "/api/locations/"+strconv.Itoa(store), 200, nil, &location)
+	assert.False(t, location.IsDefault)
+	superAdminTester.testGet(t, "/api/locations/id=id", 400, nil, nil)
+	superAdminTester.testPatch(t, "/api/locations/"+strconv.Itoa(store), 200, Map{"is_default": true, "name": DefaultLocationName}, &location)
+	assert.True(t, location.IsDefault)
+	superAdminTester.testGet(t, "/api/locations", 200, nil, &locations)
+	assert.Len(t, locations, 2)
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testLoyalty(t *testing.T) {
+	var customer apis.CustomerResponse
+	adminTester.testPost(t, "/api/customers", 201, Map{"name": "王五", "phone": "13700000000"}, &customer)
+	assert.NotNil(t, customer.TierID) // default tier
+	assert.Equal(t, 0, customer.Points)
+	customerURL := "/api/customers/" + strconv.Itoa(customer.ID)
+
+	book := testCreateBook(t, Map{"title": "积分", "isbn": "9787111000273", "price": 50}, 20)
+
+	// accrue 1 point per yuan in the default tier
+	var sale apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 2, "customer_id": customer.ID}, &sale)
+	assert.Equal(t, 100, sale.PointsEarned)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, 100, customer.Points)
+
+	// redeem points as payment, the rest earns points
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 1, "redeem_points": 10}, nil)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 1, "customer_id": customer.ID, "redeem_points": 1000}, nil)
+	var redeemed apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "customer_id": customer.ID, "redeem_points": 50}, &redeemed)
+	assert.Equal(t, 50, redeemed.PointsRedeemed)
+	assert.Equal(t, 0.5, redeemed.PointsPaidFloat)
+	assert.Equal(t, 49, redeemed.PointsEarned)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, 99, customer.Points)
+
+	var balances apis.BalanceListResponse
+	adminTester.testGet(t, "/api/balances", 200, Map{"sort": "desc", "page_num": 1, "page_size": 10}, &balances)
+	if assert.NotEmpty(t, balances.Balances) {
+		assert.Equal(t, 49.5, balances.Balances[0].Change) // amount paid by points is not cash
+	}
+
+	// manual adjustment
+	adminTester.testPost(t, customerURL+"/points", 403, Map{"change": 10000}, nil)
+	superAdminTester.testPost(t, customerURL+"/points", 400, Map{"change": -1000}, nil)
+	var record apis.PointRecordResponse
+	superAdminTester.testPost(t, customerURL+"/points", 201, Map{"change": 10000, "reason": "活动赠送"}, &record)
+	assert.Equal(t, 10099, record.Total)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 1, "customer_id": customer.ID, "redeem_points": 6000}, nil)
+	superAdminTester.testPost(t, customerURL+"/points", 201, Map{"change": -10000}, &record)
+	assert.Equal(t, 99, record.Total)
+
+	// redeem on a receipt
+	var receipt apis.ReceiptResponse
+	adminTester.testPost(t, "/api/receipts", 400, Map{
+		"redeem_points": 10,
+		"sales":         []Map{{"book_id": book.ID, "quantity": 1}},
+	}, nil)
+	adminTester.testPost(t, "/api/receipts", 201, Map{
+		"customer_id":   customer.ID,
+		"redeem_points": 90,
+		"sales":         []Map{{"book_id": book.ID, "quantity": 2, "redeem_points": 10}},
+	}, &receipt)
+	assert.Equal(t, 100.0, receipt.TotalFloat)
+	assert.Equal(t, 90, receipt.PointsRedeemed)
+	assert.Equal(t, 0.9, receipt.PointsPaidFloat)
+	assert.Equal(t, 99, receipt.PointsEarned)
+	for _, sale := range receipt.Sales {
+		assert.Equal(t, 0, sale.PointsRedeemed)
+	}
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, 108, customer.Points)
+
+	// refund returns the redeemed points and reverses the earned points
+	var refund apis.SaleRefundResponse
+	adminTester.testPost(t, "/api/sales/"+strconv.Itoa(redeemed.ID)+"/_refund", 201, nil, &refund)
+	assert.Equal(t, 50.0, refund.AmountFloat)
+	assert.Equal(t, 0.5, refund.PointsPaidFloat)
+	assert.Equal(t, 49.5, refund.CashFloat)
+	assert.Equal(t, 50, refund.PointsReturned)
+	assert.Equal(t, 49, refund.PointsReversed)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, 109, customer.Points)
+
+	// points ledger
+	var records apis.PointRecordListResponse
+	adminTester.testGet(t, customerURL+"/points", 200, nil, &records)
+	assert.Equal(t, 9, records.PageTotal)
+	if assert.NotEmpty(t, records.Records) {
+		last := records.Records[len(records.Records)-1]
+		assert.Equal(t, customer.Points, last.Total)
+		assert.Equal(t, PointOperationTypeRefundReverse, last.OperationType)
+	}
+	adminTester.testGet(t, customerURL+"/points", 200, Map{"operation_type": PointOperationTypeManual}, &records)
+	assert.Equal(t, 2, records.PageTotal)
+	adminTester.testGet(t, "/api/customers/100000/points", 404, nil, nil)
+	adminTester.testGet(t, "/api/customers/id=id/points", 400, nil, nil)
+
+	// tiers upgrade and downgrade by the rolling 12-month spend, which is 200 now
+	defaultTierID := *customer.TierID
+	var gold apis.MembershipTierResponse
+	adminTester.testPost(t, "/api/membership_tiers", 403, Map{"name": "金卡", "min_spend": 200, "points_per_yuan": 2}, nil)
+	superAdminTester.testPost(t, "/api/membership_tiers", 409, Map{"name": "金卡", "min_spend": 0, "points_per_yuan": 2}, nil)
+	superAdminTester.testPost(t, "/api/membership_tiers", 201, Map{"name": "金卡", "min_spend": 200, "points_per_yuan": 2}, &gold)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, gold.ID, *customer.TierID)
+
+	var tiers []apis.MembershipTierResponse
+	adminTester.testGet(t, "/api/membership_tiers", 200, nil, &tiers)
+	if assert.Len(t, tiers, 2) {
+		assert.Equal(t, gold.ID, tiers[1].ID)
+	}
+
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "customer_id": customer.ID}, &sale)
+	assert.Equal(t, 100, sale.PointsEarned)
+
+	goldURL := "/api/membership_tiers/" + strconv.Itoa(gold.ID)
+	superAdminTester.testPatch(t, goldURL, 200, Map{"min_spend": 1000}, &gold)
+	assert.Equal(t, 1000.0, gold.MinSpendFloat)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, defaultTierID, *customer.TierID)
+
+	superAdminTester.testPatch(t, goldURL, 200, Map{"min_spend": 250}, &gold)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, gold.ID, *customer.TierID)
+	adminTester.testPost(t, "/api/sales/"+strconv.Itoa(sale.ID)+"/_refund", 201, nil, &refund)
+	assert.Equal(t, 100, refund.PointsReversed)
+	adminTester.testGet(t, customerURL, 200, nil, &customer)
+	assert.Equal(t, defaultTierID, *customer.TierID)
+
+	adminTester.testDelete(t, goldURL, 403, nil, nil)
+	superAdminTester.testDelete(t, goldURL, 204, nil, nil)
+	superAdminTester.testDelete(t, goldURL, 404, nil, nil)
+}
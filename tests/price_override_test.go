@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testPriceOverrides(t *testing.T) {
+	book := testCreateBook(t, Map{"title": "改价", "isbn": "9787111000310", "price": 100}, 0)
+	var purchase apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{"items": []Map{{"book_id": book.ID, "quantity": 20, "price": 90}}}, &purchase)
+	superAdminTester.testPost(t, "/api/purchases/"+strconv.Itoa(purchase.ID)+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, "/api/purchases/"+strconv.Itoa(purchase.ID)+"/_arrive", 200, nil, nil)
+	var admin, staff apis.UserResponse
+	superAdminTester.testGet(t, "/api/users/me", 200, nil, &admin)
+	adminTester.testGet(t, "/api/users/me", 200, nil, &staff)
+	approver := Map{"username": "admin", "password": "adminadmin"}
+
+	// within the limit of the user, a reason is required
+	var sale apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 1, "price": 95}, nil)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book.ID, "quantity": 1, "price": 95, "price_reason": " "}, nil)
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "price": 95, "price_reason": "破损"}, &sale)
+	if assert.NotNil(t, sale.PriceOverride) {
+		assert.Equal(t, 100.0, *sale.PriceOverride.ListPriceFloat)
+		assert.Equal(t, 90.0, *sale.PriceOverride.CostFloat)
+		assert.Equal(t, 5.0, sale.PriceOverride.DiscountPercent)
+		assert.Equal(t, "破损", sale.PriceOverride.Reason)
+		assert.Nil(t, sale.PriceOverride.ApproverID)
+	}
+
+	// above the limit, an admin other than the user approves
+	adminTester.testPost(t, "/api/sales", 403, Map{"book_id": book.ID, "quantity": 1, "price": 80, "price_reason": "老顾客"}, nil)
+	adminTester.testPost(t, "/api/sales", 403, Map{
+		"book_id": book.ID, "quantity": 1, "price": 80, "price_reason": "老顾客",
+		"approver": Map{"username": "user", "password": "12345678"},
+	}, nil)
+	adminTester.testPost(t, "/api/sales", 400, Map{
+		"book_id": book.ID, "quantity": 1, "price": 80, "price_reason": "老顾客",
+		"approver": Map{"username": "admin", "password": "wrongpassword"},
+	}, nil)
+	adminTester.testPost(t, "/api/sales", 201, Map{
+		"book_id": book.ID, "quantity": 2, "price": 80, "price_reason": "老顾客", "approver": approver,
+	}, &sale)
+	if assert.NotNil(t, sale.PriceOverride) {
+		assert.Equal(t, admin.ID, *sale.PriceOverride.ApproverID)
+		assert.Equal(t, 2, sale.PriceOverride.Quantity)
+	}
+
+	// admins have a higher limit, but still need a second admin far below the list price or below cost
+	superAdminTester.testPost(t, "/api/sales", 403, Map{"book_id": book.ID, "quantity": 1, "price": 1, "price_reason": "清仓"}, nil)
+	superAdminTester.testPost(t, "/api/sales", 403, Map{
+		"book_id": book.ID, "quantity": 1, "price": 1, "price_reason": "清仓",
+		"approver": Map{"username": "user", "password": "12345678"},
+	}, nil) // the approver must be an admin
+	superAdminTester.testPost(t, "/api/sales", 403, Map{"book_id": book.ID, "quantity": 1, "price": 85, "price_reason": "清仓"}, nil)
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "price": 92, "price_reason": "清仓"}, &sale)
+	if assert.NotNil(t, sale.PriceOverride) {
+		assert.Nil(t, sale.PriceOverride.ApproverID)
+	}
+
+	// raising the price or selling at the book price
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "price": 120, "price_reason": "加急"}, &sale)
+	if assert.NotNil(t, sale.PriceOverride) {
+		assert.Equal(t, 0.0, sale.PriceOverride.DiscountPercent)
+	}
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 1, "price": 100}, &sale)
+	assert.Nil(t, sale.PriceOverride)
+
+	// the approver of a receipt approves all the sales
+	var receipt apis.ReceiptResponse
+	adminTester.testPost(t, "/api/receipts", 403, Map{
+		"sales": []Map{{"book_id": book.ID, "quantity": 1, "price": 50, "price_reason": "活动"}},
+	}, nil)
+	adminTester.testPost(t, "/api/receipts", 201, Map{
+		"approver": approver,
+		"sales":    []Map{{"book_id": book.ID, "quantity": 1, "price": 50, "price_reason": "活动"}},
+	}, &receipt)
+	if assert.Len(t, receipt.Sales, 1) && assert.NotNil(t, receipt.Sales[0].PriceOverride) {
+		assert.Equal(t, admin.ID, *receipt.Sales[0].PriceOverride.ApproverID)
+	}
+	adminTester.testGet(t, "/api/sales/"+strconv.Itoa(receipt.Sales[0].ID), 200, nil, &sale)
+	assert.NotNil(t, sale.PriceOverride)
+
+	// audit list
+	var overrides apis.PriceOverrideListResponse
+	adminTester.testGet(t, "/api/price_overrides", 403, nil, nil)
+	superAdminTester.testGet(t, "/api/price_overrides", 200, Map{"book_id": book.ID}, &overrides)
+	assert.Equal(t, 5, overrides.PageTotal)
+	superAdminTester.testGet(t, "/api/price_overrides", 200, Map{"book_id": book.ID, "approved": true}, &overrides)
+	assert.Equal(t, 2, overrides.PageTotal)
+	superAdminTester.testGet(t, "/api/price_overrides", 200, Map{"book_id": book.ID, "user_id": staff.ID, "approved": false}, &overrides)
+	assert.Equal(t, 2, overrides.PageTotal)
+}
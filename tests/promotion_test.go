@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testPromotions(t *testing.T) {
+	var parent, child apis.CategoryResponse
+	superAdminTester.testPost(t, "/api/categories", 201, Map{"name": "促销分类"}, &parent)
+	superAdminTester.testPost(t, "/api/categories", 201, Map{"name": "促销子分类", "parent_id": parent.ID}, &child)
+
+	book1 := testCreateBook(t, Map{"title": "促销1", "press": "促销出版社", "isbn": "9787111000280", "price": 100}, 20)
+	book2 := testCreateBook(t, Map{"title": "促销2", "isbn": "9787111000297", "price": 40, "category_ids": []int{child.ID}}, 20)
+	book3 := testCreateBook(t, Map{"title": "促销3", "isbn": "9787111000303", "price": 30}, 20)
+
+	// promotions
+	var press, fixed, buyGet, couponOnly apis.PromotionResponse
+	adminTester.testPost(t, "/api/promotions", 403, Map{"name": "八折", "type": PromotionTypePercentage, "value": 20}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 400, Map{"name": "无效", "type": PromotionTypePercentage, "value": 150}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 400, Map{"name": "无效", "type": PromotionTypeBuyGet, "buy_quantity": 2}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 400, Map{
+		"name": "无效", "type": PromotionTypeFixed, "value": 5,
+		"start_at": time.Now().Add(time.Hour), "end_at": time.Now(),
+	}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 404, Map{"name": "无效", "type": PromotionTypeFixed, "value": 5, "category_ids": []int{100000}}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{
+		"name": "出版社八折", "type": PromotionTypePercentage, "value": 20, "presses": []string{"促销出版社"},
+	}, &press)
+	assert.Equal(t, []string{"促销出版社"}, press.Presses)
+	assert.True(t, press.Enabled)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{"name": "每本减 5 元", "type": PromotionTypeFixed, "value": 5}, &fixed)
+	assert.Equal(t, 5.0, fixed.ValueFloat)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{
+		"name": "买二赠一", "type": PromotionTypeBuyGet, "buy_quantity": 2, "free_quantity": 1, "category_ids": []int{parent.ID},
+	}, &buyGet)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{
+		"name": "未开始", "type": PromotionTypePercentage, "value": 90, "start_at": time.Now().Add(24 * time.Hour),
+	}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{"name": "未启用", "type": PromotionTypePercentage, "value": 90, "enabled": false}, nil)
+	superAdminTester.testPost(t, "/api/promotions", 201, Map{
+		"name": "优惠券九折", "type": PromotionTypePercentage, "value": 10, "coupon_only": true,
+	}, &couponOnly)
+
+	var promotions apis.PromotionListResponse
+	adminTester.testGet(t, "/api/promotions", 200, Map{"active": true}, &promotions)
+	assert.Equal(t, 4, promotions.PageTotal)
+	adminTester.testGet(t, "/api/promotions", 200, Map{"active": false}, &promotions)
+	assert.Equal(t, 2, promotions.PageTotal)
+
+	// the best automatic promotion applies
+	var sale1, sale2, sale3 apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 1}, &sale1)
+	assert.Equal(t, 80.0, sale1.PriceFloat)
+	assert.Equal(t, 20.0, sale1.DiscountFloat)
+	if assert.Len(t, sale1.Promotions, 1) {
+		assert.Equal(t, press.ID, sale1.Promotions[0].PromotionID)
+	}
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book2.ID, "quantity": 3}, &sale2)
+	assert.Equal(t, 26.67, sale2.PriceFloat) // one of three free, rounded down to whole cents per book
+	assert.Equal(t, 39.99, sale2.DiscountFloat)
+	if assert.Len(t, sale2.Promotions, 1) {
+		assert.Equal(t, buyGet.ID, sale2.Promotions[0].PromotionID)
+	}
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book3.ID, "quantity": 2}, &sale3)
+	assert.Equal(t, 25.0, sale3.PriceFloat)
+	assert.Equal(t, 10.0, sale3.DiscountFloat)
+
+	// no promotion for a price given manually
+	var manual apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 1, "price": 90, "price_reason": "破损"}, &manual)
+	assert.Equal(t, 90.0, manual.PriceFloat)
+	assert.Empty(t, manual.Promotions)
+
+	// coupons
+	var coupon, bookCoupon, receiptCoupon apis.CouponResponse
+	adminTester.testPost(t, "/api/coupons", 403, Map{"promotion_id": couponOnly.ID, "code": "save10"}, nil)
+	superAdminTester.testPost(t, "/api/coupons", 404, Map{"promotion_id": 100000, "code": "save10"}, nil)
+	superAdminTester.testPost(t, "/api/coupons", 201, Map{"promotion_id": couponOnly.ID, "code": " save10 ", "usage_limit": 1}, &coupon)
+	assert.Equal(t, "SAVE10", coupon.Code)
+	superAdminTester.testPost(t, "/api/coupons", 409, Map{"promotion_id": couponOnly.ID, "code": "Save10"}, nil)
+	superAdminTester.testPost(t, "/api/coupons", 201, Map{"promotion_id": press.ID, "code": "PRESS"}, &bookCoupon)
+	superAdminTester.testPost(t, "/api/coupons", 201, Map{"promotion_id": couponOnly.ID, "code": "RECEIPT", "usage_limit": 5}, &receiptCoupon)
+	var found apis.CouponResponse
+	adminTester.testGet(t, "/api/coupons/_lookup", 200, Map{"code": "save10"}, &found)
+	assert.Equal(t, coupon.ID, found.ID)
+
+	adminTester.testPost(t, "/api/sales", 404, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "NOTEXIST"}, nil)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "SAVE10", "price": 30}, nil)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "PRESS"}, nil)
+	var couponSale apis.SaleResponse
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "save10"}, &couponSale)
+	assert.Equal(t, 22.5, couponSale.PriceFloat) // 5 off, then 10% off
+	assert.Equal(t, 7.5, couponSale.DiscountFloat)
+	if assert.Len(t, couponSale.Promotions, 2) {
+		assert.Equal(t, fixed.ID, couponSale.Promotions[0].PromotionID)
+		assert.Nil(t, couponSale.Promotions[0].CouponID)
+		assert.Equal(t, coupon.ID, *couponSale.Promotions[1].CouponID)
+	}
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "SAVE10"}, nil)
+
+	var coupons apis.CouponListResponse
+	adminTester.testGet(t, "/api/coupons", 200, Map{"promotion_id": couponOnly.ID}, &coupons)
+	if assert.Len(t, coupons.Coupons, 2) {
+		assert.Equal(t, 1, coupons.Coupons[0].UsedCount)
+	}
+	adminTester.testGet(t, "/api/coupons/_lookup", 200, Map{"code": "PRESS"}, &found)
+	assert.Equal(t, 0, found.UsedCount) // not counted if not applicable
+
+	// a coupon of a receipt is applied to each sale and counted once
+	var receipt apis.ReceiptResponse
+	adminTester.testPost(t, "/api/receipts", 400, Map{"coupon_code": "PRESS", "sales": []Map{{"book_id": book3.ID, "quantity": 1}}}, nil)
+	adminTester.testPost(t, "/api/receipts", 201, Map{
+		"coupon_code": "receipt",
+		"sales":       []Map{{"book_id": book1.ID, "quantity": 1}, {"book_id": book3.ID, "quantity": 1}},
+	}, &receipt)
+	assert.Equal(t, 94.5, receipt.TotalFloat) // 100 * 0.8 * 0.9 + (30 - 5) * 0.9
+	adminTester.testGet(t, "/api/coupons/_lookup", 200, Map{"code": "RECEIPT"}, &found)
+	assert.Equal(t, 1, found.UsedCount)
+	adminTester.testGet(t, "/api/receipts/"+strconv.Itoa(receipt.ID), 200, nil, &receipt)
+	for _, sale := range receipt.Sales {
+		assert.Len(t, sale.Promotions, 2)
+	}
+
+	superAdminTester.testDelete(t, "/api/coupons/"+strconv.Itoa(coupon.ID), 400, nil, nil)
+	superAdminTester.testPatch(t, "/api/coupons/"+strconv.Itoa(receiptCoupon.ID), 200, Map{"usage_limit": 1}, &found)
+	adminTester.testPost(t, "/api/sales", 400, Map{"book_id": book3.ID, "quantity": 1, "coupon_code": "RECEIPT"}, nil)
+	superAdminTester.testDelete(t, "/api/coupons/"+strconv.Itoa(bookCoupon.ID), 204, nil, nil)
+
+	// refunds are at the discounted price
+	var refund apis.SaleRefundResponse
+	adminTester.testPost(t, "/api/sales/"+strconv.Itoa(sale1.ID)+"/_refund", 201, nil, &refund)
+	assert.Equal(t, 80.0, refund.AmountFloat)
+
+	// summary for reporting
+	var sales apis.SaleListResponse
+	adminTester.testGet(t, "/api/sales", 200, Map{"promotion_id": press.ID}, &sales)
+	assert.Equal(t, 2, sales.PageTotal)
+	var promotion apis.PromotionResponse
+	adminTester.testGet(t, "/api/promotions/"+strconv.Itoa(press.ID), 200, nil, &promotion)
+	assert.Equal(t, press.Name, promotion.Name)
+	adminTester.testGet(t, "/api/promotions/id=id", 400, nil, nil)
+	var summaries []apis.PromotionSummaryResponse
+	adminTester.testGet(t, "/api/promotions/_summary", 200, nil, &summaries)
+	for _, summary := range summaries {
+		switch summary.PromotionID {
+		case press.ID:
+			assert.Equal(t, 2, summary.SaleCount)
+			assert.Equal(t, 1, summary.RefundedQuantity)
+			assert.Equal(t, 40.0, summary.DiscountFloat)
+			assert.Equal(t, 72.0, summary.RevenueFloat) // the first sale is refunded
+		case fixed.ID:
+			assert.Equal(t, 3, summary.SaleCount)
+			assert.Equal(t, 4, summary.Quantity)
+		case couponOnly.ID:
+			assert.Equal(t, 3, summary.SaleCount)
+			assert.Equal(t, "优惠券九折", summary.Promotion.Name)
+		}
+	}
+	assert.Len(t, summaries, 4)
+
+	// modify and delete, later sales are not discounted
+	superAdminTester.testPatch(t, "/api/promotions/"+strconv.Itoa(press.ID), 200, Map{"presses": []string{}, "book_ids": []int{book3.ID}}, &press)
+	assert.Equal(t, []int{book3.ID}, press.BookIDs)
+	assert.Empty(t, press.Presses)
+	superAdminTester.testPatch(t, "/api/promotions/"+strconv.Itoa(press.ID), 400, Map{"value": 0}, nil)
+	adminTester.testDelete(t, "/api/promotions/"+strconv.Itoa(fixed.ID), 403, nil, nil)
+	adminTester.testGet(t, "/api/promotions", 200, nil, &promotions)
+	for _, promotion := range promotions.Promotions {
+		superAdminTester.testDelete(t, "/api/promotions/"+strconv.Itoa(promotion.ID), 204, nil, nil)
+	}
+	adminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 1}, &sale1)
+	assert.Equal(t, 100.0, sale1.PriceFloat)
+	assert.Empty(t, sale1.Promotions)
+	adminTester.testGet(t, "/api/promotions/_summary", 200, nil, &summaries)
+	assert.Len(t, summaries, 4)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testCreateAPurchase(t *testing.T) {
+	var bookResponse apis.BookResponse
+	superAdminTester.testPost(t, "/api/books", 201, Map{
+		"title":  "purchaseBook",
+		"author": "testAuthor",
+		"press":  "testPress",
+		"isbn":   "9787111000020",
+		"price":  50,
+	}, &bookResponse)
+
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{
+			{"book_id": 1, "quantity": 10, "price": 20},
+			{"book_id": bookResponse.ID, "quantity": 5, "price": 10.5},
+		},
+	}, &purchaseResponse)
+	assert.Equal(t, 2, len(purchaseResponse.Items))
+	assert.Equal(t, 252.5, purchaseResponse.TotalFloat)
+
+	// duplicated books in one purchase are not allowed
+	superAdminTester.testPost(t, "/api/purchases", 400, Map{
+		"items": []Map{
+			{"book_id": 1, "quantity": 10, "price": 20},
+			{"book_id": 1, "quantity": 5, "price": 10},
+		},
+	}, nil)
+
+	var purchaseListResponse apis.PurchaseListResponse
+	superAdminTester.testGet(t, "/api/purchases", 200, Map{"book_id": bookResponse.ID}, &purchaseListResponse)
+	assert.Equal(t, 1, purchaseListResponse.PageTotal)
+	assert.Equal(t, "purchaseBook", purchaseListResponse.Purchases[0].Items[1].Book.Title)
+}
+
+func testPayAndArriveAPurchase(t *testing.T) {
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testGet(t, "/api/purchases/1", 200, nil, &purchaseResponse)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+
+	// cannot arrive an unpaid purchase
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 400, nil, nil)
+
+	var balanceCount int64
+	DB.Model(&Balance{}).Count(&balanceCount)
+
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusPaid, purchaseResponse.Status)
+
+	// one balance for the whole purchase
+	var balance Balance
+	DB.Last(&balance)
+	assert.Equal(t, balanceCount+1, int64(balance.ID))
+	assert.Equal(t, -25250, balance.Change)
+	assert.Equal(t, OperationTypePurchase, balance.OperationType)
+	assert.Equal(t, purchaseResponse.ID, balance.OperationID)
+
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, nil, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusArrived, purchaseResponse.Status)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 400, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_cancel", 400, nil, nil)
+
+	// transition history
+	superAdminTester.testGet(t, purchaseURL, 200, nil, &purchaseResponse)
+	assert.Equal(t, 3, len(purchaseResponse.Transitions))
+	assert.Equal(t, PurchaseStatusDraft, purchaseResponse.Transitions[0].To)
+	assert.Equal(t, PurchaseStatusPaid, purchaseResponse.Transitions[1].To)
+	assert.Equal(t, PurchaseStatusPaid, purchaseResponse.Transitions[2].From)
+	assert.Equal(t, 1, *purchaseResponse.ArrivedBy)
+
+	for _, item := range purchaseResponse.Items {
+		var book Book
+		DB.First(&book, item.BookID)
+		assert.Equal(t, item.Quantity, book.Stock)
+	}
+}
+
+func testCancelAPurchase(t *testing.T) {
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": 1, "quantity": 1, "price": 20}},
+	}, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusDraft, purchaseResponse.Status)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+
+	superAdminTester.testPost(t, purchaseURL+"/_cancel", 200, nil, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusCancelled, purchaseResponse.Status)
+	assert.NotNil(t, purchaseResponse.CancelledAt)
+	assert.NotNil(t, purchaseResponse.CancelledBy)
+	assert.Nil(t, purchaseResponse.ReturnedAt)
+	superAdminTester.testPost(t, purchaseURL+"/_return", 400, nil, nil)
+
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 400, nil, nil)
+	superAdminTester.testPatch(t, purchaseURL, 400, Map{
+		"items": []Map{{"book_id": 1, "quantity": 2, "price": 20}},
+	}, nil)
+
+	var purchaseListResponse apis.PurchaseListResponse
+	superAdminTester.testGet(t, "/api/purchases", 200, Map{"status": PurchaseStatusCancelled}, &purchaseListResponse)
+	assert.Equal(t, 1, purchaseListResponse.PageTotal)
+}
+
+func testPartialArrival(t *testing.T) {
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{
+			{"book_id": 1, "quantity": 5, "price": 10},
+			{"book_id": 2, "quantity": 3, "price": 4},
+		},
+	}, &purchaseResponse)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+
+	var book1, book2 Book
+	DB.First(&book1, 1)
+	DB.First(&book2, 2)
+
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, Map{
+		"items": []Map{{"book_id": 1, "quantity": 2}},
+	}, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusPaid, purchaseResponse.Status)
+	assert.Equal(t, 2, purchaseResponse.Items[0].ReceivedQuantity)
+	assert.Equal(t, 3, purchaseResponse.Items[0].OutstandingQuantity)
+
+	// cannot receive more than outstanding, or books not in the purchase
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 400, Map{
+		"items": []Map{{"book_id": 1, "quantity": 4}},
+	}, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 400, Map{
+		"items": []Map{{"book_id": 100, "quantity": 1}},
+	}, nil)
+
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, Map{
+		"items": []Map{{"book_id": 1, "quantity": 3}, {"book_id": 2, "quantity": 1}},
+	}, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusPaid, purchaseResponse.Status)
+	assert.Equal(t, 8.0, purchaseResponse.OutstandingTotalFloat)
+
+	var stock int
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 1).Scan(&stock)
+	assert.Equal(t, book1.Stock+5, stock)
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 2).Scan(&stock)
+	assert.Equal(t, book2.Stock+1, stock)
+
+	// close short, the supplier credits the missing units
+	superAdminTester.testPost(t, purchaseURL+"/_close", 200, nil, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusClosed, purchaseResponse.Status)
+	var balance Balance
+	DB.Last(&balance)
+	assert.Equal(t, OperationTypeSupplierCredit, balance.OperationType)
+	assert.Equal(t, 800, balance.Change)
+
+	superAdminTester.testGet(t, purchaseURL, 200, nil, &purchaseResponse)
+	assert.Equal(t, 2, len(purchaseResponse.Arrivals))
+	assert.Equal(t, 2, len(purchaseResponse.Arrivals[1].Items))
+}
+
+func testRefundAPurchase(t *testing.T) {
+	var purchaseResponse apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": 1, "quantity": 3, "price": 12}},
+	}, &purchaseResponse)
+	purchaseURL := "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+
+	// cannot refund an unpaid purchase
+	superAdminTester.testPost(t, purchaseURL+"/_refund", 400, nil, nil)
+
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+	var payment Balance
+	DB.Last(&payment)
+
+	superAdminTester.testPost(t, purchaseURL+"/_refund", 200, nil, &purchaseResponse)
+	assert.Equal(t, PurchaseStatusRefunded, purchaseResponse.Status)
+
+	var balanceResponse apis.BalanceResponse
+	var balance Balance
+	DB.Last(&balance)
+	superAdminTester.testGet(t, "/api/balances/"+strconv.Itoa(balance.ID), 200, nil, &balanceResponse)
+	assert.Equal(t, OperationTypePurchaseRefund, balanceResponse.OperationType)
+	assert.Equal(t, 36.0, balanceResponse.Change)
+	assert.Equal(t, payment.ID, *balanceResponse.RelatedID)
+	assert.Equal(t, payment.Total-payment.Change, balance.Total)
+
+	superAdminTester.testPost(t, purchaseURL+"/_refund", 400, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 400, nil, nil)
+
+	// partially arrived purchases should be closed instead
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"items": []Map{{"book_id": 1, "quantity": 3, "price": 12}},
+	}, &purchaseResponse)
+	purchaseURL = "/api/purchases/" + strconv.Itoa(purchaseResponse.ID)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, Map{
+		"items": []Map{{"book_id": 1, "quantity": 1}},
+	}, nil)
+	superAdminTester.testPost(t, purchaseURL+"/_refund", 400, nil, nil)
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testReorders(t *testing.T) {
+	var supplier apis.SupplierResponse
+	superAdminTester.testPost(t, "/api/suppliers", 201, Map{"name": "补货供应商", "lead_time": 10}, &supplier)
+
+	// book1 has a reorder point, and was purchased from the supplier
+	book1 := testCreateBook(t, Map{"title": "补货", "isbn": "9787111000235", "price": 20, "reorder_point": 5, "reorder_quantity": 20}, 0)
+	assert.Equal(t, 5, *book1.ReorderPoint)
+	var purchase apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"supplier_id": supplier.ID, "items": []Map{{"book_id": book1.ID, "quantity": 6, "price": 12}},
+	}, &purchase)
+	superAdminTester.testPost(t, "/api/purchases/"+strconv.Itoa(purchase.ID)+"/_pay", 200, nil, nil)
+	superAdminTester.testPost(t, "/api/purchases/"+strconv.Itoa(purchase.ID)+"/_arrive", 200, nil, nil)
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 2}, nil)
+
+	// book2 has no reorder point, and is sold out
+	book2 := testCreateBook(t, Map{"title": "补货2", "isbn": "9787111000242", "price": 20}, 3)
+	assert.Nil(t, book2.ReorderPoint)
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book2.ID, "quantity": 3}, nil)
+
+	var evaluated apis.ReorderEvaluateResponse
+	adminTester.testPost(t, "/api/reorder_suggestions/_evaluate", 200, nil, &evaluated)
+	assert.Contains(t, evaluated.Added, book1.ID)
+	assert.Contains(t, evaluated.Added, book2.ID)
+	adminTester.testPost(t, "/api/reorder_suggestions/_evaluate", 200, nil, &evaluated)
+	assert.Empty(t, evaluated.Added)
+
+	var suggestions apis.ReorderSuggestionListResponse
+	adminTester.testGet(t, "/api/reorder_suggestions", 200, Map{"supplier_id": supplier.ID}, &suggestions)
+	if assert.Len(t, suggestions.Suggestions, 1) {
+		suggestion := suggestions.Suggestions[0]
+		assert.Equal(t, book1.ID, suggestion.BookID)
+		assert.Equal(t, "补货", suggestion.Book.Title)
+		assert.Equal(t, 4, suggestion.Stock)
+		assert.Equal(t, 5, suggestion.ReorderPoint)
+		assert.Equal(t, 20, suggestion.Quantity)
+		assert.Equal(t, 12.0, *suggestion.PriceFloat)
+	}
+	adminTester.testGet(t, "/api/reorder_suggestions", 200, nil, &suggestions)
+	for _, suggestion := range suggestions.Suggestions {
+		if suggestion.BookID == book2.ID {
+			assert.Nil(t, suggestion.PriceFloat)    // never purchased
+			assert.Equal(t, 3, suggestion.Quantity) // sales in 30 days
+		}
+	}
+
+	// one draft purchase for each supplier, books never purchased are skipped
+	var purchases []apis.PurchaseResponse
+	adminTester.testPost(t, "/api/reorder_suggestions/_draft", 400, Map{"book_ids": []int{book2.ID}}, nil)
+	adminTester.testPost(t, "/api/reorder_suggestions/_draft", 201, Map{"book_ids": []int{book1.ID, book2.ID}}, &purchases)
+	if assert.Len(t, purchases, 1) {
+		assert.Equal(t, supplier.ID, *purchases[0].SupplierID)
+		assert.Equal(t, PurchaseStatusDraft, purchases[0].Status)
+		assert.Equal(t, []apis.PurchaseItemResponse{{
+			ID: purchases[0].Items[0].ID, BookID: book1.ID, Quantity: 20, OutstandingQuantity: 20, PriceFloat: 12,
+		}}, purchases[0].Items)
+	}
+	adminTester.testPost(t, "/api/reorder_suggestions/_draft", 400, Map{"book_ids": []int{book1.ID}}, nil)
+
+	// quantities on order are not suggested again
+	adminTester.testPost(t, "/api/reorder_suggestions/_evaluate", 200, nil, &evaluated)
+	assert.NotContains(t, evaluated.Added, book1.ID)
+	assert.NotContains(t, evaluated.Added, book2.ID) // still suggested
+	adminTester.testGet(t, "/api/reorder_suggestions", 200, Map{"supplier_id": supplier.ID}, &suggestions)
+	assert.Empty(t, suggestions.Suggestions)
+
+	superAdminTester.testPatch(t, "/api/books/"+strconv.Itoa(book2.ID), 200, Map{"reorder_point": 2}, &book2)
+	assert.Equal(t, 2, *book2.ReorderPoint)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testCreateAReceipt(t *testing.T) {
+	superAdminTester.testPatch(t, "/api/books/1", 200, Map{"on_sale": true, "price": 100}, nil)
+	superAdminTester.testPatch(t, "/api/books/2", 200, Map{"on_sale": true}, nil)
+	var book1, book2 Book
+	DB.First(&book1, 1)
+	DB.First(&book2, 2)
+
+	// not enough stock for the second line, nothing should be sold
+	superAdminTester.testPost(t, "/api/receipts", 400, Map{
+		"sales": []Map{
+			{"book_id": 1, "quantity": 2},
+			{"book_id": 2, "quantity": book2.Stock + 1},
+		},
+	}, nil)
+	var stock int
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 1).Scan(&stock)
+	assert.Equal(t, book1.Stock, stock)
+
+	var receiptResponse apis.ReceiptResponse
+	superAdminTester.testPost(t, "/api/receipts", 201, Map{
+		"sales": []Map{
+			{"book_id": 2, "quantity": 1, "price": 8, "price_reason": "会员价"},
+			{"book_id": 1, "quantity": 2},
+		},
+	}, &receiptResponse)
+	assert.Equal(t, 2, len(receiptResponse.Sales))
+	assert.Equal(t, 208.0, receiptResponse.TotalFloat)
+
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 1).Scan(&stock)
+	assert.Equal(t, book1.Stock-2, stock)
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 2).Scan(&stock)
+	assert.Equal(t, book2.Stock-1, stock)
+
+	// one balance for the whole receipt
+	var balance Balance
+	DB.Last(&balance)
+	assert.Equal(t, OperationTypeReceipt, balance.OperationType)
+	assert.Equal(t, receiptResponse.ID, balance.OperationID)
+	assert.Equal(t, 20800, balance.Change)
+
+	var saleListResponse apis.SaleListResponse
+	superAdminTester.testGet(t, "/api/sales", 200, Map{"receipt_id": receiptResponse.ID}, &saleListResponse)
+	assert.Equal(t, 2, saleListResponse.PageTotal)
+
+	superAdminTester.testGet(t, "/api/receipts/"+strconv.Itoa(receiptResponse.ID), 200, nil, &receiptResponse)
+	assert.Equal(t, 2, len(receiptResponse.Sales))
+	superAdminTester.testGet(t, "/api/receipts/id=id", 400, nil, nil)
+}
+
+func testRefundASale(t *testing.T) {
+	var sale Sale
+	DB.Where("book_id = ? AND receipt_id IS NOT NULL", 1).Last(&sale)
+	assert.Equal(t, 2, sale.Quantity)
+	saleURL := "/api/sales/" + strconv.Itoa(sale.ID)
+
+	var stock int
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 1).Scan(&stock)
+
+	var refundResponse apis.SaleRefundResponse
+	superAdminTester.testPost(t, saleURL+"/_refund", 201, Map{"quantity": 1, "reason": "damaged"}, &refundResponse)
+	assert.Equal(t, 1, refundResponse.Quantity)
+	assert.Equal(t, sale.PriceFloat(), refundResponse.AmountFloat)
+
+	var balance Balance
+	DB.Last(&balance)
+	assert.Equal(t, OperationTypeRefund, balance.OperationType)
+	assert.Equal(t, -sale.Price, balance.Change)
+
+	// cannot refund more than sold across partial refunds
+	superAdminTester.testPost(t, saleURL+"/_refund", 400, Map{"quantity": 2}, nil)
+
+	// refund all remaining quantity
+	superAdminTester.testPost(t, saleURL+"/_refund", 201, nil, &refundResponse)
+	assert.Equal(t, 1, refundResponse.Quantity)
+	superAdminTester.testPost(t, saleURL+"/_refund", 400, nil, nil)
+
+	var saleResponse apis.SaleResponse
+	superAdminTester.testGet(t, saleURL, 200, nil, &saleResponse)
+	assert.Equal(t, 2, saleResponse.RefundedQuantity)
+
+	var newStock int
+	DB.Model(&Book{}).Select("stock").Where("id = ?", 1).Scan(&newStock)
+	assert.Equal(t, stock+2, newStock)
+
+	var refunds []apis.SaleRefundResponse
+	superAdminTester.testGet(t, saleURL+"/refunds", 200, nil, &refunds)
+	assert.Equal(t, 2, len(refunds))
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testStockMovements(t *testing.T) {
+	book := testCreateBook(t, Map{"title": "库存流水", "isbn": "9787111000204", "price": 20}, 0)
+
+	// manual adjustments require a reason, damage and loss decrease the stock
+	var movement apis.StockMovementResponse
+	adminTester.testPost(t, "/api/stock_movements", 201, Map{"book_id": book.ID, "change": 5, "reason": "found in the back room"}, &movement)
+	assert.Equal(t, StockMovementTypeAdjustment, movement.Type)
+	assert.Equal(t, 5, movement.Stock)
+	assert.Nil(t, movement.OperationID)
+	adminTester.testPost(t, "/api/stock_movements", 400, Map{"book_id": book.ID, "change": 5}, nil)
+	adminTester.testPost(t, "/api/stock_movements", 400, Map{"book_id": book.ID, "change": 0, "reason": "nothing"}, nil)
+	adminTester.testPost(t, "/api/stock_movements", 400, Map{"book_id": book.ID, "change": 1, "type": "damage", "reason": "damaged"}, nil)
+	adminTester.testPost(t, "/api/stock_movements", 400, Map{"book_id": book.ID, "change": -10, "type": "loss", "reason": "stolen"}, nil)
+	adminTester.testPost(t, "/api/stock_movements", 404, Map{"book_id": 100000, "change": 1, "reason": "unknown"}, nil)
+	adminTester.testPost(t, "/api/stock_movements", 201, Map{"book_id": book.ID, "change": -2, "type": "damage", "reason": "water damage"}, &movement)
+	assert.Equal(t, 3, movement.Stock)
+
+	// sales and refunds are recorded with the reference
+	var sale apis.SaleResponse
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book.ID, "quantity": 2}, &sale)
+	superAdminTester.testPost(t, "/api/sales/"+strconv.Itoa(sale.ID)+"/_refund", 201, Map{"quantity": 1}, nil)
+
+	var movements apis.StockMovementListResponse
+	superAdminTester.testGet(t, "/api/stock_movements", 200, Map{"book_id": book.ID}, &movements)
+	if assert.Len(t, movements.Movements, 4) {
+		assert.Equal(t, StockMovementTypeSale, movements.Movements[2].Type)
+		assert.Equal(t, -2, movements.Movements[2].Change)
+		assert.Equal(t, 1, movements.Movements[2].Stock)
+		assert.Equal(t, sale.ID, *movements.Movements[2].OperationID)
+		assert.Equal(t, StockMovementTypeRefund, movements.Movements[3].Type)
+		assert.Equal(t, 2, movements.Movements[3].Stock)
+	}
+	superAdminTester.testGet(t, "/api/stock_movements", 200, Map{"book_id": book.ID, "type": "damage"}, &movements)
+	assert.Equal(t, 1, movements.PageTotal)
+
+	// the movements add up to the stock
+	var total int
+	DB.Model(&StockMovement{}).Select("SUM(change)").Where("book_id = ?", book.ID).Scan(&total)
+	superAdminTester.testGet(t, "/api/books/"+strconv.Itoa(book.ID), 200, nil, &book)
+	assert.Equal(t, 2, book.Stock)
+	assert.Equal(t, book.Stock, total)
+}
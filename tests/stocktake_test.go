@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testStocktakes(t *testing.T) {
+	book1 := testCreateBook(t, Map{"title": "盘点", "isbn": "9787111000211", "price": 20}, 10)
+	book2 := testCreateBook(t, Map{"title": "盘点2", "isbn": "9787111000228", "price": 20}, 2)
+
+	var stocktake apis.StocktakeResponse
+	adminTester.testPost(t, "/api/stocktakes", 201, Map{"note": "年终盘点"}, &stocktake)
+	assert.Equal(t, StocktakeStatusOpen, stocktake.Status)
+	stocktakeURL := "/api/stocktakes/" + strconv.Itoa(stocktake.ID)
+
+	// counts of several batches are added up
+	adminTester.testPost(t, stocktakeURL+"/counts", 201, Map{"items": []Map{{"book_id": book1.ID, "quantity": 4}}}, nil)
+	superAdminTester.testPost(t, stocktakeURL+"/counts", 201, Map{"items": []Map{
+		{"book_id": book1.ID, "quantity": 3}, {"book_id": book2.ID, "quantity": 2},
+	}}, &stocktake)
+	adminTester.testPost(t, stocktakeURL+"/counts", 404, Map{"items": []Map{{"book_id": 100000, "quantity": 1}}}, nil)
+	adminTester.testPost(t, stocktakeURL+"/counts", 400, Map{"items": []Map{{"book_id": book1.ID, "quantity": -1}}}, nil)
+	assert.Equal(t, []apis.StocktakeItemResponse{{BookID: book1.ID, Counted: 7}, {BookID: book2.ID, Counted: 2}}, stocktake.Items)
+	assert.Len(t, stocktake.Counts, 3)
+
+	// variance report against the current stock
+	var report apis.StocktakeVarianceResponse
+	adminTester.testGet(t, stocktakeURL+"/variances", 200, nil, &report)
+	if assert.Len(t, report.Items, 2) {
+		assert.Equal(t, 10, report.Items[0].SystemStock)
+		assert.Equal(t, -3, report.Items[0].Variance)
+		assert.Equal(t, 0, report.Items[1].Variance)
+		assert.Equal(t, "盘点", report.Items[0].Book.Title)
+	}
+	assert.Equal(t, 3, report.Shortage)
+	assert.Equal(t, 0, report.Surplus)
+	adminTester.testGet(t, stocktakeURL+"/variances", 200, Map{"only_variance": true}, &report)
+	assert.Len(t, report.Items, 1)
+	adminTester.testGet(t, "/api/stocktakes/id=id", 400, nil, nil)
+	adminTester.testGet(t, "/api/stocktakes/id=id/variances", 400, nil, nil)
+
+	// posting adjusts the stock of the counted books
+	adminTester.testPost(t, stocktakeURL+"/_post", 403, nil, nil)
+	superAdminTester.testPost(t, stocktakeURL+"/_post", 200, nil, &stocktake)
+	assert.Equal(t, StocktakeStatusPosted, stocktake.Status)
+	assert.Equal(t, 10, *stocktake.Items[0].SystemStock)
+	superAdminTester.testGet(t, "/api/books/"+strconv.Itoa(book1.ID), 200, nil, &book1)
+	assert.Equal(t, 7, book1.Stock)
+	var movements apis.StockMovementListResponse
+	superAdminTester.testGet(t, "/api/stock_movements", 200, Map{"type": "stocktake"}, &movements)
+	if assert.Len(t, movements.Movements, 1) {
+		assert.Equal(t, book1.ID, movements.Movements[0].BookID)
+		assert.Equal(t, -3, movements.Movements[0].Change)
+		assert.Equal(t, stocktake.ID, *movements.Movements[0].OperationID)
+		assert.Equal(t, "年终盘点", *movements.Movements[0].Reason)
+	}
+	superAdminTester.testPost(t, stocktakeURL+"/_post", 400, nil, nil)
+	superAdminTester.testPost(t, stocktakeURL+"/_cancel", 400, nil, nil)
+	adminTester.testPost(t, stocktakeURL+"/counts", 400, Map{"items": []Map{{"book_id": book1.ID, "quantity": 1}}}, nil)
+
+	// the report of a posted stocktake keeps the stock at posting
+	superAdminTester.testPost(t, "/api/sales", 201, Map{"book_id": book1.ID, "quantity": 1}, nil)
+	adminTester.testGet(t, stocktakeURL+"/variances", 200, nil, &report)
+	assert.Equal(t, 10, report.Items[0].SystemStock)
+
+	// an empty stocktake cannot be posted
+	var empty apis.StocktakeResponse
+	adminTester.testPost(t, "/api/stocktakes", 201, Map{"note": "空盘点"}, &empty)
+	superAdminTester.testPost(t, "/api/stocktakes/"+strconv.Itoa(empty.ID)+"/_post", 400, nil, nil)
+	superAdminTester.testPost(t, "/api/stocktakes/"+strconv.Itoa(empty.ID)+"/_cancel", 200, nil, &empty)
+	assert.Equal(t, StocktakeStatusCancelled, empty.Status)
+
+	var stocktakes apis.StocktakeListResponse
+	adminTester.testGet(t, "/api/stocktakes", 200, Map{"status": "posted"}, &stocktakes)
+	assert.Equal(t, 1, stocktakes.PageTotal)
+}
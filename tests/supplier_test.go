@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"book_management_system_backend/apis"
+	. "book_management_system_backend/models"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func testCreateASupplier(t *testing.T) {
+	var supplierResponse apis.SupplierResponse
+	superAdminTester.testPost(t, "/api/suppliers", 201, Map{
+		"name":      "testSupplier",
+		"contact":   "13800000000",
+		"tax_id":    "91310000000000000X",
+		"lead_time": 3,
+	}, &supplierResponse)
+	assert.Equal(t, "testSupplier", supplierResponse.Name)
+	assert.Equal(t, 3, supplierResponse.LeadTime)
+
+	superAdminTester.testPatch(t, "/api/suppliers/"+strconv.Itoa(supplierResponse.ID), 200, Map{
+		"notes": "weekly delivery",
+	}, &supplierResponse)
+	assert.Equal(t, "weekly delivery", *supplierResponse.Notes)
+	assert.Equal(t, "testSupplier", supplierResponse.Name)
+
+	var supplierListResponse apis.SupplierListResponse
+	superAdminTester.testGet(t, "/api/suppliers", 200, Map{"name": "test"}, &supplierListResponse)
+	assert.Equal(t, 1, supplierListResponse.PageTotal)
+
+	superAdminTester.testPost(t, "/api/purchases", 404, Map{
+		"supplier_id": 100,
+		"items":       []Map{{"book_id": 1, "quantity": 1, "price": 1}},
+	}, nil)
+}
+
+func testSupplierSummary(t *testing.T) {
+	var supplier Supplier
+	DB.Where("name = ?", "testSupplier").First(&supplier)
+	supplierURL := "/api/suppliers/" + strconv.Itoa(supplier.ID)
+
+	var paidPurchase, unpaidPurchase apis.PurchaseResponse
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"supplier_id": supplier.ID,
+		"items":       []Map{{"book_id": 1, "quantity": 2, "price": 15}},
+	}, &paidPurchase)
+	assert.Equal(t, supplier.ID, *paidPurchase.SupplierID)
+	superAdminTester.testPost(t, "/api/purchases", 201, Map{
+		"supplier_id": supplier.ID,
+		"items":       []Map{{"book_id": 2, "quantity": 1, "price": 7.5}},
+	}, &unpaidPurchase)
+
+	purchaseURL := "/api/purchases/" + strconv.Itoa(paidPurchase.ID)
+	superAdminTester.testPost(t, purchaseURL+"/_pay", 200, nil, &paidPurchase)
+	assert.NotNil(t, paidPurchase.PaidAt)
+	superAdminTester.testPost(t, purchaseURL+"/_arrive", 200, nil, &paidPurchase)
+	assert.NotNil(t, paidPurchase.ArrivedAt)
+
+	var purchaseListResponse apis.PurchaseListResponse
+	superAdminTester.testGet(t, "/api/purchases", 200, Map{"supplier_id": supplier.ID}, &purchaseListResponse)
+	assert.Equal(t, 2, purchaseListResponse.PageTotal)
+
+	var summary apis.SupplierSummaryResponse
+	superAdminTester.testGet(t, supplierURL+"/summary", 200, nil, &summary)
+	assert.Equal(t, int64(2), summary.PurchaseCount)
+	assert.Equal(t, 30.0, summary.TotalSpend)
+	assert.Equal(t, 7.5, summary.OpenUnpaid)
+	assert.NotNil(t, summary.AverageDeliveryDays)
+	superAdminTester.testGet(t, "/api/suppliers/id=id", 400, nil, nil)
+	superAdminTester.testGet(t, "/api/suppliers/id=id/summary", 400, nil, nil)
+
+	// remove the supplier of a draft purchase
+	superAdminTester.testPatch(t, "/api/purchases/"+strconv.Itoa(unpaidPurchase.ID), 200, Map{"supplier_id": 0}, &unpaidPurchase)
+	assert.Nil(t, unpaidPurchase.SupplierID)
+
+	var other apis.SupplierResponse
+	superAdminTester.testPost(t, "/api/suppliers", 201, Map{"name": "otherSupplier"}, &other)
+	adminTester.testDelete(t, "/api/suppliers/"+strconv.Itoa(other.ID), 403, nil, nil)
+	superAdminTester.testDelete(t, "/api/suppliers/"+strconv.Itoa(other.ID), 204, nil, nil)
+	superAdminTester.testDelete(t, "/api/suppliers/"+strconv.Itoa(other.ID), 404, nil, nil)
+	superAdminTester.testDelete(t, "/api/suppliers/100000", 404, nil, nil)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"book_management_system_backend/apis"
 	"book_management_system_backend/bootstrap"
 	. "book_management_system_backend/models"
 	"bytes"
@@ -24,6 +25,20 @@ var (
 	adminTester      tester
 )
 
+// testCreateBook creates a book on sale with the fields, which override the default author and press.
+// The stock is added to the default location by a manual stock movement if it is positive.
+func testCreateBook(t *testing.T, fields Map, stock int) (book apis.BookResponse) {
+	data := Map{"author": "佚名", "press": "测试出版社", "on_sale": true}
+	for key, value := range fields {
+		data[key] = value
+	}
+	superAdminTester.testPost(t, "/api/books", 201, data, &book)
+	if stock > 0 {
+		superAdminTester.testPost(t, "/api/stock_movements", 201, Map{"book_id": book.ID, "change": stock, "reason": "test"}, nil)
+	}
+	return book
+}
+
 func (tester *tester) testCommon(t *testing.T, method string, route string, statusCode int, isQuery bool, data Map, model any) {
 	var requestData []byte
 	var err error
@@ -89,3 +104,47 @@ func (tester *tester) testDelete(t *testing.T, route string, statusCode int, dat
 func (tester *tester) testPatch(t *testing.T, route string, statusCode int, data Map, model any) {
 	tester.testCommonBody(t, http.MethodPatch, route, statusCode, data, model)
 }
+
+// testPostRaw posts a raw body, such as a csv file or a multipart form
+func (tester *tester) testPostRaw(t *testing.T, route string, statusCode int, contentType string, body []byte, model any) {
+	req, err := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(body))
+	assert.Nilf(t, err, "constructs http request")
+	req.Header.Add("Content-Type", contentType)
+	if tester.Token != "" {
+		req.Header.Add("Authorization", "Bearer "+tester.Token)
+	}
+
+	res, err := App.Test(req, -1)
+	assert.Nilf(t, err, "perform request")
+	assert.Equalf(t, statusCode, res.StatusCode, "status code")
+
+	responseBody, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "decode response")
+
+	if model != nil {
+		err = json.Unmarshal(responseBody, model)
+		assert.Nilf(t, err, "decode response")
+	}
+}
+
+// testGetRaw returns the raw response body, such as an exported file
+func (tester *tester) testGetRaw(t *testing.T, route string, statusCode int, data Map) (contentType string, body []byte) {
+	if data != nil {
+		queryData, err := urlquery.Marshal(data)
+		assert.Nilf(t, err, "encode request query")
+		route += "?" + string(queryData)
+	}
+	req, err := http.NewRequest(http.MethodGet, route, nil)
+	assert.Nilf(t, err, "constructs http request")
+	if tester.Token != "" {
+		req.Header.Add("Authorization", "Bearer "+tester.Token)
+	}
+
+	res, err := App.Test(req, -1)
+	assert.Nilf(t, err, "perform request")
+	assert.Equalf(t, statusCode, res.StatusCode, "status code")
+
+	body, err = io.ReadAll(res.Body)
+	assert.Nilf(t, err, "read response")
+	return res.Header.Get("Content-Type"), body
+}
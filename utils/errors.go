@@ -65,6 +65,17 @@ func NotFound(messages ...string) *HttpError {
 	}
 }
 
+func Conflict(messages ...string) *HttpError {
+	message := "Conflict"
+	if len(messages) > 0 {
+		message = messages[0]
+	}
+	return &HttpError{
+		Code:    409,
+		Message: message,
+	}
+}
+
 func InternalServerError(messages ...string) *HttpError {
 	message := "Unknown Error"
 	if len(messages) > 0 {
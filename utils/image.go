@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoders
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const imageMaxPixels = 40_000_000 // refuse to decode larger images, which may exhaust the memory
+
+// ImageContentTypes supported image content types, detected from the content
+var ImageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var (
+	ErrImageType = BadRequest("只支持 JPEG, PNG, GIF 格式的图片")
+	ErrImageData = BadRequest("图片已损坏或尺寸过大")
+)
+
+// DecodeImage detects the content type and decodes an image, the first frame is decoded for gif
+func DecodeImage(data []byte) (img image.Image, contentType string, err error) {
+	contentType = http.DetectContentType(data)
+	if !ImageContentTypes[contentType] {
+		return nil, "", ErrImageType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > imageMaxPixels {
+		return nil, "", ErrImageData
+	}
+	img, _, err = image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrImageData
+	}
+	return img, contentType, nil
+}
+
+// Thumbnail scales the image down to fit in size x size, keeping the aspect ratio.
+// Every pixel of the thumbnail is the average of the source pixels it covers.
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+	thumbWidth, thumbHeight := size, height*size/width
+	if height > width {
+		thumbWidth, thumbHeight = width*size/height, size
+	}
+	thumbWidth, thumbHeight = max(thumbWidth, 1), max(thumbHeight, 1)
+
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := y*height/thumbHeight, max((y+1)*height/thumbHeight, y*height/thumbHeight+1)
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := x*width/thumbWidth, max((x+1)*width/thumbWidth, x*width/thumbWidth+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					pixel := rgba.Pix[offset : offset+4 : offset+4]
+					r, g, b, a = r+int(pixel[0]), g+int(pixel[1]), b+int(pixel[2]), a+int(pixel[3])
+					n++
+					offset += 4
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// EncodeThumbnail encodes the image as png if it has transparent pixels, otherwise as jpeg
+func EncodeThumbnail(img image.Image) (data []byte, contentType string, err error) {
+	var buffer bytes.Buffer
+	if isOpaque(img) {
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 85})
+		contentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buffer, img)
+		contentType = "image/png"
+	}
+	return buffer.Bytes(), contentType, err
+}
+
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
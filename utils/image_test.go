@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, src); err != nil {
+		t.Fatal(err)
+	}
+	img, contentType, err := DecodeImage(buffer.Bytes())
+	if err != nil || contentType != "image/png" || img.Bounds().Dx() != 3 {
+		t.Errorf("DecodeImage = %v, %s, %v", img.Bounds(), contentType, err)
+	}
+
+	if _, _, err = DecodeImage([]byte("<svg></svg>")); err != ErrImageType {
+		t.Errorf("DecodeImage error = %v for svg, want ErrImageType", err)
+	}
+	if _, _, err = DecodeImage(buffer.Bytes()[:20]); err != ErrImageData {
+		t.Errorf("DecodeImage error = %v for truncated png, want ErrImageData", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	// left half black and right half white
+	src := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 200; x < 400; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+		for x := 0; x < 200; x++ {
+			src.SetRGBA(x, y, color.RGBA{A: 255})
+		}
+	}
+
+	thumbnail := Thumbnail(src, 100)
+	if bounds := thumbnail.Bounds(); bounds.Dx() != 100 || bounds.Dy() != 25 {
+		t.Fatalf("Thumbnail size = %v, want 100x25", bounds)
+	}
+	if r, _, _, _ := thumbnail.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("left pixel = %d, want black", r)
+	}
+	if r, _, _, _ := thumbnail.At(90, 10).RGBA(); r != 0xffff {
+		t.Errorf("right pixel = %d, want white", r)
+	}
+
+	if Thumbnail(src, 1000) != image.Image(src) {
+		t.Error("small images should not be scaled")
+	}
+
+	_, contentType, err := EncodeThumbnail(thumbnail)
+	if err != nil || contentType != "image/jpeg" {
+		t.Errorf("EncodeThumbnail = %s, %v, want image/jpeg for opaque images", contentType, err)
+	}
+	_, contentType, _ = EncodeThumbnail(image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	if contentType != "image/png" {
+		t.Errorf("EncodeThumbnail = %s, want image/png for transparent images", contentType)
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import "strings"
+
+// NormalizeISBN strips hyphens and spaces, verifies the ISBN-10 or ISBN-13 checksum
+// and converts ISBN-10 to ISBN-13. ok is false if isbn is invalid.
+func NormalizeISBN(isbn string) (normalized string, ok bool) {
+	isbn = strings.NewReplacer("-", "", " ", "").Replace(isbn)
+	switch len(isbn) {
+	case 10:
+		if !isISBN10(isbn) {
+			return "", false
+		}
+		isbn = "978" + isbn[:9]
+		return isbn + string(isbn13CheckDigit(isbn)), true
+	case 13:
+		if !isISBN13(isbn) {
+			return "", false
+		}
+		return isbn, true
+	default:
+		return "", false
+	}
+}
+
+func isISBN10(isbn string) bool {
+	var sum int
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch c := isbn[i]; {
+		case c >= '0' && c <= '9':
+			digit = int(c - '0')
+		case (c == 'X' || c == 'x') && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * digit
+	}
+	return sum%11 == 0
+}
+
+func isISBN13(isbn string) bool {
+	for i := 0; i < 13; i++ {
+		if isbn[i] < '0' || isbn[i] > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(isbn[:12]) == isbn[12]
+}
+
+// isbn13CheckDigit computes the check digit of the first 12 digits
+func isbn13CheckDigit(isbn string) byte {
+	var sum int
+	for i := 0; i < 12; i++ {
+		digit := int(isbn[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		isbn       string
+		normalized string
+		ok         bool
+	}{
+		{"9780306406157", "9780306406157", true},
+		{"978-0-306-40615-7", "9780306406157", true},
+		{"0-306-40615-2", "9780306406157", true},
+		{"0 306 40615 2", "9780306406157", true},
+		{"080442957X", "9780804429573", true},
+		{"9780306406158", "", false}, // wrong check digit
+		{"0306406153", "", false},
+		{"90000000001", "", false},
+		{"97803064061a7", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		normalized, ok := NormalizeISBN(test.isbn)
+		if normalized != test.normalized || ok != test.ok {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want %q, %v", test.isbn, normalized, ok, test.normalized, test.ok)
+		}
+	}
+}
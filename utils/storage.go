@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage stores objects by key, such as uploaded images
+type Storage interface {
+	Put(key string, contentType string, data []byte) error
+	Get(key string) ([]byte, error) // returns ErrObjectNotFound if the key does not exist
+	Delete(key string) error        // deleting a missing key is not an error
+}
+
+// LocalStorage stores objects as files in a directory
+type LocalStorage struct {
+	Dir string
+}
+
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{Dir: dir}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", errors.New("invalid object key: " + key)
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+// Put writes to a temporary file first, so that readers never see partial objects
+func (s *LocalStorage) Put(key string, _ string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (s *LocalStorage) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// MemoryStorage keeps objects in memory, used in test mode
+type MemoryStorage struct {
+	objects sync.Map
+}
+
+func (s *MemoryStorage) Put(key string, _ string, data []byte) error {
+	s.objects.Store(key, append([]byte(nil), data...))
+	return nil
+}
+
+func (s *MemoryStorage) Get(key string) ([]byte, error) {
+	data, ok := s.objects.Load(key)
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return data.([]byte), nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.objects.Delete(key)
+	return nil
+}
@@ -10,10 +10,10 @@ import (
 )
 
 type ErrorDetailElement struct {
-	validator.FieldError
-	Field string `json:"field"`
-	Tag   string `json:"tag"`
-	Value string `json:"value"`
+	validator.FieldError `json:"-"`
+	Field                string `json:"field"`
+	Tag                  string `json:"tag"`
+	Value                string `json:"value"`
 }
 
 type ErrorDetail []*ErrorDetailElement
@@ -40,6 +40,15 @@ func init() {
 
 		return name
 	})
+
+	// isbn accepts ISBN-10 or ISBN-13 with valid checksum, hyphens and spaces are allowed
+	err := validate.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		_, ok := NormalizeISBN(fl.Field().String())
+		return ok
+	})
+	if err != nil {
+		panic(err)
+	}
 }
 
 func Validate(model any) error {
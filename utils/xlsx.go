@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXWriter writes a single sheet xlsx file row by row, rows are streamed to the underlying writer
+// so that large exports are not held in memory. Cells are written as inline strings, numbers or booleans.
+type XLSXWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+var xlsxStaticFiles = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	writer := &XLSXWriter{zip: zip.NewWriter(w)}
+	for _, file := range xlsxStaticFiles {
+		if err := writer.writeFile(file.name, file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	var name = sheetName
+	if len(name) > 31 {
+		name = name[:31] // excel limit
+	}
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + xmlEscape(name) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writer.writeFile("xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	// the sheet must be the last file since it is streamed
+	sheet, err := writer.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	writer.sheet = bufio.NewWriter(sheet)
+	_, err = writer.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *XLSXWriter) writeFile(name, content string) error {
+	file, err := w.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, content)
+	return err
+}
+
+// WriteRow writes a row of cells, nil cells are left empty
+func (w *XLSXWriter) WriteRow(cells []any) error {
+	w.rows++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows); err != nil {
+		return err
+	}
+	for i, cell := range cells {
+		ref := xlsxColumnName(i) + strconv.Itoa(w.rows)
+		var err error
+		switch value := cell.(type) {
+		case nil:
+			continue
+		case bool:
+			b := 0
+			if value {
+				b = 1
+			}
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%v</v></c>`, ref, value)
+		case time.Time:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, value.Format(time.RFC3339))
+		default:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(fmt.Sprint(value)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Flush flushes the buffered rows to the underlying writer
+func (w *XLSXWriter) Flush() error {
+	return w.sheet.Flush()
+}
+
+// Close finishes the sheet and the zip archive, it does not close the underlying writer
+func (w *XLSXWriter) Close() error {
+	if _, err := w.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// xlsxColumnName converts a zero based column index to A, B, ..., Z, AA, AB, ...
+func xlsxColumnName(index int) string {
+	var name []byte
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = append([]byte{byte('A' + (index-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+func xmlEscape(s string) string {
+	var builder strings.Builder
+	_ = xml.EscapeText(&builder, []byte(s))
+	return builder.String()
+}
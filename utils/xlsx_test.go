@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXColumnName(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumnName(index); got != name {
+			t.Errorf("xlsxColumnName(%d) = %s, want %s", index, got, name)
+		}
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewXLSXWriter(&buffer, "books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"id", "title", "price", "on_sale", "created_at"},
+		{1, "<Go> & Rust", 12.5, true, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{2, nil, nil, false, nil},
+	}
+	for _, row := range rows {
+		if err = writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, file := range reader.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		f, _ := file.Open()
+		content, _ := io.ReadAll(f)
+		sheet = string(content)
+	}
+	for _, expected := range []string{
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">&lt;Go&gt; &amp; Rust</t></is></c>`,
+		`<c r="C2"><v>12.5</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`<c r="E2" t="inlineStr"><is><t>2023-01-02T03:04:05Z</t></is></c>`,
+		`<row r="3"><c r="A3"><v>2</v></c><c r="D3" t="b"><v>0</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("sheet does not contain %s", expected)
+		}
+	}
+}